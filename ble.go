@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type BLEStatus struct{}
@@ -15,6 +12,10 @@ func (r *BLEGetStatusRequest) Method() string {
 	return "BLE.GetStatus"
 }
 
+func (r *BLEGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BLEGetStatusRequest) NewTypedResponse() *BLEStatus {
 	return &BLEStatus{}
 }
@@ -25,16 +26,14 @@ func (r *BLEGetStatusRequest) NewResponse() any {
 
 func (r *BLEGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BLEStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type BLEConfig struct {
@@ -65,6 +64,10 @@ func (r *BLEGetConfigRequest) Method() string {
 	return "BLE.GetConfig"
 }
 
+func (r *BLEGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BLEGetConfigRequest) NewTypedResponse() *BLEConfig {
 	return &BLEConfig{}
 }
@@ -75,16 +78,14 @@ func (r *BLEGetConfigRequest) NewResponse() any {
 
 func (r *BLEGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BLEConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type BLESetConfigRequest struct {
@@ -95,6 +96,10 @@ func (r *BLESetConfigRequest) Method() string {
 	return "BLE.SetConfig"
 }
 
+func (r *BLESetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BLESetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -105,14 +110,12 @@ func (r *BLESetConfigRequest) NewResponse() any {
 
 func (r *BLESetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
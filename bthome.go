@@ -2,16 +2,13 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 // BTHomeAddDeviceRequest contains parameters for the BTHome.GetConfig RPC request.
 type BTHomeAddDeviceRequest struct {
 	// ID for the new component. Accepted range: [200..299]. Optional. If omitted, the first free
 	// ID will be used. If the desired ID is not available, an error will be returned.
-	ID int `json:"id"`
+	ID *int `json:"id,omitempty"`
 
 	// Config to be used for the new component.
 	Config BTHomeDeviceConfig `json:"config"`
@@ -21,6 +18,10 @@ func (r *BTHomeAddDeviceRequest) Method() string {
 	return "BTHome.AddDevice"
 }
 
+func (r *BTHomeAddDeviceRequest) Idempotent() bool {
+	return false
+}
+
 func (r *BTHomeAddDeviceRequest) NewTypedResponse() *BTHomeAddDeviceResponse {
 	return &BTHomeAddDeviceResponse{}
 }
@@ -31,16 +32,22 @@ func (r *BTHomeAddDeviceRequest) NewResponse() any {
 
 func (r *BTHomeAddDeviceRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeAddDeviceResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeAddDeviceRequest) Validate() error {
+	v := newValidation(r)
+	if r.ID != nil {
+		v.bthomeID("id", *r.ID)
+	}
+	return v.err()
 }
 
 type BTHomeAddDeviceResponse struct {
@@ -58,6 +65,10 @@ func (r *BTHomeDeleteDeviceRequest) Method() string {
 	return "BTHome.DeleteDevice"
 }
 
+func (r *BTHomeDeleteDeviceRequest) Idempotent() bool {
+	return false
+}
+
 func (r *BTHomeDeleteDeviceRequest) NewTypedResponse() *BTHomeDeleteDeviceResponse {
 	return &BTHomeDeleteDeviceResponse{}
 }
@@ -68,16 +79,20 @@ func (r *BTHomeDeleteDeviceRequest) NewResponse() any {
 
 func (r *BTHomeDeleteDeviceRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeDeleteDeviceResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeDeleteDeviceRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 type BTHomeDeleteDeviceResponse struct{}
@@ -96,6 +111,10 @@ func (r *BTHomeAddSensorRequest) Method() string {
 	return "BTHome.AddSensor"
 }
 
+func (r *BTHomeAddSensorRequest) Idempotent() bool {
+	return false
+}
+
 func (r *BTHomeAddSensorRequest) NewTypedResponse() *BTHomeAddSensorResponse {
 	return &BTHomeAddSensorResponse{}
 }
@@ -106,16 +125,22 @@ func (r *BTHomeAddSensorRequest) NewResponse() any {
 
 func (r *BTHomeAddSensorRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeAddSensorResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeAddSensorRequest) Validate() error {
+	v := newValidation(r)
+	if r.ID != nil {
+		v.bthomeID("id", *r.ID)
+	}
+	return v.err()
 }
 
 type BTHomeAddSensorResponse struct {
@@ -133,6 +158,10 @@ func (r *BTHomeDeleteSensorRequest) Method() string {
 	return "BTHome.DeleteSensor"
 }
 
+func (r *BTHomeDeleteSensorRequest) Idempotent() bool {
+	return false
+}
+
 func (r *BTHomeDeleteSensorRequest) NewTypedResponse() *BTHomeDeleteSensorResponse {
 	return &BTHomeDeleteSensorResponse{}
 }
@@ -143,16 +172,20 @@ func (r *BTHomeDeleteSensorRequest) NewResponse() any {
 
 func (r *BTHomeDeleteSensorRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeDeleteSensorResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeDeleteSensorRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 type BTHomeDeleteSensorResponse struct{}
@@ -167,6 +200,10 @@ func (r *BTHomeStartDeviceDiscoveryRequest) Method() string {
 	return "BTHome.StartDeviceDiscovery"
 }
 
+func (r *BTHomeStartDeviceDiscoveryRequest) Idempotent() bool {
+	return false
+}
+
 func (r *BTHomeStartDeviceDiscoveryRequest) NewTypedResponse() *BTHomeStartDeviceDiscoveryResponse {
 	return &BTHomeStartDeviceDiscoveryResponse{}
 }
@@ -177,16 +214,14 @@ func (r *BTHomeStartDeviceDiscoveryRequest) NewResponse() any {
 
 func (r *BTHomeStartDeviceDiscoveryRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeStartDeviceDiscoveryResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type BTHomeStartDeviceDiscoveryResponse struct{}
@@ -201,6 +236,10 @@ func (r *BTHomeGetObjectInfosRequest) Method() string {
 	return "BTHome.GetObjectInfos"
 }
 
+func (r *BTHomeGetObjectInfosRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeGetObjectInfosRequest) NewTypedResponse() *BTHomeGetObjectInfosResponse {
 	return &BTHomeGetObjectInfosResponse{}
 }
@@ -211,16 +250,14 @@ func (r *BTHomeGetObjectInfosRequest) NewResponse() any {
 
 func (r *BTHomeGetObjectInfosRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeGetObjectInfosResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // BTHomeGetObjectInfosResponse ...
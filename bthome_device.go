@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type BTHomeDeviceConfig struct {
@@ -12,13 +9,13 @@ type BTHomeDeviceConfig struct {
 	ID int `json:"id"`
 
 	// Name of the component instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// MAC address of the physical device
 	Addr string `json:"addr"`
 
 	// AES encryption key as hexadecimal string for encrypted devices
-	Key *string `json:"key"`
+	Key Optional[string] `json:"key,omitempty"`
 
 	// Meta contains meta data for the component.
 	Meta BTHomeDeviceConfigMeta `json:"meta"`
@@ -33,7 +30,7 @@ type BTHomeDeviceConfigMeta struct {
 // BTHomeDeviceConfigMetaUI contains setting for how the component will be rendered in the UI.
 type BTHomeDeviceConfigMetaUI struct {
 	// Icon allows setting custom icon for the component's card by providing an external hosted image via link.
-	Icon *string `json:"icon"`
+	Icon Optional[string] `json:"icon,omitempty"`
 }
 
 type BTHomeDeviceStatus struct {
@@ -67,6 +64,10 @@ func (r *BTHomeDeviceGetConfigRequest) Method() string {
 	return "BTHomeDevice.GetConfig"
 }
 
+func (r *BTHomeDeviceGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeDeviceGetConfigRequest) NewTypedResponse() *BTHomeDeviceConfig {
 	return &BTHomeDeviceConfig{}
 }
@@ -77,16 +78,20 @@ func (r *BTHomeDeviceGetConfigRequest) NewResponse() any {
 
 func (r *BTHomeDeviceGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeDeviceConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeDeviceGetConfigRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 // BTHomeDeviceSetConfigRequest contains parameters for the BTHomeDevice.SetConfig RPC request.
@@ -102,6 +107,10 @@ func (r *BTHomeDeviceSetConfigRequest) Method() string {
 	return "BTHomeDevice.SetConfig"
 }
 
+func (r *BTHomeDeviceSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeDeviceSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -112,16 +121,20 @@ func (r *BTHomeDeviceSetConfigRequest) NewResponse() any {
 
 func (r *BTHomeDeviceSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeDeviceSetConfigRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 // BTHomeDeviceGetStatusRequst contains parameters for the BTHomeDevice.GetStatus RPC request.
@@ -134,6 +147,10 @@ func (r *BTHomeDeviceGetStatusRequest) Method() string {
 	return "BTHomeDevice.GetStatus"
 }
 
+func (r *BTHomeDeviceGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeDeviceGetStatusRequest) NewTypedResponse() *BTHomeDeviceStatus {
 	return &BTHomeDeviceStatus{}
 }
@@ -144,16 +161,20 @@ func (r *BTHomeDeviceGetStatusRequest) NewResponse() any {
 
 func (r *BTHomeDeviceGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeDeviceStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeDeviceGetStatusRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 // BTHomeDeviceGetKnownObjectsRequst contains parameters for the BTHomeDevice.GetKnownObjects RPC request.
@@ -166,6 +187,10 @@ func (r *BTHomeDeviceGetKnownObjectsRequest) Method() string {
 	return "BTHomeDevice.GetKnownObjects"
 }
 
+func (r *BTHomeDeviceGetKnownObjectsRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeDeviceGetKnownObjectsRequest) NewTypedResponse() *BTHomeDeviceGetKnownObjectsResponse {
 	return &BTHomeDeviceGetKnownObjectsResponse{}
 }
@@ -176,16 +201,20 @@ func (r *BTHomeDeviceGetKnownObjectsRequest) NewResponse() any {
 
 func (r *BTHomeDeviceGetKnownObjectsRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeDeviceGetKnownObjectsResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeDeviceGetKnownObjectsRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 type BTHomeDeviceGetKnownObjectsResponse struct {
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type BTHomeSensorConfig struct {
@@ -12,7 +9,7 @@ type BTHomeSensorConfig struct {
 	ID int `json:"id"`
 
 	// Name of the component instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// ObjID is the BTHome object id in decimal
 	ObjID int `json:"obj_id"`
@@ -36,7 +33,7 @@ type BTHomeSensorConfigMeta struct {
 // BTHomeSensorConfigMetaUI contains setting for how the component will be rendered in the UI.
 type BTHomeSensorConfigMetaUI struct {
 	// Icon allows setting custom icon for the component's card by providing an external hosted image via link.
-	Icon *string `json:"icon"`
+	Icon Optional[string] `json:"icon,omitempty"`
 }
 
 // BTHomeSensorGetConfigRequest contains parameters for the BTHomeSensor.GetConfig RPC request.
@@ -49,6 +46,10 @@ func (r *BTHomeSensorGetConfigRequest) Method() string {
 	return "BTHomeSensor.GetConfig"
 }
 
+func (r *BTHomeSensorGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeSensorGetConfigRequest) NewTypedResponse() *BTHomeSensorConfig {
 	return &BTHomeSensorConfig{}
 }
@@ -59,16 +60,20 @@ func (r *BTHomeSensorGetConfigRequest) NewResponse() any {
 
 func (r *BTHomeSensorGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeSensorConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeSensorGetConfigRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 // BTHomeSensorSetConfigRequest contains parameters for the BTHomeSensor.SetConfig RPC request.
@@ -84,6 +89,10 @@ func (r *BTHomeSensorSetConfigRequest) Method() string {
 	return "BTHomeSensor.SetConfig"
 }
 
+func (r *BTHomeSensorSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeSensorSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -94,16 +103,20 @@ func (r *BTHomeSensorSetConfigRequest) NewResponse() any {
 
 func (r *BTHomeSensorSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeSensorSetConfigRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 // BTHomeSensorGetStatusRequst contains parameters for the BTHomeSensor.GetStatus RPC request.
@@ -116,6 +129,10 @@ func (r *BTHomeSensorGetStatusRequest) Method() string {
 	return "BTHomeSensor.GetStatus"
 }
 
+func (r *BTHomeSensorGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *BTHomeSensorGetStatusRequest) NewTypedResponse() *BTHomeSensorStatus {
 	return &BTHomeSensorStatus{}
 }
@@ -126,16 +143,20 @@ func (r *BTHomeSensorGetStatusRequest) NewResponse() any {
 
 func (r *BTHomeSensorGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*BTHomeSensorStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *BTHomeSensorGetStatusRequest) Validate() error {
+	v := newValidation(r)
+	v.bthomeID("id", r.ID)
+	return v.err()
 }
 
 // BTHomeSensorStatus describes the status of BTHomeSensor component instances.
@@ -0,0 +1,266 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// https://shelly-api-docs.shelly.cloud/gen2/General/RPCProtocol
+
+const (
+	// JSONRPCVersion is the JSON-RPC version implemented by Gen2 devices.
+	JSONRPCVersion = "2.0"
+
+	announceTimeout = 10 * time.Second
+)
+
+var (
+	// ErrClientClosed is returned when making calls on a Client after Disconnect.
+	ErrClientClosed = errors.New("shelly client is closed")
+)
+
+// RPCFrame is the Gen2 JSON-RPC framing. Requests set Method and Params, responses set Result or
+// Error, and notifications set Method and Params but have no ID.
+type RPCFrame struct {
+	// JSONRPC describes the JSON-RPC version used. This library implements 2.0.
+	JSONRPC string `json:"jsonrpc,omitempty"`
+
+	// ID is a unique identifier for matching responses with requests.
+	ID int64 `json:"id,omitempty"`
+
+	// Src is the name of the source of the frame. For requests this may be an arbitrary string
+	// and the device will use it as the destination of responses and notifications.
+	Src string `json:"src,omitempty"`
+
+	// Dst is the name of the destination of the frame.
+	Dst string `json:"dst,omitempty"`
+
+	// Method is the name of the procedure to be called, or of the notification.
+	Method string `json:"method,omitempty"`
+
+	// Params is the key-value dictionary of parameters for the Method, if any.
+	Params json.RawMessage `json:"params,omitempty"`
+
+	// Result is the response payload of a successful call.
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Error describes a failed call.
+	Error *RPCFrameError `json:"error,omitempty"`
+
+	// Auth authenticates the request on devices with authentication enabled.
+	Auth *RPCAuth `json:"auth,omitempty"`
+}
+
+// IsNotification returns true if the frame is a notification sent by the device.
+func (f *RPCFrame) IsNotification() bool {
+	return f.Method != "" && f.ID == 0
+}
+
+// RPCFrameError is the error object of a failed call.
+type RPCFrameError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Transport carries RPC frames between a Client and a device.
+type Transport interface {
+	// RoundTrip sends the request frame and returns the matching response frame.
+	RoundTrip(ctx context.Context, req *RPCFrame) (*RPCFrame, error)
+
+	// Close releases any resources held by the transport.
+	Close() error
+}
+
+// NotifyingTransport is implemented by transports which receive unsolicited notification frames
+// from the device (ex. WebSocket).
+type NotifyingTransport interface {
+	Transport
+
+	// SetNotificationHandler registers the function called for each notification frame.
+	SetNotificationHandler(func(*RPCFrame))
+}
+
+// ReconnectingTransport is implemented by transports which re-establish dropped connections.
+type ReconnectingTransport interface {
+	Transport
+
+	// SetReconnectHandler registers the function called after the connection is re-established.
+	SetReconnectHandler(func())
+}
+
+// Client is a native Gen2 JSON-RPC client. It implements Caller, so every request type's Do
+// method can be used with it, and makes calls with the configured Transport.
+type Client struct {
+	transport Transport
+	src       string
+	nextID    atomic.Int64
+	closed    atomic.Bool
+
+	mu        sync.Mutex
+	challenge *AuthChallenge
+
+	notifications NotificationHub
+
+	// wsOpts configure the transport dialed by NewWebSocketClient.
+	wsOpts []WebSocketOption
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithSource sets the `src` used on requests. The device addresses responses and notifications
+// to this name. By default a random name is generated.
+func WithSource(src string) ClientOption {
+	return func(c *Client) {
+		c.src = src
+	}
+}
+
+// NewClient builds a Client using the provided Transport.
+func NewClient(t Transport, opts ...ClientOption) *Client {
+	c := &Client{
+		transport: t,
+		src:       "go-shelly-" + uuid.NewString()[:8],
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if nt, ok := t.(NotifyingTransport); ok {
+		nt.SetNotificationHandler(c.handleNotification)
+	}
+	if rt, ok := t.(ReconnectingTransport); ok {
+		rt.SetReconnectHandler(c.handleReconnect)
+	}
+	return c
+}
+
+// Source returns the `src` used on requests.
+func (c *Client) Source() string {
+	return c.src
+}
+
+// Transport returns the client's transport.
+func (c *Client) Transport() Transport {
+	return c.transport
+}
+
+// CallRequest implements RequestCaller, making the call with Call. Transports which resend
+// requests without a response (ex. UDPTransport) only resend idempotent requests, unless ctx
+// opts in with WithRetryNonIdempotent.
+func (c *Client) CallRequest(
+	ctx context.Context,
+	req RPCRequestBody,
+	f *RPCFrame,
+	getCreds GetCredsCallback,
+) (*RPCFrame, error) {
+	if req != nil && req.Idempotent() {
+		ctx = context.WithValue(ctx, idempotentRequestKey{}, true)
+	}
+	return c.Call(ctx, f, getCreds)
+}
+
+// Call implements Caller. If the device responds with 401 and getCreds is provided, the request
+// is retried once with a digest auth object. The most recent challenge is cached so subsequent
+// calls authenticate on the first attempt.
+func (c *Client) Call(ctx context.Context, f *RPCFrame, getCreds GetCredsCallback) (*RPCFrame, error) {
+	if c.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	req := &RPCFrame{
+		JSONRPC: JSONRPCVersion,
+		Src:     c.src,
+		Dst:     f.Dst,
+		Method:  f.Method,
+		Params:  f.Params,
+	}
+	var username, password string
+	if getCreds != nil {
+		var err error
+		if username, password, err = getCreds(); err != nil {
+			return nil, fmt.Errorf("fetching credentials: %w", err)
+		}
+		c.mu.Lock()
+		challenge := c.challenge
+		c.mu.Unlock()
+		if challenge != nil {
+			if req.Auth, err = challenge.Authenticate(username, password); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp, err := c.roundTrip(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil && resp.Error.Code == int(ErrRPCUnauthorized) && getCreds != nil {
+		challenge, err := ParseAuthChallenge(resp.Error.Message)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.challenge = challenge
+		c.mu.Unlock()
+		if req.Auth, err = challenge.Authenticate(username, password); err != nil {
+			return nil, err
+		}
+		if resp, err = c.roundTrip(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, req *RPCFrame) (*RPCFrame, error) {
+	req.ID = c.nextID.Add(1)
+	resp, err := c.transport.RoundTrip(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != 0 && resp.ID != req.ID {
+		return nil, fmt.Errorf("response id %d does not match request id %d", resp.ID, req.ID)
+	}
+	return resp, nil
+}
+
+func (c *Client) handleNotification(f *RPCFrame) {
+	c.notifications.Publish(f)
+}
+
+// handleReconnect announces the client on a new connection so notifications resume. Devices
+// may have rebooted, invalidating the cached challenge, so it is discarded.
+func (c *Client) handleReconnect() {
+	c.mu.Lock()
+	c.challenge = nil
+	c.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), announceTimeout)
+	defer cancel()
+	c.Announce(ctx)
+}
+
+// Disconnect closes the client's transport. Calls made afterwards fail with ErrClientClosed.
+func (c *Client) Disconnect(ctx context.Context) error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	return c.transport.Close()
+}
+
+// IsConnected is false once the client is disconnected, or while its transport is
+// reconnecting.
+func (c *Client) IsConnected() bool {
+	if c.closed.Load() {
+		return false
+	}
+	if cc, ok := c.transport.(interface{ IsConnected() bool }); ok {
+		return cc.IsConnected()
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRealm    = "shellypro4pm-f008d1d8b8b8"
+	testPassword = "hunter2"
+	testNonce    = 1625038762
+)
+
+// verifyTestAuth checks the auth object the way a device would.
+func verifyTestAuth(a *RPCAuth) bool {
+	if a == nil || a.Nonce != testNonce {
+		return false
+	}
+	ha1 := sha256Hex(a.Username + ":" + a.Realm + ":" + testPassword)
+	ha2 := sha256Hex(authDummyHA2)
+	return a.Response == sha256Hex(fmt.Sprintf("%s:%d:1:%d:auth:%s", ha1, a.Nonce, a.CNonce, ha2))
+}
+
+func TestHTTPClientDigestAuth(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req RPCFrame
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if !verifyTestAuth(req.Auth) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Digest qop="auth", realm=%q, nonce="%x", algorithm=SHA-256`, testRealm, testNonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(&RPCFrame{
+			ID:     req.ID,
+			Src:    testRealm,
+			Dst:    req.Src,
+			Result: json.RawMessage(`{"was_on":true}`),
+		})
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	c := NewHTTPClient(ts.URL + "/rpc")
+	creds := func() (string, string, error) { return DefaultAuthenticationUsername, testPassword, nil }
+
+	resp, _, err := (&SwitchSetRequest{ID: 0, On: true}).Do(ctx, c, creds)
+	require.NoError(t, err)
+	assert.True(t, resp.WasOn)
+	assert.Equal(t, 2, calls)
+
+	// The challenge is cached so later calls authenticate on the first attempt.
+	_, _, err = (&SwitchSetRequest{ID: 0, On: false}).Do(ctx, c, creds)
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	_, _, err = (&SwitchSetRequest{ID: 0, On: false}).Do(ctx, NewHTTPClient(ts.URL+"/rpc"), nil)
+	assert.ErrorIs(t, err, ErrRPCUnauthorized)
+}
+
+func TestWebSocketClient(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		for {
+			var req RPCFrame
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			conn.WriteJSON(&RPCFrame{
+				Src:    testRealm,
+				Dst:    req.Src,
+				Method: "NotifyStatus",
+				Params: json.RawMessage(`{"ts":1703811195.5,"switch:0":{"id":0,"output":true}}`),
+			})
+			if req.Method != "Switch.GetStatus" {
+				conn.WriteJSON(&RPCFrame{
+					ID:    req.ID,
+					Error: &RPCFrameError{Code: int(ErrRPCNoHandler), Message: "No handler for " + req.Method},
+				})
+				continue
+			}
+			conn.WriteJSON(&RPCFrame{
+				ID:     req.ID,
+				Src:    testRealm,
+				Dst:    req.Src,
+				Result: json.RawMessage(`{"id":0,"output":true}`),
+			})
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	c, err := NewWebSocketClient(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/rpc")
+	require.NoError(t, err)
+	defer c.Disconnect(ctx)
+
+	notified := c.Subscribe(ctx, "NotifyStatus")
+
+	status, _, err := (&SwitchGetStatusRequest{ID: 0}).Do(ctx, c, nil)
+	require.NoError(t, err)
+	assert.True(t, *status.Output)
+	assert.JSONEq(t, `{"ts":1703811195.5,"switch:0":{"id":0,"output":true}}`, string((<-notified).Params))
+
+	_, _, err = (&SwitchToggleRequest{ID: 0}).Do(ctx, c, nil)
+	assert.ErrorIs(t, err, ErrRPCNoHandler)
+}
+
+func TestWebSocketClientReconnectAndSubscribe(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var conns atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		n := conns.Add(1)
+		var held []RPCFrame
+		for {
+			var req RPCFrame
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			switch req.Method {
+			case "Shelly.GetDeviceInfo":
+				conn.WriteJSON(&RPCFrame{ID: req.ID, Result: json.RawMessage(`{"id":"` + testRealm + `"}`)})
+				conn.WriteJSON(&RPCFrame{
+					Src:    testRealm,
+					Method: "NotifyEvent",
+					Params: json.RawMessage(`{"ts":1.5,"events":[{"component":"input:0","id":0,"event":"single_push"}]}`),
+				})
+			case "Switch.GetStatus":
+				// Hold responses until two calls are in flight, then answer in reverse order.
+				held = append(held, req)
+				if len(held) < 2 {
+					continue
+				}
+				for i := len(held) - 1; i >= 0; i-- {
+					conn.WriteJSON(&RPCFrame{ID: held[i].ID, Result: json.RawMessage(fmt.Sprintf(`{"id":%d}`, i))})
+				}
+				held = nil
+				conn.WriteJSON(&RPCFrame{
+					Src:    testRealm,
+					Method: "NotifyStatus",
+					Params: json.RawMessage(`{"ts":2.5,"switch:0":{"id":0,"output":true}}`),
+				})
+				if n == 1 {
+					// Drop the first connection to exercise reconnection.
+					return
+				}
+			}
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := NewWebSocketClient(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/rpc",
+		WithWebSocketOptions(WithReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)))
+	require.NoError(t, err)
+	defer c.Disconnect(ctx)
+
+	statuses := c.SubscribeStatus(ctx)
+	events := c.SubscribeEvent(ctx)
+
+	concurrentCalls := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := (&SwitchGetStatusRequest{ID: 0}).Do(ctx, c, nil)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+	}
+	concurrentCalls()
+	status := <-statuses
+	assert.Equal(t, 2.5, status.Value.TS)
+	require.Len(t, status.Value.Switches, 1)
+	assert.True(t, *status.Value.Switches[0].Output)
+	assert.JSONEq(t, `{"ts":2.5,"switch:0":{"id":0,"output":true}}`, string(status.Raw))
+
+	// The client re-announces itself after reconnecting.
+	event := <-events
+	require.Len(t, event.Value.Events, 1)
+	assert.Equal(t, "single_push", event.Value.Events[0].Event)
+	assert.EqualValues(t, 2, conns.Load())
+
+	concurrentCalls()
+	<-statuses
+}
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type CloudSetConfigRequest struct {
@@ -15,6 +12,10 @@ func (r *CloudSetConfigRequest) Method() string {
 	return "Cloud.SetConfig"
 }
 
+func (r *CloudSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CloudSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -25,16 +26,14 @@ func (r *CloudSetConfigRequest) NewResponse() any {
 
 func (r *CloudSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type CloudConfig struct {
@@ -42,7 +41,7 @@ type CloudConfig struct {
 	Enable bool `json:"enable"`
 
 	// Server is the name of the server to which the device is connected (optional).
-	Server *string `json:"server"`
+	Server Optional[string] `json:"server,omitempty"`
 }
 
 type CloudGetConfigRequest struct{}
@@ -51,6 +50,10 @@ func (r *CloudGetConfigRequest) Method() string {
 	return "Cloud.GetConfig"
 }
 
+func (r *CloudGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CloudGetConfigRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -61,16 +64,14 @@ func (r *CloudGetConfigRequest) NewResponse() any {
 
 func (r *CloudGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type CloudStatus struct {
@@ -83,6 +84,10 @@ func (r *CloudGetStatusRequest) Method() string {
 	return "Cloud.GetStatus"
 }
 
+func (r *CloudGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CloudGetStatusRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -93,14 +98,12 @@ func (r *CloudGetStatusRequest) NewResponse() any {
 
 func (r *CloudGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
@@ -1,6 +1,162 @@
 package shelly
 
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// componentNamespaces maps component types to their method namespace where it isn't the
+// capitalized type.
+var componentNamespaces = map[string]string{
+	"ble":          "BLE",
+	"mqtt":         "MQTT",
+	"ws":           "WS",
+	"devicepower":  "DevicePower",
+	"bthome":       "BTHome",
+	"bthomedevice": "BTHomeDevice",
+	"bthomesensor": "BTHomeSensor",
+	"em":           "EM",
+	"em1":          "EM1",
+	"emdata":       "EMData",
+	"em1data":      "EM1Data",
+	"pm1":          "PM1",
+	"ui":           "UI",
+	"knx":          "KNX",
+}
+
+// ComponentNamespace returns the method namespace of the component with key, ex. "Switch" for
+// "switch:0" or "MQTT" for "mqtt".
+func ComponentNamespace(key string) string {
+	kind, _, _ := strings.Cut(key, ":")
+	if ns, ok := componentNamespaces[kind]; ok {
+		return ns
+	}
+	if kind == "" {
+		return kind
+	}
+	return strings.ToUpper(kind[:1]) + kind[1:]
+}
+
+// readOnlyConfigFields are the dotted paths of fields which GetConfig reports but SetConfig
+// doesn't accept, by component type. Those under "*" apply to every type.
+var readOnlyConfigFields = map[string][]string{
+	"*":    {"id"},
+	"sys":  {"cfg_rev", "device.mac", "device.fw_id", "device.profile"},
+	"wifi": {"ap.ssid", "ap.is_open", "sta.is_open", "sta1.is_open"},
+}
+
+// writeOnlyConfigFields are the dotted paths of fields which SetConfig accepts but GetConfig
+// never reports, by component type.
+var writeOnlyConfigFields = map[string][]string{
+	"wifi": {"ap.pass", "sta.pass", "sta1.pass"},
+	"mqtt": {"pass"},
+}
+
+// StripReadOnlyConfig removes the read-only fields, ex. id and sys.device.mac, from config, the
+// decoded config of the component with key, so it can be passed to SetConfig. The profile of
+// multi-profile devices is changed with Shelly.SetProfile, so sys.device.profile is removed too.
+func StripReadOnlyConfig(key string, config map[string]any) {
+	kind, _, _ := strings.Cut(key, ":")
+	for _, paths := range [][]string{readOnlyConfigFields["*"], readOnlyConfigFields[kind]} {
+		for _, path := range paths {
+			deleteConfigField(config, path)
+		}
+	}
+}
+
+// StripWriteOnlyConfig removes the write-only fields, ex. wifi sta.pass, from config, the decoded
+// config of the component with key.
+func StripWriteOnlyConfig(key string, config map[string]any) {
+	kind, _, _ := strings.Cut(key, ":")
+	for _, path := range writeOnlyConfigFields[kind] {
+		deleteConfigField(config, path)
+	}
+}
+
+// IsWriteOnlyConfigField is true if path, a dotted path within the config of the component with
+// key (ex. "sta.pass" for "wifi"), is a field which GetConfig never reports, ex. a password.
+func IsWriteOnlyConfigField(key, path string) bool {
+	kind, _, _ := strings.Cut(key, ":")
+	for _, p := range writeOnlyConfigFields[kind] {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteConfigField(config map[string]any, path string) {
+	for {
+		field, rest, nested := strings.Cut(path, ".")
+		if !nested {
+			delete(config, field)
+			return
+		}
+		var ok bool
+		if config, ok = config[field].(map[string]any); !ok {
+			return
+		}
+		path = rest
+	}
+}
+
 type SetConfigResponse struct {
 	// RestartRequired is true if the system must be restarted for a pending change to take effect.
 	RestartRequired bool `json:"restart_required,omitempty"`
 }
+
+// ComponentSetConfigRequest calls the SetConfig method of the component with Key, for callers
+// which address components by key rather than with the typed requests, ex. SwitchSetConfigRequest.
+type ComponentSetConfigRequest struct {
+	// Key of the component, ex. "switch:0" or "sys".
+	Key string
+
+	// Config is the partial configuration to apply, ex. a *SwitchConfig or a map.
+	Config any
+}
+
+func (r *ComponentSetConfigRequest) Method() string {
+	return ComponentNamespace(r.Key) + ".SetConfig"
+}
+
+func (r *ComponentSetConfigRequest) Idempotent() bool {
+	return true
+}
+
+func (r *ComponentSetConfigRequest) NewTypedResponse() *SetConfigResponse {
+	return &SetConfigResponse{}
+}
+
+func (r *ComponentSetConfigRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *ComponentSetConfigRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*SetConfigResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+// MarshalJSON implements json.Marshaler, adding the id of component instances.
+func (r *ComponentSetConfigRequest) MarshalJSON() ([]byte, error) {
+	params := struct {
+		ID     *int `json:"id,omitempty"`
+		Config any  `json:"config"`
+	}{Config: r.Config}
+	if _, id, ok := strings.Cut(r.Key, ":"); ok {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, err
+		}
+		params.ID = &n
+	}
+	return json.Marshal(params)
+}
@@ -0,0 +1,52 @@
+package shelly
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentSetConfigRequest(t *testing.T) {
+	for _, tc := range []struct {
+		key    string
+		method string
+		params string
+	}{
+		{"switch:0", "Switch.SetConfig", `{"id":0,"config":{"name":"Porch"}}`},
+		{"sys", "Sys.SetConfig", `{"config":{"name":"Porch"}}`},
+		{"mqtt", "MQTT.SetConfig", `{"config":{"name":"Porch"}}`},
+		{"boolean:200", "Boolean.SetConfig", `{"id":200,"config":{"name":"Porch"}}`},
+	} {
+		req := &ComponentSetConfigRequest{Key: tc.key, Config: map[string]any{"name": "Porch"}}
+		assert.Equal(t, tc.method, req.Method(), tc.key)
+		b, err := json.Marshal(req)
+		require.NoError(t, err)
+		assert.JSONEq(t, tc.params, string(b), tc.key)
+	}
+}
+
+func TestConfigFieldAccess(t *testing.T) {
+	config := map[string]any{
+		"id":      1,
+		"cfg_rev": 7,
+		"device":  map[string]any{"name": "Porch", "mac": "B8D61A000001", "fw_id": "20240101-000000/1.2.0"},
+		"sntp":    map[string]any{"server": "time.google.com"},
+	}
+	StripReadOnlyConfig("sys", config)
+	assert.Equal(t, map[string]any{
+		"device": map[string]any{"name": "Porch"},
+		"sntp":   map[string]any{"server": "time.google.com"},
+	}, config)
+
+	wifi := map[string]any{"sta": map[string]any{"ssid": "home", "pass": "hunter2", "is_open": false}, "ap": true}
+	StripReadOnlyConfig("wifi", wifi)
+	StripWriteOnlyConfig("wifi", wifi)
+	assert.Equal(t, map[string]any{"sta": map[string]any{"ssid": "home"}, "ap": true}, wifi)
+
+	assert.True(t, IsWriteOnlyConfigField("wifi", "sta1.pass"))
+	assert.True(t, IsWriteOnlyConfigField("mqtt", "pass"))
+	assert.False(t, IsWriteOnlyConfigField("mqtt", "user"))
+	assert.False(t, IsWriteOnlyConfigField("switch:0", "pass"))
+}
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type CoverGetConfigRequest struct {
@@ -16,6 +13,10 @@ func (r *CoverGetConfigRequest) Method() string {
 	return "Cover.GetConfig"
 }
 
+func (r *CoverGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CoverGetConfigRequest) NewTypedResponse() *CoverConfig {
 	return &CoverConfig{}
 }
@@ -26,16 +27,14 @@ func (r *CoverGetConfigRequest) NewResponse() any {
 
 func (r *CoverGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type CoverSetConfigRequest struct {
@@ -50,6 +49,10 @@ func (r *CoverSetConfigRequest) Method() string {
 	return "Cover.SetConfig"
 }
 
+func (r *CoverSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CoverSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -60,16 +63,14 @@ func (r *CoverSetConfigRequest) NewResponse() any {
 
 func (r *CoverSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type CoverConfig struct {
@@ -77,7 +78,7 @@ type CoverConfig struct {
 	ID int `json:"id"`
 
 	// Name of the cover instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// InMode is the mode of the associated input. One of single, dual or detached,
 	// only present if there is at least one input associated with the Cover instance.
@@ -226,7 +227,7 @@ type CoverSafetySwitchConfig struct {
 	//   the direction opposite to the one that was interrupted (for example, if the safety
 	//   switch was hit while opening, Cover can only be commanded to close if the switch is
 	//   not disengaged)
-	AllowedMove *string `json:"allowed_move,omitempty"`
+	AllowedMove Optional[string] `json:"allowed_move,omitempty"`
 }
 
 type CoverGetStatusRequest struct {
@@ -238,6 +239,10 @@ func (r *CoverGetStatusRequest) Method() string {
 	return "Cover.GetStatus"
 }
 
+func (r *CoverGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CoverGetStatusRequest) NewTypedResponse() *CoverStatus {
 	return &CoverStatus{}
 }
@@ -248,16 +253,14 @@ func (r *CoverGetStatusRequest) NewResponse() any {
 
 func (r *CoverGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // CoverStatus describes the current state of the Cover.
@@ -343,6 +346,10 @@ func (r *CoverCalibrateRequest) Method() string {
 	return "Cover.Calibrate"
 }
 
+func (r *CoverCalibrateRequest) Idempotent() bool {
+	return false
+}
+
 func (r *CoverCalibrateRequest) NewTypedResponse() *CoverCalibrateRespose {
 	return &CoverCalibrateRespose{}
 }
@@ -353,16 +360,14 @@ func (r *CoverCalibrateRequest) NewResponse() any {
 
 func (r *CoverCalibrateRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverCalibrateRespose,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // CoverCalibrateRespose is the RPC response for Cover.Calibrate.
@@ -384,6 +389,11 @@ func (r *CoverOpenRequest) Method() string {
 	return "Cover.Open"
 }
 
+func (r *CoverOpenRequest) Idempotent() bool {
+	// A timed move travels further each time it's repeated.
+	return r.Duration == nil
+}
+
 func (r *CoverOpenRequest) NewTypedResponse() *CoverOpenResponse {
 	return &CoverOpenResponse{}
 }
@@ -394,16 +404,14 @@ func (r *CoverOpenRequest) NewResponse() any {
 
 func (r *CoverOpenRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverOpenResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // CoverOpenResponse is the RPC response for Cover.Open.
@@ -425,6 +433,11 @@ func (r *CoverCloseRequest) Method() string {
 	return "Cover.Close"
 }
 
+func (r *CoverCloseRequest) Idempotent() bool {
+	// A timed move travels further each time it's repeated.
+	return r.Duration == nil
+}
+
 func (r *CoverCloseRequest) NewTypedResponse() *CoverCloseResponse {
 	return &CoverCloseResponse{}
 }
@@ -435,16 +448,14 @@ func (r *CoverCloseRequest) NewResponse() any {
 
 func (r *CoverCloseRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverCloseResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // CoverCloseResponse is the RPC response for Cover.Close.
@@ -460,6 +471,10 @@ func (r *CoverStopRequest) Method() string {
 	return "Cover.Stop"
 }
 
+func (r *CoverStopRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CoverStopRequest) NewTypedResponse() *CoverStopResponse {
 	return &CoverStopResponse{}
 }
@@ -470,16 +485,14 @@ func (r *CoverStopRequest) NewResponse() any {
 
 func (r *CoverStopRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverStopResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // CoverStopResponse is the RPC response for Cover.Stop.
@@ -506,6 +519,11 @@ func (r *CoverGoToPositionRequest) Method() string {
 	return "Cover.GoToPosition"
 }
 
+func (r *CoverGoToPositionRequest) Idempotent() bool {
+	// A relative move travels further each time it's repeated.
+	return r.Rel == nil
+}
+
 func (r *CoverGoToPositionRequest) NewTypedResponse() *CoverGoToPositionResponse {
 	return &CoverGoToPositionResponse{}
 }
@@ -516,16 +534,14 @@ func (r *CoverGoToPositionRequest) NewResponse() any {
 
 func (r *CoverGoToPositionRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverGoToPositionResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // CoverGoToPositionResponse is the RPC response for Cover.GoToPosition.
@@ -544,6 +560,10 @@ func (r *CoverResetCountersRequest) Method() string {
 	return "Cover.ResetCounters"
 }
 
+func (r *CoverResetCountersRequest) Idempotent() bool {
+	return true
+}
+
 func (r *CoverResetCountersRequest) NewTypedResponse() *CoverResetCountersResponse {
 	return &CoverResetCountersResponse{}
 }
@@ -554,16 +574,14 @@ func (r *CoverResetCountersRequest) NewResponse() any {
 
 func (r *CoverResetCountersRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*CoverResetCountersResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // CoverResetCountersResponse is the RPC response for Cover.ResetCounters.
@@ -571,3 +589,30 @@ type CoverResetCountersResponse struct {
 	// AEnergy contains information about the active energy counter prior to reset.
 	AEnergy *EnergyCounters `json:"aenergy,omitempty"`
 }
+
+// coverEvents are the events emitted as covers move.
+var coverEvents = map[string]func() EventData{
+	"opening": func() EventData { return &CoverMovingEvent{} },
+	"closing": func() EventData { return &CoverMovingEvent{} },
+	"stopped": func() EventData { return &CoverStoppedEvent{} },
+	"open":    func() EventData { return &CoverStoppedEvent{} },
+	"closed":  func() EventData { return &CoverStoppedEvent{} },
+}
+
+// CoverMovingEvent is the payload of the opening and closing events.
+type CoverMovingEvent struct {
+	// TargetPos is the position in percent the cover is moving to. Only present if the cover
+	// is calibrated and moving to a specific position.
+	TargetPos *float64 `json:"target_pos,omitempty"`
+}
+
+func (*CoverMovingEvent) eventData() {}
+
+// CoverStoppedEvent is the payload of the stopped, open, and closed events.
+type CoverStoppedEvent struct {
+	// CurrentPos is the position in percent where the cover stopped. Only present if the
+	// cover is calibrated.
+	CurrentPos *float64 `json:"current_pos,omitempty"`
+}
+
+func (*CoverStoppedEvent) eventData() {}
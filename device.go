@@ -0,0 +1,545 @@
+package shelly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// scriptCodeChunkSize is the amount of code sent in each Script.PutCode call. Devices
+	// reject larger requests.
+	scriptCodeChunkSize = 1024
+)
+
+var (
+	// ErrComponentNotPresent is returned by Device accessors for component IDs which the
+	// device's specs don't include.
+	ErrComponentNotPresent = errors.New("component not present on device")
+)
+
+// DeviceOption configures a Device.
+type DeviceOption func(*Device)
+
+// WithDeviceSpecs sets the specs which component IDs are validated against.
+func WithDeviceSpecs(specs DeviceSpecs) DeviceOption {
+	return func(d *Device) {
+		d.specs = &specs
+	}
+}
+
+// Device is a handle to a Gen2 device, pairing an RPC channel with credentials so calls can be
+// made through per-component accessors:
+//
+//	dev := shelly.NewDevice(c, creds)
+//	wasOn, err := dev.Switch(0).Set(ctx, true)
+//
+// If the device's specs are known, via WithDeviceSpecs or LoadSpecs, accessors for components
+// the device doesn't have fail with ErrComponentNotPresent without making a call.
+type Device struct {
+	c     Caller
+	creds GetCredsCallback
+	specs *DeviceSpecs
+}
+
+// NewDevice returns a Device which makes calls over c, authenticating with creds.
+func NewDevice(c Caller, creds GetCredsCallback, opts ...DeviceOption) *Device {
+	d := &Device{c: c, creds: creds}
+	for _, o := range opts {
+		o(d)
+	}
+	return d
+}
+
+// Do makes req, decoding the result into resp. It's useful for requests which don't have an
+// accessor.
+func (d *Device) Do(ctx context.Context, req RPCRequestBody, resp any) (*RPCFrame, error) {
+	return Do(ctx, d.c, d.creds, req, resp)
+}
+
+// Channel returns the device's RPC channel.
+func (d *Device) Channel() Caller {
+	return d.c
+}
+
+// Specs returns the specs component IDs are validated against, or nil if they're unknown.
+func (d *Device) Specs() *DeviceSpecs {
+	return d.specs
+}
+
+// LoadSpecs resolves the device's specs from its app and profile, and validates component IDs
+// against them from then on.
+func (d *Device) LoadSpecs(ctx context.Context) (*DeviceSpecs, error) {
+	info, err := d.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := AppToDeviceSpecs(info.App, info.Profile)
+	if err != nil {
+		return nil, err
+	}
+	d.specs = &specs
+	return d.specs, nil
+}
+
+// Info returns the device's identification.
+func (d *Device) Info(ctx context.Context) (*ShellyGetDeviceInfoResponse, error) {
+	resp, _, err := (&ShellyGetDeviceInfoRequest{}).Do(ctx, d.c, d.creds)
+	return resp, err
+}
+
+// Status returns the status of every component.
+func (d *Device) Status(ctx context.Context) (*ShellyGetStatusResponse, error) {
+	resp, _, err := (&ShellyGetStatusRequest{}).Do(ctx, d.c, d.creds)
+	return resp, err
+}
+
+// Config returns the configuration of every component.
+func (d *Device) Config(ctx context.Context) (*ShellyGetConfigResponse, error) {
+	resp, _, err := (&ShellyGetConfigRequest{}).Do(ctx, d.c, d.creds)
+	return resp, err
+}
+
+// Reboot restarts the device.
+func (d *Device) Reboot(ctx context.Context) error {
+	_, _, err := (&ShellyRebootRequest{}).Do(ctx, d.c, d.creds)
+	return err
+}
+
+// check returns ErrComponentNotPresent if the specs are known and don't include the component.
+func (d *Device) check(component string, id, count int) error {
+	if d.specs == nil || (id >= 0 && id < count) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s:%d", ErrComponentNotPresent, component, id)
+}
+
+// Sys returns a handle to the sys component.
+func (d *Device) Sys() *SysHandle {
+	return &SysHandle{d: d}
+}
+
+// Switch returns a handle to the switch with the given id.
+func (d *Device) Switch(id int) *SwitchHandle {
+	var count int
+	if d.specs != nil {
+		count = d.specs.Switches
+	}
+	return &SwitchHandle{d: d, id: id, err: d.check("switch", id, count)}
+}
+
+// Cover returns a handle to the cover with the given id.
+func (d *Device) Cover(id int) *CoverHandle {
+	var count int
+	if d.specs != nil {
+		count = d.specs.Covers
+	}
+	return &CoverHandle{d: d, id: id, err: d.check("cover", id, count)}
+}
+
+// Light returns a handle to the light with the given id.
+func (d *Device) Light(id int) *LightHandle {
+	var count int
+	if d.specs != nil {
+		count = d.specs.Lights
+	}
+	return &LightHandle{d: d, id: id, err: d.check("light", id, count)}
+}
+
+// Input returns a handle to the input with the given id.
+func (d *Device) Input(id int) *InputHandle {
+	var count int
+	if d.specs != nil {
+		count = d.specs.Inputs
+	}
+	return &InputHandle{d: d, id: id, err: d.check("input", id, count)}
+}
+
+// Scripts returns a handle to the device's scripts.
+func (d *Device) Scripts() *ScriptsHandle {
+	var err error
+	if d.specs != nil && d.specs.Scripts == 0 {
+		err = fmt.Errorf("%w: script", ErrComponentNotPresent)
+	}
+	return &ScriptsHandle{d: d, err: err}
+}
+
+// SysHandle makes calls to the sys component.
+type SysHandle struct {
+	d *Device
+}
+
+// Status returns the component's status.
+func (h *SysHandle) Status(ctx context.Context) (*SysStatus, error) {
+	resp, _, err := (&SysGetStatusRequest{}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// Config returns the component's configuration.
+func (h *SysHandle) Config(ctx context.Context) (*SysConfig, error) {
+	resp, _, err := (&SysGetConfigRequest{}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// SetConfig updates the component's configuration.
+func (h *SysHandle) SetConfig(ctx context.Context, config *SysConfig) (*SetConfigResponse, error) {
+	resp, _, err := (&SysSetConfigRequest{Config: *config}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// SwitchHandle makes calls to a switch component.
+type SwitchHandle struct {
+	d   *Device
+	id  int
+	err error
+}
+
+// ID returns the component's ID.
+func (h *SwitchHandle) ID() int {
+	return h.id
+}
+
+// Set turns the switch on or off, returning its previous state.
+func (h *SwitchHandle) Set(ctx context.Context, on bool) (bool, error) {
+	if h.err != nil {
+		return false, h.err
+	}
+	resp, _, err := (&SwitchSetRequest{ID: h.id, On: on}).Do(ctx, h.d.c, h.d.creds)
+	if err != nil {
+		return false, err
+	}
+	return resp.WasOn, nil
+}
+
+// Toggle flips the switch, returning its previous state.
+func (h *SwitchHandle) Toggle(ctx context.Context) (bool, error) {
+	if h.err != nil {
+		return false, h.err
+	}
+	resp, _, err := (&SwitchToggleRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	if err != nil {
+		return false, err
+	}
+	return resp.WasOn, nil
+}
+
+// Status returns the component's status.
+func (h *SwitchHandle) Status(ctx context.Context) (*SwitchStatus, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&SwitchGetStatusRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// Config returns the component's configuration.
+func (h *SwitchHandle) Config(ctx context.Context) (*SwitchConfig, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&SwitchGetConfigRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// SetConfig updates the component's configuration.
+func (h *SwitchHandle) SetConfig(ctx context.Context, config *SwitchConfig) (*SetConfigResponse, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&SwitchSetConfigRequest{ID: h.id, Config: *config}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// CoverHandle makes calls to a cover component.
+type CoverHandle struct {
+	d   *Device
+	id  int
+	err error
+}
+
+// ID returns the component's ID.
+func (h *CoverHandle) ID() int {
+	return h.id
+}
+
+// Open fully opens the cover.
+func (h *CoverHandle) Open(ctx context.Context) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&CoverOpenRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// Close fully closes the cover.
+func (h *CoverHandle) Close(ctx context.Context) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&CoverCloseRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// Stop stops the cover.
+func (h *CoverHandle) Stop(ctx context.Context) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&CoverStopRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// GoTo moves the cover to pos, in percent. The cover must be calibrated.
+func (h *CoverHandle) GoTo(ctx context.Context, pos float64) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&CoverGoToPositionRequest{ID: h.id, Pos: &pos}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// Calibrate starts calibration of the cover.
+func (h *CoverHandle) Calibrate(ctx context.Context) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&CoverCalibrateRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// Status returns the component's status.
+func (h *CoverHandle) Status(ctx context.Context) (*CoverStatus, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&CoverGetStatusRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// Config returns the component's configuration.
+func (h *CoverHandle) Config(ctx context.Context) (*CoverConfig, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&CoverGetConfigRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// SetConfig updates the component's configuration.
+func (h *CoverHandle) SetConfig(ctx context.Context, config *CoverConfig) (*SetConfigResponse, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&CoverSetConfigRequest{ID: h.id, Config: *config}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// LightHandle makes calls to a light component.
+type LightHandle struct {
+	d   *Device
+	id  int
+	err error
+}
+
+// ID returns the component's ID.
+func (h *LightHandle) ID() int {
+	return h.id
+}
+
+// Set turns the light on or off.
+func (h *LightHandle) Set(ctx context.Context, on bool) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&LightSetRequest{ID: h.id, On: &on}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// SetBrightness turns the light on at brightness, in percent.
+func (h *LightHandle) SetBrightness(ctx context.Context, brightness float64) error {
+	if h.err != nil {
+		return h.err
+	}
+	on := true
+	_, _, err := (&LightSetRequest{ID: h.id, On: &on, Brightness: &brightness}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// Toggle flips the light.
+func (h *LightHandle) Toggle(ctx context.Context) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&LightToggleRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
+
+// Status returns the component's status.
+func (h *LightHandle) Status(ctx context.Context) (*LightStatus, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&LightGetStatusRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// Config returns the component's configuration.
+func (h *LightHandle) Config(ctx context.Context) (*LightConfig, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&LightGetConfigRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// SetConfig updates the component's configuration.
+func (h *LightHandle) SetConfig(ctx context.Context, config *LightConfig) (*SetConfigResponse, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&LightSetConfigRequest{ID: h.id, Config: *config}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// InputHandle makes calls to an input component.
+type InputHandle struct {
+	d   *Device
+	id  int
+	err error
+}
+
+// ID returns the component's ID.
+func (h *InputHandle) ID() int {
+	return h.id
+}
+
+// Status returns the component's status.
+func (h *InputHandle) Status(ctx context.Context) (*InputStatus, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&InputGetStatusRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// Config returns the component's configuration.
+func (h *InputHandle) Config(ctx context.Context) (*InputConfig, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&InputGetConfigRequest{ID: h.id}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// SetConfig updates the component's configuration.
+func (h *InputHandle) SetConfig(ctx context.Context, config *InputConfig) (*SetConfigResponse, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&InputSetConfigRequest{ID: h.id, Config: *config}).Do(ctx, h.d.c, h.d.creds)
+	return resp, err
+}
+
+// ScriptsHandle makes calls to manage scripts.
+type ScriptsHandle struct {
+	d   *Device
+	err error
+}
+
+// List returns the device's scripts.
+func (h *ScriptsHandle) List(ctx context.Context) ([]ScriptListScript, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	resp, _, err := (&ScriptListRequest{}).Do(ctx, h.d.c, h.d.creds)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Scripts, nil
+}
+
+// Create adds an empty script, returning its ID.
+func (h *ScriptsHandle) Create(ctx context.Context, name string) (int, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+	resp, _, err := (&ScriptCreateRequest{Name: &name}).Do(ctx, h.d.c, h.d.creds)
+	if err != nil {
+		return 0, err
+	}
+	return resp.ID, nil
+}
+
+// PutCode replaces the code of the script with the given id. Long code is uploaded in chunks.
+func (h *ScriptsHandle) PutCode(ctx context.Context, id int, code string) error {
+	if h.err != nil {
+		return h.err
+	}
+	for offset := 0; offset == 0 || offset < len(code); {
+		end := min(offset+scriptCodeChunkSize, len(code))
+		// Split between characters; a partial UTF-8 sequence can't be encoded as JSON.
+		for end < len(code) && end > offset+1 && !utf8.RuneStart(code[end]) {
+			end--
+		}
+		req := &ScriptPutCodeRequest{ID: id, Code: code[offset:end], Append: offset > 0}
+		if _, _, err := req.Do(ctx, h.d.c, h.d.creds); err != nil {
+			return err
+		}
+		offset = end
+	}
+	return nil
+}
+
+// GetCode returns the code of the script with the given id. Long code is downloaded in chunks.
+func (h *ScriptsHandle) GetCode(ctx context.Context, id int) (string, error) {
+	if h.err != nil {
+		return "", h.err
+	}
+	var code strings.Builder
+	for {
+		req := &ScriptGetCodeRequest{ID: id, Offset: code.Len()}
+		resp, _, err := req.Do(ctx, h.d.c, h.d.creds)
+		if err != nil {
+			return "", err
+		}
+		code.WriteString(resp.Data)
+		if resp.Left <= 0 || resp.Data == "" {
+			return code.String(), nil
+		}
+	}
+}
+
+// Start runs the script with the given id, returning true if it was already running.
+func (h *ScriptsHandle) Start(ctx context.Context, id int) (bool, error) {
+	if h.err != nil {
+		return false, h.err
+	}
+	resp, _, err := (&ScriptStartRequest{ID: id}).Do(ctx, h.d.c, h.d.creds)
+	if err != nil {
+		return false, err
+	}
+	return resp.WasRunning, nil
+}
+
+// Stop stops the script with the given id, returning true if it was running.
+func (h *ScriptsHandle) Stop(ctx context.Context, id int) (bool, error) {
+	if h.err != nil {
+		return false, h.err
+	}
+	resp, _, err := (&ScriptStopRequest{ID: id}).Do(ctx, h.d.c, h.d.creds)
+	if err != nil {
+		return false, err
+	}
+	return resp.WasRunning, nil
+}
+
+// Delete removes the script with the given id.
+func (h *ScriptsHandle) Delete(ctx context.Context, id int) error {
+	if h.err != nil {
+		return h.err
+	}
+	_, _, err := (&ScriptDeleteRequest{ID: id}).Do(ctx, h.d.c, h.d.creds)
+	return err
+}
@@ -0,0 +1,89 @@
+package shelly_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/jcodybaker/go-shelly/pkg/shellysim"
+)
+
+func TestDevice(t *testing.T) {
+	ctx := context.Background()
+	specs, err := shelly.AppToDeviceSpecs("Pro4PM", "")
+	require.NoError(t, err)
+	sim := shellysim.New(specs, shellysim.WithModel("Pro4PM", "SPSW-104PE16EU"), shellysim.WithPassword("hunter2"))
+	creds := func() (string, string, error) { return shelly.DefaultAuthenticationUsername, "hunter2", nil }
+	dev := shelly.NewDevice(sim, creds)
+
+	// Without specs, IDs are checked by the device.
+	_, err = dev.Switch(7).Status(ctx)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnknownComponentID)
+
+	loaded, err := dev.LoadSpecs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, loaded.Switches)
+	_, err = dev.Switch(7).Status(ctx)
+	assert.ErrorIs(t, err, shelly.ErrComponentNotPresent)
+	assert.ErrorIs(t, dev.Cover(0).Open(ctx), shelly.ErrComponentNotPresent)
+
+	wasOn, err := dev.Switch(2).Set(ctx, true)
+	require.NoError(t, err)
+	assert.False(t, wasOn)
+	status, err := dev.Switch(2).Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, *status.Output)
+
+	sys, err := dev.Sys().Config(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, sys.Device)
+
+	id, err := dev.Scripts().Create(ctx, "long")
+	require.NoError(t, err)
+	code := strings.Repeat("print('hello');\n", 200)
+	require.NoError(t, dev.Scripts().PutCode(ctx, id, code))
+	var got struct {
+		Data string `json:"data"`
+	}
+	_, err = dev.Do(ctx, &shelly.RawRequest{Cmd: "Script.GetCode", Args: json.RawMessage(fmt.Sprintf(`{"id":%d,"len":10000}`, id))}, &got)
+	require.NoError(t, err)
+	assert.Equal(t, code, got.Data)
+	downloaded, err := dev.Scripts().GetCode(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, code, downloaded)
+	// Multi-byte characters straddle the chunk boundaries.
+	code = strings.Repeat("// ½°€🔌\n", 300)
+	require.NoError(t, dev.Scripts().PutCode(ctx, id, code))
+	downloaded, err = dev.Scripts().GetCode(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, code, downloaded)
+
+	scripts, err := dev.Scripts().List(ctx)
+	require.NoError(t, err)
+	require.Len(t, scripts, 1)
+	assert.Equal(t, "long", scripts[0].Name)
+}
+
+func TestDeviceCover(t *testing.T) {
+	ctx := context.Background()
+	clock := shellysim.NewVirtualClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	specs, err := shelly.AppToDeviceSpecs("Plus2PM", "cover")
+	require.NoError(t, err)
+	dev := shelly.NewDevice(shellysim.NewCoverDevice(shellysim.WithClock(clock)), nil, shelly.WithDeviceSpecs(specs))
+
+	assert.ErrorIs(t, dev.Cover(1).Open(ctx), shelly.ErrComponentNotPresent)
+	require.NoError(t, dev.Cover(0).Calibrate(ctx))
+	clock.Advance(time.Minute)
+	require.NoError(t, dev.Cover(0).GoTo(ctx, 50))
+	clock.Advance(time.Minute)
+	status, err := dev.Cover(0).Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, *status.CurrentPos)
+}
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type DevicePowerStatus struct {
@@ -45,6 +42,10 @@ func (r *DevicePowerGetStatusRequest) Method() string {
 	return "DevicePower.GetStatus"
 }
 
+func (r *DevicePowerGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *DevicePowerGetStatusRequest) NewTypedResponse() *DevicePowerStatus {
 	return &DevicePowerStatus{}
 }
@@ -55,14 +56,12 @@ func (r *DevicePowerGetStatusRequest) NewResponse() any {
 
 func (r *DevicePowerGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*DevicePowerStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
@@ -4,35 +4,97 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
+// GetCredsCallback returns the username and password used to authenticate with the device.
+type GetCredsCallback func() (username, password string, err error)
+
+// Caller makes RPC calls to a device. Client implements it, as do the middlewares which wrap a
+// Caller (ex. RetryClient, Limiter and InterceptedClient), so every request type's Do method can
+// be used with any of them.
+type Caller interface {
+	// Call sends req, which has Method and Params set, and returns the response frame. Errors
+	// reported by the device are returned in the frame's Error rather than as err. If getCreds
+	// is provided it's used to authenticate when the device requires it.
+	Call(ctx context.Context, req *RPCFrame, getCreds GetCredsCallback) (*RPCFrame, error)
+}
+
+// RequestCaller is implemented by callers which act on the typed request as well as the frame,
+// ex. RetryClient. Do uses CallRequest rather than Call when it's available.
+type RequestCaller interface {
+	Caller
+
+	// CallRequest makes the call described by f, which is the encoding of req.
+	CallRequest(
+		ctx context.Context,
+		req RPCRequestBody,
+		f *RPCFrame,
+		getCreds GetCredsCallback,
+	) (*RPCFrame, error)
+}
+
+// Call makes req and returns its typed response. Request types implement their Do method with
+// Call, so adding a request only requires its Method, Idempotent and NewTypedResponse methods.
+func Call[Req TypedRequest[Resp], Resp any](
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+	req Req,
+) (*Resp, *RPCFrame, error) {
+	resp := req.NewTypedResponse()
+	raw, err := Do(ctx, c, credsCallback, req, resp)
+	return resp, raw, err
+}
+
 func Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 	req RPCRequestBody,
 	resp any,
-) (*frame.Response, error) {
-	args, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshalling shelly rpc request: %w", err)
+) (*RPCFrame, error) {
+	if enforce, _ := ctx.Value(validateRequestsKey{}).(bool); enforce {
+		if err := Validate(req); err != nil {
+			return nil, err
+		}
 	}
-	command := &frame.Command{
-		Cmd:  req.Method(),
-		Args: json.RawMessage(args),
+	f, err := NewRequestFrame(req)
+	if err != nil {
+		return nil, err
 	}
-	rawResp, err := c.Call(ctx, "", command, credsCallback)
+	rawResp, err := callRequest(ctx, c, req, f, credsCallback)
 	if err != nil {
 		return rawResp, fmt.Errorf("making shelly rpc request: %w", err)
 	}
-	if rawResp.Status != 0 {
-		return rawResp, &BadStatusWithMessageError{Status: ShellyErrorCode(rawResp.Status), Msg: rawResp.StatusMsg}
+	if err := ResponseError(rawResp, nil); err != nil {
+		return rawResp, err
 	}
-	if err := json.Unmarshal(rawResp.Response, resp); err != nil {
+	if err := json.Unmarshal(rawResp.Result, resp); err != nil {
 		return rawResp, fmt.Errorf("failed to unmarshal response body: %w", err)
 	}
 	return rawResp, err
 }
+
+// NewRequestFrame encodes req as a request frame. The JSON-RPC version, ID and source are set
+// by the Client which sends it.
+func NewRequestFrame(req RPCRequestBody) (*RPCFrame, error) {
+	args, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling shelly rpc request: %w", err)
+	}
+	return &RPCFrame{Method: req.Method(), Params: args}, nil
+}
+
+// callRequest makes the call via CallRequest if c is a RequestCaller, or Call otherwise.
+func callRequest(
+	ctx context.Context,
+	c Caller,
+	req RPCRequestBody,
+	f *RPCFrame,
+	getCreds GetCredsCallback,
+) (*RPCFrame, error) {
+	if rc, ok := c.(RequestCaller); ok && req != nil {
+		return rc.CallRequest(ctx, req, f, getCreds)
+	}
+	return c.Call(ctx, f, getCreds)
+}
@@ -0,0 +1,29 @@
+package shelly_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/jcodybaker/go-shelly/pkg/shellytest"
+)
+
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+	fake := shellytest.New(t)
+	fake.ExpectRequest(&shelly.SwitchSetRequest{ID: 1, On: true}).
+		Return(map[string]any{"was_on": true})
+	fake.Expect("Switch.Toggle").ReturnError(shelly.ErrRPCUnknownComponentID, "switch:9 not found")
+
+	// The response type is inferred from the request.
+	resp, raw, err := shelly.Call(ctx, fake, nil, &shelly.SwitchSetRequest{ID: 1, On: true})
+	require.NoError(t, err)
+	require.NotNil(t, raw)
+	assert.True(t, resp.WasOn)
+
+	_, _, err = shelly.Call(ctx, fake, nil, &shelly.SwitchToggleRequest{ID: 9})
+	assert.ErrorIs(t, err, shelly.ErrRPCUnknownComponentID)
+}
@@ -1,8 +1,4 @@
+// Package shelly is a native Go client for the Shelly Gen2 JSON-RPC API. Requests are made with
+// a Client over HTTP, WebSocket, MQTT or UDP; channels built with mgrpc can be used through
+// github.com/jcodybaker/go-shelly/pkg/mgrpcadapter.
 package shelly
-
-import (
-	_ "github.com/mongoose-os/mos/common/mgrpc"
-
-	_ "github.com/mongoose-os/mos/common/mgrpc/codec"
-	_ "github.com/mongoose-os/mos/common/mgrpc/frame"
-)
@@ -0,0 +1,369 @@
+package shelly
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// UnknownField is a response key which doesn't map to a field of the response type, and so is
+// dropped when decoding. These are typically undocumented fields or API drift in newer firmware.
+type UnknownField struct {
+	// Path is the JSON path of the key, ex. `sys.reset_reason` or `switch:0.aenergy.ts`.
+	Path string
+
+	// Value is the value of the key.
+	Value json.RawMessage
+}
+
+// UnknownFields returns the keys of data which don't map to a field of resp, a pointer to the
+// response type. Keys are matched as encoding/json does, including case-insensitively. Fields
+// of type json.RawMessage or interface{}, and types with custom decoding (other than Optional
+// and the component-keyed Shelly.GetStatus and Shelly.GetConfig responses) are not inspected.
+func UnknownFields(data []byte, resp any) ([]UnknownField, error) {
+	if !json.Valid(data) {
+		return nil, errors.New("response is not valid json")
+	}
+	var unknown []UnknownField
+	walkUnknownFields(reflect.TypeOf(resp), data, "", &unknown)
+	return unknown, nil
+}
+
+// DriftReport lists the unknown fields in a response from a device.
+type DriftReport struct {
+	// Method is the method called.
+	Method string
+
+	// Model is the device's model, ex. SPSW-104PE16EU.
+	Model string
+
+	// App is the device's application name, ex. Pro4PM.
+	App string
+
+	// FirmwareVersion is the device's firmware version, ex. 1.4.4.
+	FirmwareVersion string
+
+	// Fields are the unknown fields.
+	Fields []UnknownField
+}
+
+func (r *DriftReport) String() string {
+	paths := make([]string, 0, len(r.Fields))
+	for _, f := range r.Fields {
+		paths = append(paths, f.Path)
+	}
+	return fmt.Sprintf("%s (%s %s, firmware %s): unknown fields %s",
+		r.Method, r.App, r.Model, r.FirmwareVersion, strings.Join(paths, ", "))
+}
+
+func newDriftReport(method string, info *ShellyGetDeviceInfoResponse) *DriftReport {
+	r := &DriftReport{Method: method}
+	if info != nil {
+		r.Model, r.App, r.FirmwareVersion = info.Model, info.App, info.Ver
+	}
+	return r
+}
+
+// DefaultDriftRequests are the requests made by FindDrift if none are given. Between them they
+// cover every component supported by the library.
+func DefaultDriftRequests() []RPCRequestBody {
+	return []RPCRequestBody{
+		&ShellyGetDeviceInfoRequest{},
+		&ShellyGetStatusRequest{},
+		&ShellyGetConfigRequest{},
+	}
+}
+
+// FindDrift makes each of reqs, or DefaultDriftRequests, and reports the unknown fields in their
+// responses. Only responses with unknown fields are reported.
+func FindDrift(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+	reqs ...RPCRequestBody,
+) ([]*DriftReport, error) {
+	info, _, err := Call(ctx, c, credsCallback, &ShellyGetDeviceInfoRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		reqs = DefaultDriftRequests()
+	}
+	var reports []*DriftReport
+	for _, req := range reqs {
+		resp := req.NewResponse()
+		raw, err := Do(ctx, c, credsCallback, req, resp)
+		if err != nil {
+			return reports, fmt.Errorf("calling %s: %w", req.Method(), err)
+		}
+		unknown, err := UnknownFields(raw.Result, resp)
+		if err != nil {
+			return reports, fmt.Errorf("checking %s response: %w", req.Method(), err)
+		}
+		if len(unknown) > 0 {
+			r := newDriftReport(req.Method(), info)
+			r.Fields = unknown
+			reports = append(reports, r)
+		}
+	}
+	return reports, nil
+}
+
+// UnknownFieldsInterceptor calls report for each successful response with unknown fields. The
+// device's model and firmware are fetched with Shelly.GetDeviceInfo on the first report.
+func UnknownFieldsInterceptor(report func(*DriftReport)) Interceptor {
+	var mu sync.Mutex
+	var info *ShellyGetDeviceInfoResponse
+	deviceInfo := func(ctx context.Context, next Invoker) *ShellyGetDeviceInfoResponse {
+		mu.Lock()
+		defer mu.Unlock()
+		if info != nil {
+			return info
+		}
+		resp, err := next(ctx, &ShellyGetDeviceInfoRequest{})
+		if ResponseError(resp, err) != nil {
+			return nil
+		}
+		var i ShellyGetDeviceInfoResponse
+		if json.Unmarshal(resp.Result, &i) == nil {
+			info = &i
+		}
+		return info
+	}
+	return func(ctx context.Context, req RPCRequestBody, next Invoker) (*RPCFrame, error) {
+		resp, err := next(ctx, req)
+		if ResponseError(resp, err) != nil {
+			return resp, err
+		}
+		unknown, uerr := UnknownFields(resp.Result, req.NewResponse())
+		if uerr != nil || len(unknown) == 0 {
+			return resp, err
+		}
+		r := newDriftReport(req.Method(), deviceInfo(ctx, next))
+		r.Fields = unknown
+		report(r)
+		return resp, err
+	}
+}
+
+// fieldTyper is implemented by types with custom decoding of JSON objects, to describe the type
+// each key decodes into.
+type fieldTyper interface {
+	fieldType(key string) (reflect.Type, bool)
+}
+
+// optionalElem is implemented by Optional, to describe its value type.
+type optionalElem interface {
+	elemType() reflect.Type
+}
+
+func (o Optional[T]) elemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+var (
+	rawMessageType      = reflect.TypeOf(json.RawMessage{})
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	fieldTyperType      = reflect.TypeOf((*fieldTyper)(nil)).Elem()
+	optionalElemType    = reflect.TypeOf((*optionalElem)(nil)).Elem()
+)
+
+func walkUnknownFields(t reflect.Type, data json.RawMessage, path string, unknown *[]UnknownField) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == rawMessageType || t.Kind() == reflect.Interface || string(data) == "null" {
+		return
+	}
+	if t.Implements(optionalElemType) {
+		walkUnknownFields(reflect.Zero(t).Interface().(optionalElem).elemType(), data, path, unknown)
+		return
+	}
+	pt := reflect.PointerTo(t)
+	if pt.Implements(fieldTyperType) {
+		ft := reflect.New(t).Interface().(fieldTyper)
+		walkObject(data, path, unknown, ft.fieldType)
+		return
+	}
+	if pt.Implements(unmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := structFields(t)
+		walkObject(data, path, unknown, func(key string) (reflect.Type, bool) {
+			if ft, ok := fields[key]; ok {
+				return ft, true
+			}
+			for name, ft := range fields {
+				if strings.EqualFold(name, key) {
+					return ft, true
+				}
+			}
+			return nil, false
+		})
+	case reflect.Map:
+		walkObject(data, path, unknown, func(string) (reflect.Type, bool) {
+			return t.Elem(), true
+		})
+	case reflect.Slice, reflect.Array:
+		var elems []json.RawMessage
+		if json.Unmarshal(data, &elems) != nil {
+			return
+		}
+		for i, e := range elems {
+			walkUnknownFields(t.Elem(), e, fmt.Sprintf("%s[%d]", path, i), unknown)
+		}
+	}
+}
+
+// walkObject checks each key of the object in data against fieldType.
+func walkObject(
+	data json.RawMessage,
+	path string,
+	unknown *[]UnknownField,
+	fieldType func(key string) (reflect.Type, bool),
+) {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(data, &obj) != nil {
+		return
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		keyPath := k
+		if path != "" {
+			keyPath = path + "." + k
+		}
+		ft, ok := fieldType(k)
+		if !ok {
+			*unknown = append(*unknown, UnknownField{Path: keyPath, Value: obj[k]})
+			continue
+		}
+		walkUnknownFields(ft, obj[k], keyPath, unknown)
+	}
+}
+
+// structFields returns the types of the fields of t by JSON name, including promoted fields of
+// embedded structs.
+func structFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	// Fields of the outer struct take precedence.
+	for _, et := range embedded {
+		for name, ft := range structFields(et) {
+			if _, ok := fields[name]; !ok {
+				fields[name] = ft
+			}
+		}
+	}
+	return fields
+}
+
+// componentFieldType maps the keys of a component-keyed object, ex. `sys` or `switch:0`, to
+// their types. As when decoding, only instances 0 through 3 are read.
+func componentFieldType(key string, singletons, instances map[string]reflect.Type) (reflect.Type, bool) {
+	if t, ok := singletons[key]; ok {
+		return t, true
+	}
+	typ, id, ok := strings.Cut(key, ":")
+	if !ok {
+		return nil, false
+	}
+	t, ok := instances[typ]
+	n, err := strconv.Atoi(id)
+	if !ok || err != nil || n < 0 || n >= 4 {
+		return nil, false
+	}
+	return t, true
+}
+
+var (
+	statusSingletons = map[string]reflect.Type{
+		"ble":   reflect.TypeOf(BLEStatus{}),
+		"sys":   reflect.TypeOf(SysStatus{}),
+		"cloud": reflect.TypeOf(CloudStatus{}),
+		"mqtt":  reflect.TypeOf(MQTTStatus{}),
+		"wifi":  reflect.TypeOf(WifiStatus{}),
+		"eth":   reflect.TypeOf(EthStatus{}),
+	}
+	statusInstances = map[string]reflect.Type{
+		"switch":      reflect.TypeOf(SwitchStatus{}),
+		"cover":       reflect.TypeOf(CoverStatus{}),
+		"input":       reflect.TypeOf(InputStatus{}),
+		"light":       reflect.TypeOf(LightStatus{}),
+		"devicepower": reflect.TypeOf(DevicePowerStatus{}),
+		"humidity":    reflect.TypeOf(HumidityStatus{}),
+		"temperature": reflect.TypeOf(TemperatureStatus{}),
+	}
+	configSingletons = map[string]reflect.Type{
+		"ble":   reflect.TypeOf(BLEConfig{}),
+		"sys":   reflect.TypeOf(SysConfig{}),
+		"cloud": reflect.TypeOf(CloudConfig{}),
+		"mqtt":  reflect.TypeOf(MQTTConfig{}),
+		"wifi":  reflect.TypeOf(WifiConfig{}),
+		"eth":   reflect.TypeOf(EthConfig{}),
+	}
+	configInstances = map[string]reflect.Type{
+		"switch":      reflect.TypeOf(SwitchConfig{}),
+		"cover":       reflect.TypeOf(CoverConfig{}),
+		"input":       reflect.TypeOf(InputConfig{}),
+		"light":       reflect.TypeOf(LightConfig{}),
+		"humidity":    reflect.TypeOf(HumidityConfig{}),
+		"temperature": reflect.TypeOf(TemperatureConfig{}),
+	}
+)
+
+func (r *ShellyGetStatusResponse) fieldType(key string) (reflect.Type, bool) {
+	return componentFieldType(key, statusSingletons, statusInstances)
+}
+
+func (r *ShellyGetConfigResponse) fieldType(key string) (reflect.Type, bool) {
+	return componentFieldType(key, configSingletons, configInstances)
+}
+
+func (r *NotifyStatus) fieldType(key string) (reflect.Type, bool) {
+	if key == "ts" {
+		return reflect.TypeOf(float64(0)), true
+	}
+	return r.ShellyGetStatusResponse.fieldType(key)
+}
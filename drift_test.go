@@ -0,0 +1,86 @@
+package shelly_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/jcodybaker/go-shelly/pkg/shellytest"
+)
+
+func unknownPaths(fields []shelly.UnknownField) []string {
+	var paths []string
+	for _, f := range fields {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func TestUnknownFields(t *testing.T) {
+	status := `{
+		"sys": {"mac": "F008D1D8B8B8", "uptime": 12, "btrelay_rev": 0},
+		"switch:0": {"id": 0, "output": true, "aenergy": {"total": 1.5, "by_minute": [0, 0, 0], "minute_ts": 1, "peak": 9}},
+		"switch:7": {"id": 7},
+		"ws": {"connected": false}
+	}`
+	unknown, err := shelly.UnknownFields([]byte(status), &shelly.ShellyGetStatusResponse{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"switch:0.aenergy.peak", "switch:7", "sys.btrelay_rev", "ws"}, unknownPaths(unknown))
+	assert.JSONEq(t, `{"connected": false}`, string(unknown[3].Value))
+
+	// Optional values and slices are inspected, and keys match case-insensitively.
+	config := `{
+		"id": 0,
+		"NAME": null,
+		"button_presets": {"button_doublepush": {"brightness": 50, "transition": 1}},
+		"night_mode": {"enable": true, "active_between": ["20:00", "6:00"]}
+	}`
+	unknown, err = shelly.UnknownFields([]byte(config), &shelly.LightConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"button_presets.button_doublepush.transition"}, unknownPaths(unknown))
+
+	unknown, err = shelly.UnknownFields([]byte(`[{"id":1,"name":"a","enable":true,"running":false,"mem":1}]`), &[]*shelly.ScriptConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"[0].mem", "[0].running"}, unknownPaths(unknown))
+
+	_, err = shelly.UnknownFields([]byte(`{`), &shelly.SysStatus{})
+	assert.Error(t, err)
+}
+
+func TestFindDrift(t *testing.T) {
+	ctx := context.Background()
+	fake := shellytest.New(t)
+	info := json.RawMessage(`{"id":"shellypro4pm-f008d1d8b8b8","model":"SPSW-104PE16EU","gen":2,"ver":"1.4.4","app":"Pro4PM","matter":false}`)
+	fake.Expect("Shelly.GetDeviceInfo").Return(info).Times(3)
+	fake.Expect("Sys.GetStatus").Return(json.RawMessage(`{"uptime":1,"utc_offset":3600}`)).Times(2)
+	fake.Expect("Switch.GetStatus").Return(json.RawMessage(`{"id":0,"output":false}`))
+
+	reports, err := shelly.FindDrift(ctx, fake, nil,
+		&shelly.ShellyGetDeviceInfoRequest{}, &shelly.SysGetStatusRequest{}, &shelly.SwitchGetStatusRequest{})
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Equal(t, "Shelly.GetDeviceInfo", reports[0].Method)
+	assert.Equal(t, []string{"matter"}, unknownPaths(reports[0].Fields))
+	assert.Equal(t, &shelly.DriftReport{
+		Method:          "Sys.GetStatus",
+		Model:           "SPSW-104PE16EU",
+		App:             "Pro4PM",
+		FirmwareVersion: "1.4.4",
+		Fields:          []shelly.UnknownField{{Path: "utc_offset", Value: json.RawMessage(`3600`)}},
+	}, reports[1])
+	assert.Equal(t, "Sys.GetStatus (Pro4PM SPSW-104PE16EU, firmware 1.4.4): unknown fields utc_offset", reports[1].String())
+
+	// The interceptor reports as calls are made.
+	var intercepted []*shelly.DriftReport
+	c := shelly.NewInterceptedClient(fake, shelly.UnknownFieldsInterceptor(func(r *shelly.DriftReport) {
+		intercepted = append(intercepted, r)
+	}))
+	_, _, err = (&shelly.SysGetStatusRequest{}).Do(ctx, c, nil)
+	require.NoError(t, err)
+	require.Len(t, intercepted, 1)
+	assert.Equal(t, reports[1], intercepted[0])
+}
@@ -1,6 +1,12 @@
 package shelly
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+)
 
 // https://shelly-api-docs.shelly.cloud/gen2/ComponentsAndServices/HTTP
 // https://shelly-api-docs.shelly.cloud/gen2/General/CommonErrors
@@ -74,6 +80,10 @@ var (
 	// ErrRPCHTTPErrorResponse ...
 	ErrRPCHTTPErrorResponse = ShellyErrorCode(-18)
 
+	// ErrRPCUnauthorized is returned when authentication is enabled on the device and the request
+	// did not include valid credentials. The message contains the digest challenge.
+	ErrRPCUnauthorized = ShellyErrorCode(401)
+
 	// ErrRPCNoHandler is returned when a call is made to an unknown handler.
 	// NOTE: This is not documented, but was seen.
 	ErrRPCNoHandler = ShellyErrorCode(404)
@@ -131,6 +141,8 @@ func (err ShellyErrorCode) Error() string {
 	case -18:
 		// -18: HTTP Error Response
 		msg = "http error response"
+	case 401:
+		msg = "unauthorized"
 	case 404:
 		msg = "no handler for request"
 	default:
@@ -138,3 +150,37 @@ func (err ShellyErrorCode) Error() string {
 	}
 	return fmt.Sprintf("rpc error: %s (%d)", msg, err)
 }
+
+// IsRetryable returns true if err describes a transient condition, such that repeating an
+// idempotent request may succeed: the device reporting a deadline, unavailable, resources
+// exhausted, or closed connection error, or a network timeout or dropped connection. Errors
+// which won't change on retry, such as ErrRPCInvalidOrMissingArguments or
+// ErrRPCFailedPrecondition, return false.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, target := range []error{
+		ErrRPCDeadlineExceeded,
+		ErrRPCUnavailable,
+		ErrRPCResourcesExhausted,
+		ErrRPCConnectionClosedPrematurely,
+		ErrRPCSendingDataToRemotePeerFailed,
+		ErrConnectionLost,
+		ErrUDPTimeout,
+		io.ErrUnexpectedEOF,
+		syscall.ECONNRESET,
+		syscall.ECONNREFUSED,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsAuthError returns true if err indicates the request lacked valid credentials.
+func IsAuthError(err error) bool {
+	return errors.Is(err, ErrRPCUnauthorized)
+}
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type EthStatus struct {
@@ -18,6 +15,10 @@ func (r *EthGetStatusRequest) Method() string {
 	return "Eth.GetStatus"
 }
 
+func (r *EthGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *EthGetStatusRequest) NewTypedResponse() *EthStatus {
 	return &EthStatus{}
 }
@@ -28,16 +29,14 @@ func (r *EthGetStatusRequest) NewResponse() any {
 
 func (r *EthGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*EthStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type EthConfig struct {
@@ -48,16 +47,16 @@ type EthConfig struct {
 	IPv4Mode *string `json:"ipv4mode,omitempty"`
 
 	// IP to use when ipv4mode is static.
-	IP *string `json:"ip,omitempty"`
+	IP Optional[string] `json:"ip,omitempty"`
 
 	// Netmask to use when ipv4mode is static
-	Netmask *string `json:"netmask,omitempty"`
+	Netmask Optional[string] `json:"netmask,omitempty"`
 
 	// GW is the gateway to use when ipv4mode is static
-	GW *string `json:"gw,omitempty"`
+	GW Optional[string] `json:"gw,omitempty"`
 
 	// Nameserver to use when ipv4mode is static
-	Nameserver *string `json:"nameserver,omitempty"`
+	Nameserver Optional[string] `json:"nameserver,omitempty"`
 }
 
 type EthGetConfigRequest struct{}
@@ -66,6 +65,10 @@ func (r *EthGetConfigRequest) Method() string {
 	return "Eth.GetConfig"
 }
 
+func (r *EthGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *EthGetConfigRequest) NewTypedResponse() *EthConfig {
 	return &EthConfig{}
 }
@@ -76,16 +79,14 @@ func (r *EthGetConfigRequest) NewResponse() any {
 
 func (r *EthGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*EthConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type EthSetConfigRequest struct {
@@ -96,6 +97,10 @@ func (r *EthSetConfigRequest) Method() string {
 	return "Eth.SetConfig"
 }
 
+func (r *EthSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *EthSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -106,14 +111,12 @@ func (r *EthSetConfigRequest) NewResponse() any {
 
 func (r *EthSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
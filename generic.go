@@ -0,0 +1,168 @@
+package shelly
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// GenericDevice is implemented by clients of any device generation, so tools such as
+// inventory, metrics and switching can treat Gen1 and Gen2 devices uniformly. Gen2Device
+// implements it over an RPC channel, and gen1.Client over the Gen1 REST API.
+type GenericDevice interface {
+	// Summary identifies the device.
+	Summary(ctx context.Context) (*DeviceSummary, error)
+
+	// Outputs returns the state of the device's relays (Gen1) or switches (Gen2).
+	Outputs(ctx context.Context) ([]*OutputStatus, error)
+
+	// SetOutput turns the relay or switch with the given id on or off.
+	SetOutput(ctx context.Context, id int, on bool) error
+
+	// Meters returns the device's power meter readings.
+	Meters(ctx context.Context) ([]*MeterReading, error)
+}
+
+// DeviceSummary identifies a device of any generation.
+type DeviceSummary struct {
+	// Gen is the device generation.
+	Gen int
+
+	// ID of the device, ex. shellypro4pm-f008d1d8b8b8 or shelly1-34945472a3b4.
+	ID string
+
+	// MAC of the device.
+	MAC string
+
+	// Model of the device, ex. SPSW-104PE16EU or SHSW-1.
+	Model string
+
+	// App is the Gen2 application name, ex. Pro4PM. Gen1 devices don't report one.
+	App string
+
+	// FirmwareVersion is the version of the device firmware.
+	FirmwareVersion string
+
+	// AuthEnabled is true if authentication is enabled.
+	AuthEnabled bool
+}
+
+// OutputStatus is the state of a relay or switch.
+type OutputStatus struct {
+	// ID of the relay or switch.
+	ID int
+
+	// On is true if the output is on.
+	On bool
+
+	// Source of the last change, if reported, ex. `http` or `timer`.
+	Source string
+}
+
+// MeterReading is a power meter reading.
+type MeterReading struct {
+	// Component names the meter, ex. `switch:0` (Gen2), `meter:0` or `emeter:1` (Gen1).
+	Component string
+
+	// Power is the active power in Watts.
+	Power float64
+
+	// Voltage in Volts, if measured.
+	Voltage *float64
+
+	// Current in Amperes, if measured.
+	Current *float64
+
+	// PF is the power factor, if measured.
+	PF *float64
+
+	// TotalEnergy is the total energy consumed in Watt-hours, if measured.
+	TotalEnergy *float64
+}
+
+// Gen2Device implements GenericDevice for Gen2 devices.
+type Gen2Device struct {
+	c     Caller
+	creds GetCredsCallback
+}
+
+var _ GenericDevice = (*Gen2Device)(nil)
+
+// NewGen2Device returns a GenericDevice which makes calls over c.
+func NewGen2Device(c Caller, creds GetCredsCallback) *Gen2Device {
+	return &Gen2Device{c: c, creds: creds}
+}
+
+// Summary implements GenericDevice.
+func (d *Gen2Device) Summary(ctx context.Context) (*DeviceSummary, error) {
+	info, _, err := (&ShellyGetDeviceInfoRequest{}).Do(ctx, d.c, d.creds)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := strconv.Atoi(info.Gen.String())
+	if err != nil {
+		return nil, fmt.Errorf("parsing device generation %q: %w", info.Gen, err)
+	}
+	return &DeviceSummary{
+		Gen:             gen,
+		ID:              info.ID,
+		MAC:             info.MAC,
+		Model:           info.Model,
+		App:             info.App,
+		FirmwareVersion: info.Ver,
+		AuthEnabled:     info.AuthEn,
+	}, nil
+}
+
+// Outputs implements GenericDevice.
+func (d *Gen2Device) Outputs(ctx context.Context) ([]*OutputStatus, error) {
+	status, _, err := (&ShellyGetStatusRequest{}).Do(ctx, d.c, d.creds)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]*OutputStatus, 0, len(status.Switches))
+	for _, sw := range status.Switches {
+		o := &OutputStatus{ID: sw.ID}
+		if sw.Output != nil {
+			o.On = *sw.Output
+		}
+		if sw.Source != nil {
+			o.Source = *sw.Source
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, nil
+}
+
+// SetOutput implements GenericDevice.
+func (d *Gen2Device) SetOutput(ctx context.Context, id int, on bool) error {
+	_, _, err := (&SwitchSetRequest{ID: id, On: on}).Do(ctx, d.c, d.creds)
+	return err
+}
+
+// Meters implements GenericDevice. Readings are reported for switches with power metering.
+func (d *Gen2Device) Meters(ctx context.Context) ([]*MeterReading, error) {
+	status, _, err := (&ShellyGetStatusRequest{}).Do(ctx, d.c, d.creds)
+	if err != nil {
+		return nil, err
+	}
+	var readings []*MeterReading
+	for _, sw := range status.Switches {
+		if sw.APower == nil {
+			continue
+		}
+		r := &MeterReading{
+			Component: fmt.Sprintf("switch:%d", sw.ID),
+			Power:     *sw.APower,
+			Voltage:   sw.Voltage,
+			Current:   sw.Current,
+			PF:        sw.PF,
+		}
+		if sw.AEnergy != nil {
+			total := sw.AEnergy.Total
+			r.TotalEnergy = &total
+		}
+		readings = append(readings, r)
+	}
+	return readings, nil
+}
@@ -0,0 +1,52 @@
+package shelly_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/jcodybaker/go-shelly/pkg/shellytest"
+)
+
+func TestGen2Device(t *testing.T) {
+	ctx := context.Background()
+	f := shellytest.New(t)
+	f.Expect("Shelly.GetDeviceInfo").Return(map[string]any{
+		"id": "shellypro4pm-f008d1d8b8b8", "mac": "F008D1D8B8B8", "model": "SPSW-104PE16EU",
+		"gen": 2, "ver": "1.4.4", "app": "Pro4PM", "auth_en": false,
+	})
+	f.Expect("Shelly.GetStatus").Times(2).Return(map[string]any{
+		"switch:0": map[string]any{
+			"id": 0, "source": "init", "output": true, "apower": 12.5, "voltage": 120.1,
+			"aenergy": map[string]any{"total": 1500.25},
+		},
+		"switch:1": map[string]any{"id": 1, "source": "WS_in", "output": false},
+	})
+	f.ExpectRequest(&shelly.SwitchSetRequest{ID: 1, On: true}).Return(map[string]any{"was_on": false})
+
+	var d shelly.GenericDevice = shelly.NewGen2Device(f, nil)
+	summary, err := d.Summary(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &shelly.DeviceSummary{
+		Gen: 2, ID: "shellypro4pm-f008d1d8b8b8", MAC: "F008D1D8B8B8", Model: "SPSW-104PE16EU",
+		App: "Pro4PM", FirmwareVersion: "1.4.4",
+	}, summary)
+
+	outputs, err := d.Outputs(ctx)
+	require.NoError(t, err)
+	require.Len(t, outputs, 2)
+	assert.True(t, outputs[0].On)
+	assert.Equal(t, "WS_in", outputs[1].Source)
+
+	meters, err := d.Meters(ctx)
+	require.NoError(t, err)
+	require.Len(t, meters, 1)
+	assert.Equal(t, "switch:0", meters[0].Component)
+	assert.Equal(t, 12.5, meters[0].Power)
+	assert.Equal(t, 1500.25, *meters[0].TotalEnergy)
+
+	require.NoError(t, d.SetOutput(ctx, 1, true))
+}
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 // HumidityGetConfigRequest contains parameters for the Humidity.GetConfig RPC request.
@@ -17,6 +14,10 @@ func (r *HumidityGetConfigRequest) Method() string {
 	return "Humidity.GetConfig"
 }
 
+func (r *HumidityGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *HumidityGetConfigRequest) NewTypedResponse() *HumidityConfig {
 	return &HumidityConfig{}
 }
@@ -27,16 +28,14 @@ func (r *HumidityGetConfigRequest) NewResponse() any {
 
 func (r *HumidityGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*HumidityConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // HumiditySetConfigRequest contains parameters for the Humidity.SetConfig RPC request.
@@ -52,6 +51,10 @@ func (r *HumiditySetConfigRequest) Method() string {
 	return "Humidity.SetConfig"
 }
 
+func (r *HumiditySetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *HumiditySetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -62,16 +65,14 @@ func (r *HumiditySetConfigRequest) NewResponse() any {
 
 func (r *HumiditySetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // HumidityGetStatusRequst contains parameters for the Humidity.GetStatus RPC request.
@@ -84,6 +85,10 @@ func (r *HumidityGetStatusRequest) Method() string {
 	return "Humidity.GetStatus"
 }
 
+func (r *HumidityGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *HumidityGetStatusRequest) NewTypedResponse() *HumidityStatus {
 	return &HumidityStatus{}
 }
@@ -94,16 +99,14 @@ func (r *HumidityGetStatusRequest) NewResponse() any {
 
 func (r *HumidityGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*HumidityStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // HumidityConfig provides configuration for humidity component instances.
@@ -112,7 +115,7 @@ type HumidityConfig struct {
 	ID int `json:"id"`
 
 	// Name of the humidity instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// ReportTHR is the humidity report threshold in %. Accepted range is device-specific,
 	// default [1.0..20.0]% unless specified otherwise.
@@ -4,9 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type InputGetStatusRequest struct {
@@ -18,6 +15,10 @@ func (r *InputGetStatusRequest) Method() string {
 	return "Input.GetStatus"
 }
 
+func (r *InputGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *InputGetStatusRequest) NewTypedResponse() *InputStatus {
 	return &InputStatus{}
 }
@@ -28,16 +29,14 @@ func (r *InputGetStatusRequest) NewResponse() any {
 
 func (r *InputGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*InputStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type InputStatus struct {
@@ -71,6 +70,10 @@ func (r *InputGetConfigRequest) Method() string {
 	return "Input.GetConfig"
 }
 
+func (r *InputGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *InputGetConfigRequest) NewTypedResponse() *InputConfig {
 	return &InputConfig{}
 }
@@ -81,16 +84,14 @@ func (r *InputGetConfigRequest) NewResponse() any {
 
 func (r *InputGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*InputConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type InputConfig struct {
@@ -98,7 +99,7 @@ type InputConfig struct {
 	ID int `json:"id"`
 
 	// Name of the switch instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// Type of associated input. Range of values switch, button, analog (only if applicable).
 	Type *string `json:"type,omitempty"`
@@ -135,12 +136,12 @@ type InputXPercent struct {
 	// Expr is a JS expression containing x, where x is the raw value to be transformed
 	// (status.percent), for example "x+1". Accepted range: null or [0..100] chars. Both
 	// null and "" mean value transformation is disabled.
-	Expr *string `json:"expr,omitempty"`
+	Expr Optional[string] `json:"expr,omitempty"`
 
 	// Unit of the transformed value (status.xpercent), for example, "m/s".
 	// Accepted range: null or [0..20] chars. Both null and "" mean value transformation
 	// is disabled.
-	Unit *string `json:"unit,omitempty"`
+	Unit Optional[string] `json:"unit,omitempty"`
 }
 
 type InputSetConfigRequest struct {
@@ -155,6 +156,10 @@ func (r *InputSetConfigRequest) Method() string {
 	return "Input.SetConfig"
 }
 
+func (r *InputSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *InputSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -165,16 +170,26 @@ func (r *InputSetConfigRequest) NewResponse() any {
 
 func (r *InputSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *InputSetConfigRequest) Validate() error {
+	v := newValidation(r)
+	if r.Config.RangeMap != nil {
+		v.rangeMap("config.range_map", r.Config.RangeMap, true)
+	}
+	if x := r.Config.XPercent; x != nil {
+		v.maxLen("config.xpercent.expr", x.Expr, 100)
+		v.maxLen("config.xpercent.unit", x.Unit, 20)
+	}
+	return v.err()
 }
 
 type InputCheckExpressionRequest struct {
@@ -189,6 +204,10 @@ func (r *InputCheckExpressionRequest) Method() string {
 	return "Input.CheckExpression"
 }
 
+func (r *InputCheckExpressionRequest) Idempotent() bool {
+	return true
+}
+
 func (r *InputCheckExpressionRequest) NewTypedResponse() *InputCheckExpressionResponse {
 	return &InputCheckExpressionResponse{}
 }
@@ -199,16 +218,14 @@ func (r *InputCheckExpressionRequest) NewResponse() any {
 
 func (r *InputCheckExpressionRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*InputCheckExpressionResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type InputCheckExpressionResponse struct {
@@ -259,3 +276,19 @@ func (r *InputCheckExpressionResult) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// inputEvents are the events emitted by input components in button mode.
+var inputEvents = map[string]func() EventData{
+	"btn_down":    func() EventData { return &InputButtonEvent{} },
+	"btn_up":      func() EventData { return &InputButtonEvent{} },
+	"single_push": func() EventData { return &InputButtonEvent{} },
+	"double_push": func() EventData { return &InputButtonEvent{} },
+	"triple_push": func() EventData { return &InputButtonEvent{} },
+	"long_push":   func() EventData { return &InputButtonEvent{} },
+}
+
+// InputButtonEvent is the payload of button events (btn_down, btn_up, single_push, double_push,
+// triple_push, and long_push). The event name identifies the action; there are no other fields.
+type InputButtonEvent struct{}
+
+func (*InputButtonEvent) eventData() {}
@@ -4,29 +4,68 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	shelly "github.com/jcodybaker/go-shelly"
-	"github.com/mongoose-os/mos/common/mgrpc"
+	"github.com/jcodybaker/go-shelly/pkg/replay"
 )
 
+// dialTestDevice returns a channel to the test device. Calls are answered from the golden file
+// testdata/<test name>.json when it exists. Otherwise they're made to the device at the address
+// in SHELLY_TEST_DEVICE (ex. 192.168.1.23), and the test is skipped if it's unset. With
+// SHELLY_RECORD set, calls are always made to the device and recorded to the golden file.
+func dialTestDevice(t *testing.T) testChannel {
+	golden := filepath.Join("testdata", filepath.FromSlash(t.Name())+".json")
+	record := os.Getenv("SHELLY_RECORD") != ""
+	if _, err := os.Stat(golden); err == nil && !record {
+		p, err := replay.Load(golden)
+		require.NoError(t, err)
+		return p
+	}
+	addr := os.Getenv("SHELLY_TEST_DEVICE")
+	if addr == "" {
+		t.Skipf("no golden file %s; set SHELLY_TEST_DEVICE to run against a device", golden)
+	}
+	c := shelly.NewHTTPClient("http://" + addr + "/rpc")
+	if record {
+		require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+		return replay.NewRecorder(c, golden)
+	}
+	return c
+}
+
+// testChannel is the caller returned by dialTestDevice.
+type testChannel interface {
+	shelly.Caller
+	Disconnect(ctx context.Context) error
+}
+
 func GetCallWithVerify(t *testing.T, req shelly.RPCRequestBody, respBody interface{}) {
 	ctx := context.Background()
-	c, err := mgrpc.New(ctx, "http://192.168.1.23/rpc", mgrpc.UseHTTPPost())
-	require.NoError(t, err)
-	defer c.Disconnect(ctx)
+	c := dialTestDevice(t)
+	defer func() {
+		// Disconnect saves any recording.
+		assert.NoError(t, c.Disconnect(ctx))
+	}()
 
 	respFrame, err := shelly.Do(ctx, c, nil, req, respBody)
 	require.NoError(t, err)
-	fmt.Println(string(respFrame.Response))
+	fmt.Println(string(respFrame.Result))
+	unknown, err := shelly.UnknownFields(respFrame.Result, respBody)
+	require.NoError(t, err)
+	for _, f := range unknown {
+		t.Logf("unknown field %s: %s", f.Path, f.Value)
+	}
 
 	// The reencoded JSON *SHOULD* match.
 	// NOTE: in practice there seem to be some undocumented fields and inconsistency in what
 	// is NULL and what is omited when NULL.
 	jsonOut, err := json.Marshal(respBody)
 	require.NoError(t, err)
-	assert.JSONEq(t, string(respFrame.Response), string(jsonOut))
+	assert.JSONEq(t, string(respFrame.Result), string(jsonOut))
 }
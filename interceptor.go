@@ -0,0 +1,115 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// Invoker makes a call, either by invoking the next interceptor or the underlying channel.
+type Invoker func(ctx context.Context, req RPCRequestBody) (*RPCFrame, error)
+
+// Interceptor wraps calls made through an InterceptedClient. It may inspect or replace the
+// request, call next any number of times, and inspect or replace the response.
+type Interceptor func(ctx context.Context, req RPCRequestBody, next Invoker) (*RPCFrame, error)
+
+// InterceptedClient implements Caller, passing each call through a chain of interceptors. The
+// first interceptor is outermost.
+type InterceptedClient struct {
+	c            Caller
+	interceptors []Interceptor
+}
+
+// NewInterceptedClient wraps c with the interceptors.
+func NewInterceptedClient(c Caller, interceptors ...Interceptor) *InterceptedClient {
+	return &InterceptedClient{c: c, interceptors: interceptors}
+}
+
+// Call implements Caller. Interceptors see the frame as a *RawRequest.
+func (ic *InterceptedClient) Call(ctx context.Context, f *RPCFrame, getCreds GetCredsCallback) (*RPCFrame, error) {
+	return ic.CallRequest(ctx, &RawRequest{Cmd: f.Method, Args: f.Params}, f, getCreds)
+}
+
+// CallRequest implements RequestCaller.
+func (ic *InterceptedClient) CallRequest(
+	ctx context.Context,
+	req RPCRequestBody,
+	f *RPCFrame,
+	getCreds GetCredsCallback,
+) (*RPCFrame, error) {
+	orig := req
+	next := func(ctx context.Context, req RPCRequestBody) (*RPCFrame, error) {
+		reqFrame := f
+		if !reflect.TypeOf(req).Comparable() || req != orig {
+			// An interceptor may have replaced the request; encode the replacement.
+			var err error
+			if reqFrame, err = NewRequestFrame(req); err != nil {
+				return nil, err
+			}
+			reqFrame.Dst = f.Dst
+		}
+		return callRequest(ctx, ic.c, req, reqFrame, getCreds)
+	}
+	for i := len(ic.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := ic.interceptors[i], next
+		next = func(ctx context.Context, req RPCRequestBody) (*RPCFrame, error) {
+			return interceptor(ctx, req, inner)
+		}
+	}
+	return next(ctx, req)
+}
+
+// Subscribe implements Subscriber, returning the notifications received by the wrapped caller.
+func (ic *InterceptedClient) Subscribe(ctx context.Context, method string) <-chan *RPCFrame {
+	return subscribe(ctx, ic.c, method)
+}
+
+// RawRequest is an RPCRequestBody carrying pre-encoded params. It represents calls made
+// without a typed request.
+type RawRequest struct {
+	// Cmd is the method name.
+	Cmd string
+
+	// Args are the JSON encoded params.
+	Args json.RawMessage
+}
+
+func (r *RawRequest) Method() string {
+	return r.Cmd
+}
+
+func (r *RawRequest) Idempotent() bool {
+	return false
+}
+
+func (r *RawRequest) NewResponse() any {
+	return &json.RawMessage{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *RawRequest) MarshalJSON() ([]byte, error) {
+	if len(r.Args) == 0 {
+		return []byte("null"), nil
+	}
+	return r.Args, nil
+}
+
+// ResponseError returns err, or the device's error if the response frame has one.
+func ResponseError(resp *RPCFrame, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp != nil && resp.Error != nil {
+		return &BadStatusWithMessageError{Status: ShellyErrorCode(resp.Error.Code), Msg: resp.Error.Message}
+	}
+	return nil
+}
+
+// Interceptor adapts the policy for use in an interceptor chain.
+func (p RetryPolicy) Interceptor() Interceptor {
+	return func(ctx context.Context, req RPCRequestBody, next Invoker) (*RPCFrame, error) {
+		return p.do(ctx, req, func() (*RPCFrame, error) {
+			return next(ctx, req)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedactedValue replaces the values of sensitive params in logs.
+const RedactedValue = "REDACTED"
+
+// redactedKeys are the param names whose values are credentials.
+var redactedKeys = map[string]bool{
+	"pass":         true,
+	"password":     true,
+	"ha1":          true,
+	"auth":         true,
+	"token":        true,
+	"access_token": true,
+	"secret":       true,
+	"psk":          true,
+}
+
+// redactedMethodKeys are, by method, the param and result names whose values are secret in
+// that method only, ex. the private key uploaded with Shelly.PutTLSClientKey or the encryption
+// key of a BTHome device. Elsewhere they're ordinary fields, ex. KVS keys. A name may be scoped
+// to the field containing it, ex. "config.key" for the key within each component's config but
+// not the component keys of Shelly.GetComponents.
+var redactedMethodKeys = map[string]map[string]bool{
+	"Shelly.PutTLSClientKey": {"data": true},
+	"Shelly.PutUserCA":       {"data": true},
+	"Shelly.GetConfig":       {"key": true},
+	"Shelly.GetComponents":   {"config.key": true},
+	"BTHome.AddDevice":       {"key": true},
+	"BTHomeDevice.GetConfig": {"key": true},
+	"BTHomeDevice.SetConfig": {"key": true},
+}
+
+// RedactParams returns a copy of the JSON encoded params with credentials (ex. wifi passwords
+// and Shelly.SetAuth's ha1) replaced by RedactedValue, at any depth. Params which are not valid
+// JSON are returned as-is.
+func RedactParams(params json.RawMessage) json.RawMessage {
+	return RedactMethodParams("", params)
+}
+
+// RedactMethodParams is RedactParams for the params or result of a call to method. It also
+// redacts the fields which are only secret in that method, ex. the data of
+// Shelly.PutTLSClientKey and the key of BTHome.AddDevice.
+func RedactMethodParams(method string, params json.RawMessage) json.RawMessage {
+	if len(params) == 0 {
+		return params
+	}
+	var v any
+	if err := json.Unmarshal(params, &v); err != nil {
+		return params
+	}
+	b, err := json.Marshal(redact(v, "", redactedMethodKeys[method]))
+	if err != nil {
+		return params
+	}
+	return b
+}
+
+// redact redacts v, the value of the field named parent, in place.
+func redact(v any, parent string, methodKeys map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			lk := strings.ToLower(k)
+			if (redactedKeys[lk] || methodKeys[lk] || methodKeys[parent+"."+lk]) && e != nil {
+				v[k] = RedactedValue
+				continue
+			}
+			v[k] = redact(e, lk, methodKeys)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = redact(e, parent, methodKeys)
+		}
+	}
+	return v
+}
+
+// LoggingInterceptor logs each call to logger: the method and redacted params at debug level,
+// and the outcome and duration at debug level on success or warn level on failure.
+func LoggingInterceptor(logger *slog.Logger) Interceptor {
+	return func(ctx context.Context, req RPCRequestBody, next Invoker) (*RPCFrame, error) {
+		var params json.RawMessage
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			if b, err := json.Marshal(req); err == nil {
+				params = RedactMethodParams(req.Method(), b)
+			}
+			logger.DebugContext(ctx, "shelly rpc call", "method", req.Method(), "params", params)
+		}
+		start := time.Now()
+		resp, err := next(ctx, req)
+		elapsed := time.Since(start)
+		if callErr := ResponseError(resp, err); callErr != nil {
+			logger.WarnContext(ctx, "shelly rpc call failed",
+				"method", req.Method(), "duration", elapsed, "error", callErr)
+		} else {
+			logger.DebugContext(ctx, "shelly rpc call succeeded", "method", req.Method(), "duration", elapsed)
+		}
+		return resp, err
+	}
+}
+
+// MetricsRecorder receives the outcome of each call. Implementations adapt to a metrics system
+// (ex. Prometheus histograms and counters) and must be safe for concurrent use.
+type MetricsRecorder interface {
+	// ObserveCall is called once per call with its latency and error, if any. Device errors are
+	// reported as *BadStatusWithMessageError.
+	ObserveCall(method string, latency time.Duration, err error)
+}
+
+// MetricsInterceptor reports every call to r.
+func MetricsInterceptor(r MetricsRecorder) Interceptor {
+	return func(ctx context.Context, req RPCRequestBody, next Invoker) (*RPCFrame, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		r.ObserveCall(req.Method(), time.Since(start), ResponseError(resp, err))
+		return resp, err
+	}
+}
+
+// MethodStats are the counters kept for a method by CallStats.
+type MethodStats struct {
+	// Calls is the number of calls made.
+	Calls int64
+
+	// Errors is the number of calls which failed.
+	Errors int64
+
+	// TotalLatency is the sum of the latency of all calls.
+	TotalLatency time.Duration
+
+	// MaxLatency is the latency of the slowest call.
+	MaxLatency time.Duration
+}
+
+// CallStats is an in-memory MetricsRecorder keeping per-method counters.
+type CallStats struct {
+	mu      sync.Mutex
+	methods map[string]MethodStats
+}
+
+// NewCallStats builds an empty CallStats.
+func NewCallStats() *CallStats {
+	return &CallStats{methods: make(map[string]MethodStats)}
+}
+
+// ObserveCall implements MetricsRecorder.
+func (s *CallStats) ObserveCall(method string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.methods[method]
+	m.Calls++
+	if err != nil {
+		m.Errors++
+	}
+	m.TotalLatency += latency
+	if latency > m.MaxLatency {
+		m.MaxLatency = latency
+	}
+	s.methods[method] = m
+}
+
+// Snapshot returns a copy of the counters, keyed by method.
+func (s *CallStats) Snapshot() map[string]MethodStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]MethodStats, len(s.methods))
+	for k, v := range s.methods {
+		out[k] = v
+	}
+	return out
+}
+
+// CapturedCall is a request and response recorded by CaptureInterceptor.
+type CapturedCall struct {
+	// Method is the name of the method called.
+	Method string
+
+	// Params are the JSON encoded params, unredacted.
+	Params json.RawMessage
+
+	// Response is the raw response, if one was received.
+	Response *RPCFrame
+
+	// Err is the error returned by the channel, if any. Device errors are in Response.
+	Err error
+
+	// Latency is the duration of the call.
+	Latency time.Duration
+}
+
+// CaptureInterceptor passes every completed call to capture. Captured params include
+// credentials; use RedactMethodParams before persisting them.
+func CaptureInterceptor(capture func(*CapturedCall)) Interceptor {
+	return func(ctx context.Context, req RPCRequestBody, next Invoker) (*RPCFrame, error) {
+		params, _ := json.Marshal(req)
+		start := time.Now()
+		resp, err := next(ctx, req)
+		capture(&CapturedCall{
+			Method:   req.Method(),
+			Params:   params,
+			Response: resp,
+			Err:      err,
+			Latency:  time.Since(start),
+		})
+		return resp, err
+	}
+}
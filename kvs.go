@@ -0,0 +1,294 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// KVSSetRequest stores a value in the Key-Value Store, replacing any existing value.
+type KVSSetRequest struct {
+	// Key to store the value under. Up to 42 characters.
+	Key string `json:"key"`
+
+	// Value is any JSON value, up to 253 bytes encoded.
+	Value any `json:"value"`
+
+	// Etag, if given, must match the etag of the existing value.
+	Etag *string `json:"etag,omitempty"`
+}
+
+func (r *KVSSetRequest) Method() string {
+	return "KVS.Set"
+}
+
+func (r *KVSSetRequest) Idempotent() bool {
+	return true
+}
+
+func (r *KVSSetRequest) NewTypedResponse() *KVSSetResponse {
+	return &KVSSetResponse{}
+}
+
+func (r *KVSSetRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *KVSSetRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*KVSSetResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *KVSSetRequest) Validate() error {
+	v := newValidation(r)
+	if r.Key == "" {
+		v.addf("key", "is required")
+	}
+	v.maxLen("key", Some(r.Key), 42)
+	return v.err()
+}
+
+// KVSSetResponse is the RPC response to the KVSSetRequest.
+type KVSSetResponse struct {
+	// Etag of the stored value.
+	Etag string `json:"etag"`
+
+	// Rev is the revision of the store after the update.
+	Rev int `json:"rev"`
+}
+
+// KVSGetRequest reads a value from the Key-Value Store.
+type KVSGetRequest struct {
+	// Key of the value.
+	Key string `json:"key"`
+}
+
+func (r *KVSGetRequest) Method() string {
+	return "KVS.Get"
+}
+
+func (r *KVSGetRequest) Idempotent() bool {
+	return true
+}
+
+func (r *KVSGetRequest) NewTypedResponse() *KVSGetResponse {
+	return &KVSGetResponse{}
+}
+
+func (r *KVSGetRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *KVSGetRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*KVSGetResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+// KVSGetResponse is the RPC response to the KVSGetRequest.
+type KVSGetResponse struct {
+	// Etag of the value.
+	Etag string `json:"etag"`
+
+	// Value is the stored JSON value.
+	Value json.RawMessage `json:"value"`
+}
+
+// KVSGetManyRequest reads the values whose keys match a pattern. Large results are paginated;
+// use DoAll to read every page.
+type KVSGetManyRequest struct {
+	// Match is a pattern where * matches any sequence of characters, default "*".
+	Match *string `json:"match,omitempty"`
+
+	// Offset is the index of the first item to return.
+	Offset *int `json:"offset,omitempty"`
+}
+
+func (r *KVSGetManyRequest) Method() string {
+	return "KVS.GetMany"
+}
+
+func (r *KVSGetManyRequest) Idempotent() bool {
+	return true
+}
+
+func (r *KVSGetManyRequest) NewTypedResponse() *KVSGetManyResponse {
+	return &KVSGetManyResponse{}
+}
+
+func (r *KVSGetManyRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *KVSGetManyRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*KVSGetManyResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *KVSGetManyRequest) DoAll(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*KVSGetManyResponse,
+	error,
+) {
+	composed := r.NewTypedResponse()
+	page := *r
+	start := 0
+	if r.Offset != nil {
+		start = *r.Offset
+	}
+	total := start + 1
+	for have := start; have < total; {
+		page.Offset = IntPtr(have)
+		resp := r.NewTypedResponse()
+		_, err := Do(ctx, c, credsCallback, &page, resp)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Items) == 0 {
+			break
+		}
+		composed.Items = append(composed.Items, resp.Items...)
+		total = resp.Total
+		have += len(resp.Items)
+	}
+	composed.Offset, composed.Total = start, total
+	return composed, nil
+}
+
+// KVSGetManyResponse is the RPC response to the KVSGetManyRequest.
+type KVSGetManyResponse struct {
+	// Items are the matching entries, ordered by key.
+	Items []KVSItem `json:"items"`
+
+	// Offset is the index of the first item in Items.
+	Offset int `json:"offset"`
+
+	// Total is the number of matching entries.
+	Total int `json:"total"`
+}
+
+// KVSItem is an entry of the Key-Value Store.
+type KVSItem struct {
+	// Key of the entry.
+	Key string `json:"key"`
+
+	// Etag of the value.
+	Etag string `json:"etag"`
+
+	// Value is the stored JSON value.
+	Value json.RawMessage `json:"value"`
+}
+
+// KVSListRequest lists the keys which match a pattern, without their values.
+type KVSListRequest struct {
+	// Match is a pattern where * matches any sequence of characters, default "*".
+	Match *string `json:"match,omitempty"`
+}
+
+func (r *KVSListRequest) Method() string {
+	return "KVS.List"
+}
+
+func (r *KVSListRequest) Idempotent() bool {
+	return true
+}
+
+func (r *KVSListRequest) NewTypedResponse() *KVSListResponse {
+	return &KVSListResponse{}
+}
+
+func (r *KVSListRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *KVSListRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*KVSListResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+// KVSListResponse is the RPC response to the KVSListRequest.
+type KVSListResponse struct {
+	// Keys maps each matching key to its etag.
+	Keys map[string]KVSListKey `json:"keys"`
+
+	// Rev is the current revision of the store.
+	Rev int `json:"rev"`
+}
+
+// KVSListKey describes a key returned by KVS.List.
+type KVSListKey struct {
+	// Etag of the value.
+	Etag string `json:"etag"`
+}
+
+// KVSDeleteRequest removes a value from the Key-Value Store.
+type KVSDeleteRequest struct {
+	// Key of the value.
+	Key string `json:"key"`
+
+	// Etag, if given, must match the etag of the existing value.
+	Etag *string `json:"etag,omitempty"`
+}
+
+func (r *KVSDeleteRequest) Method() string {
+	return "KVS.Delete"
+}
+
+func (r *KVSDeleteRequest) Idempotent() bool {
+	return false
+}
+
+func (r *KVSDeleteRequest) NewTypedResponse() *KVSDeleteResponse {
+	return &KVSDeleteResponse{}
+}
+
+func (r *KVSDeleteRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *KVSDeleteRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*KVSDeleteResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+// KVSDeleteResponse is the RPC response to the KVSDeleteRequest.
+type KVSDeleteResponse struct {
+	// Rev is the revision of the store after the update.
+	Rev int `json:"rev"`
+}
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 // LightGetConfigRequest contains parameters for the Light.GetConfig RPC request.
@@ -17,6 +14,10 @@ func (r *LightGetConfigRequest) Method() string {
 	return "Light.GetConfig"
 }
 
+func (r *LightGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *LightGetConfigRequest) NewTypedResponse() *LightConfig {
 	return &LightConfig{}
 }
@@ -27,16 +28,14 @@ func (r *LightGetConfigRequest) NewResponse() any {
 
 func (r *LightGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*LightConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // LightSetConfigRequest contains parameters for the Light.SetConfig RPC request.
@@ -52,6 +51,10 @@ func (r *LightSetConfigRequest) Method() string {
 	return "Light.SetConfig"
 }
 
+func (r *LightSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *LightSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -62,16 +65,22 @@ func (r *LightSetConfigRequest) NewResponse() any {
 
 func (r *LightSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *LightSetConfigRequest) Validate() error {
+	v := newValidation(r)
+	if r.Config.RangeMap != nil {
+		v.rangeMap("config.range_map", *r.Config.RangeMap, false)
+	}
+	return v.err()
 }
 
 // LightGetStatusRequst contains parameters for the Light.GetStatus RPC request.
@@ -84,6 +93,10 @@ func (r *LightGetStatusRequest) Method() string {
 	return "Light.GetStatus"
 }
 
+func (r *LightGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *LightGetStatusRequest) NewTypedResponse() *LightStatus {
 	return &LightStatus{}
 }
@@ -94,16 +107,14 @@ func (r *LightGetStatusRequest) NewResponse() any {
 
 func (r *LightGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*LightStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // LightSetRequest is the parameters for the Light.Set RPC, which enables or disables a light.
@@ -129,6 +140,10 @@ func (r *LightSetRequest) Method() string {
 	return "Light.Set"
 }
 
+func (r *LightSetRequest) Idempotent() bool {
+	return true
+}
+
 func (r *LightSetRequest) NewTypedResponse() *LightSetResponse {
 	return &LightSetResponse{}
 }
@@ -139,16 +154,22 @@ func (r *LightSetRequest) NewResponse() any {
 
 func (r *LightSetRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*LightSetResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *LightSetRequest) Validate() error {
+	v := newValidation(r)
+	if r.On == nil && r.Brightness == nil {
+		v.addf("on", "on or brightness is required")
+	}
+	return v.err()
 }
 
 // LightSetResponse is the response body for the Light.Set RPC.
@@ -164,6 +185,10 @@ func (r *LightToggleRequest) Method() string {
 	return "Light.Toggle"
 }
 
+func (r *LightToggleRequest) Idempotent() bool {
+	return false
+}
+
 func (r *LightToggleRequest) NewTypedResponse() *LightToggleResponse {
 	return &LightToggleResponse{}
 }
@@ -174,16 +199,14 @@ func (r *LightToggleRequest) NewResponse() any {
 
 func (r *LightToggleRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*LightToggleResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // LightToggleResponse is the body for the Light.Toggle RPC response.
@@ -195,7 +218,7 @@ type LightConfig struct {
 	ID int `json:"id"`
 
 	// Name of the light instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// InMode is the mode of the associated input. Range of values: follow, flip,
 	// activate, detached, dim (if applicable), dual_dim (if applicable).
@@ -263,7 +286,7 @@ type LightNightModeConfig struct {
 // LightButtonPresetsConfig provides configuration for button presets.
 type LightButtonPresetsConfig struct {
 	// ButtonDoublePush configures button double push behavior. nil disables button_doublepush.
-	ButtonDoublePush *LightButtonPresetsDoublePushConfig `json:"button_doublepush,omitempty"`
+	ButtonDoublePush Optional[LightButtonPresetsDoublePushConfig] `json:"button_doublepush,omitempty"`
 }
 
 // LightButtonPresetsDoublePushConfig configures button double push behavior.
@@ -0,0 +1,315 @@
+package shelly
+
+import (
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultMaxInFlight is the default number of concurrent calls allowed per device. Gen2
+	// devices handle only a few simultaneous connections.
+	DefaultMaxInFlight = 2
+)
+
+// Priority orders queued calls; higher priorities are sent first.
+type Priority int
+
+const (
+	// PriorityTelemetry is used for reads such as Shelly.GetStatus and Switch.GetConfig.
+	PriorityTelemetry Priority = -10
+
+	// PriorityDefault is used for methods which are neither reads nor control commands.
+	PriorityDefault Priority = 0
+
+	// PriorityControl is used for commands which change outputs, ex. Switch.Set or Cover.Open.
+	PriorityControl Priority = 10
+)
+
+var controlMethods = map[string]bool{
+	"Set": true, "Toggle": true, "Open": true, "Close": true, "Stop": true, "GoToPosition": true,
+	"Trigger": true,
+}
+
+// MethodPriority returns the default priority of a method. Control commands precede other
+// calls, which precede reads.
+func MethodPriority(method string) Priority {
+	_, name, _ := strings.Cut(method, ".")
+	switch {
+	case controlMethods[name]:
+		return PriorityControl
+	case isReadMethod(method):
+		return PriorityTelemetry
+	default:
+		return PriorityDefault
+	}
+}
+
+// isReadMethod returns true for methods which only read device state.
+func isReadMethod(method string) bool {
+	_, name, _ := strings.Cut(method, ".")
+	return strings.HasPrefix(name, "Get") || strings.HasPrefix(name, "List")
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context which overrides the priority of calls made through a Limiter.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// LimiterGroup queues calls per device so that independent channels to the same device share a
+// concurrency limit.
+type LimiterGroup struct {
+	maxInFlight int
+
+	mu     sync.Mutex
+	queues map[string]*deviceQueue
+}
+
+// NewLimiterGroup builds a group allowing maxInFlight concurrent calls per device. If
+// maxInFlight is less than 1, DefaultMaxInFlight is used.
+func NewLimiterGroup(maxInFlight int) *LimiterGroup {
+	if maxInFlight < 1 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	return &LimiterGroup{maxInFlight: maxInFlight, queues: make(map[string]*deviceQueue)}
+}
+
+// Wrap returns a Limiter which makes calls via c while sharing the queue of deviceID with other
+// channels wrapped by the group.
+func (g *LimiterGroup) Wrap(deviceID string, c Caller) *Limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	q, ok := g.queues[deviceID]
+	if !ok {
+		q = newDeviceQueue(g.maxInFlight)
+		g.queues[deviceID] = q
+	}
+	return &Limiter{c: c, q: q}
+}
+
+// Limiter implements Caller, queueing calls to the wrapped channel. At most the configured
+// number of calls are in flight; queued calls are sent in priority order (see MethodPriority and
+// WithPriority), then in order of arrival. Identical concurrent reads made with the same
+// credentials (ex. two Shelly.GetStatus calls) are merged into a single call whose response is
+// shared. The merged call uses the priority of the first caller, and is cancelled only when
+// every caller's context is done. Writes, even idempotent ones, are always sent.
+type Limiter struct {
+	c Caller
+	q *deviceQueue
+}
+
+// NewLimiter wraps c with its own queue allowing maxInFlight concurrent calls. Use a
+// LimiterGroup to share a queue between channels.
+func NewLimiter(c Caller, maxInFlight int) *Limiter {
+	return NewLimiterGroup(maxInFlight).Wrap("", c)
+}
+
+// Call implements Caller.
+func (l *Limiter) Call(ctx context.Context, f *RPCFrame, getCreds GetCredsCallback) (*RPCFrame, error) {
+	return l.CallRequest(ctx, nil, f, getCreds)
+}
+
+// CallRequest implements RequestCaller, passing req to the wrapped channel.
+func (l *Limiter) CallRequest(
+	ctx context.Context,
+	req RPCRequestBody,
+	f *RPCFrame,
+	getCreds GetCredsCallback,
+) (*RPCFrame, error) {
+	// Only reads are merged. Raw calls aren't, as their idempotency is unknown, nor are calls
+	// whose credentials can't be compared.
+	identity, ok := credsIdentity(getCreds)
+	if req == nil || !req.Idempotent() || !isReadMethod(f.Method) || !ok {
+		return l.q.do(ctx, f.Method, func() (*RPCFrame, error) {
+			return callRequest(ctx, l.c, req, f, getCreds)
+		})
+	}
+
+	key := f.Dst + "\x00" + f.Method + "\x00" + string(f.Params) + "\x00" + identity
+	l.q.mu.Lock()
+	shared, ok := l.q.shared[key]
+	if !ok {
+		shared = &sharedCall{done: make(chan struct{})}
+		// The call outlives its first caller, keeping its values (ex. WithPriority).
+		var callCtx context.Context
+		callCtx, shared.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		l.q.shared[key] = shared
+		go func() {
+			resp, err := l.q.do(callCtx, f.Method, func() (*RPCFrame, error) {
+				return callRequest(callCtx, l.c, req, f, getCreds)
+			})
+			l.q.mu.Lock()
+			if l.q.shared[key] == shared {
+				delete(l.q.shared, key)
+			}
+			shared.resp, shared.err = resp, err
+			l.q.mu.Unlock()
+			shared.cancel()
+			close(shared.done)
+		}()
+	}
+	shared.waiters++
+	l.q.mu.Unlock()
+
+	select {
+	case <-shared.done:
+		return shared.resp, shared.err
+	case <-ctx.Done():
+		l.q.mu.Lock()
+		shared.waiters--
+		if shared.waiters == 0 {
+			// Nobody is waiting for the response; later callers start a new call.
+			if l.q.shared[key] == shared {
+				delete(l.q.shared, key)
+			}
+			shared.cancel()
+		}
+		l.q.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// Subscribe implements Subscriber, returning the notifications received by the wrapped caller.
+func (l *Limiter) Subscribe(ctx context.Context, method string) <-chan *RPCFrame {
+	return subscribe(ctx, l.c, method)
+}
+
+// credsIdentity identifies the credentials returned by getCreds, so calls are only merged with
+// calls made by the same user. It's false if the credentials can't be read.
+func credsIdentity(getCreds GetCredsCallback) (string, bool) {
+	if getCreds == nil {
+		return "", true
+	}
+	username, password, err := getCreds()
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(username + "\x00" + password))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// sharedCall is a call whose response is shared by its waiters.
+type sharedCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	resp    *RPCFrame
+	err     error
+}
+
+// deviceQueue limits the calls in flight to a single device.
+type deviceQueue struct {
+	max int
+
+	mu       sync.Mutex
+	inFlight int
+	seq      uint64
+	waiting  waitHeap
+	shared   map[string]*sharedCall
+}
+
+func newDeviceQueue(max int) *deviceQueue {
+	return &deviceQueue{max: max, shared: make(map[string]*sharedCall)}
+}
+
+type waiter struct {
+	prio  Priority
+	seq   uint64
+	ready chan struct{}
+	index int
+}
+
+func (q *deviceQueue) do(ctx context.Context, method string, call func() (*RPCFrame, error)) (*RPCFrame, error) {
+	if err := q.acquire(ctx, method); err != nil {
+		return nil, err
+	}
+	defer q.release()
+	return call()
+}
+
+func (q *deviceQueue) acquire(ctx context.Context, method string) error {
+	prio, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		prio = MethodPriority(method)
+	}
+	q.mu.Lock()
+	if q.inFlight < q.max && len(q.waiting) == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return nil
+	}
+	q.seq++
+	w := &waiter{prio: prio, seq: q.seq, ready: make(chan struct{})}
+	heap.Push(&q.waiting, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if w.index >= 0 {
+			heap.Remove(&q.waiting, w.index)
+			return ctx.Err()
+		}
+		// The slot was granted while the context expired; pass it on.
+		q.inFlight--
+		q.grant()
+		return ctx.Err()
+	}
+}
+
+func (q *deviceQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inFlight--
+	q.grant()
+}
+
+// grant starts waiting calls while slots are available. q.mu must be held.
+func (q *deviceQueue) grant() {
+	for q.inFlight < q.max && len(q.waiting) > 0 {
+		w := heap.Pop(&q.waiting).(*waiter)
+		q.inFlight++
+		close(w.ready)
+	}
+}
+
+// waitHeap orders waiters by descending priority, then arrival.
+type waitHeap []*waiter
+
+func (h waitHeap) Len() int { return len(h) }
+
+func (h waitHeap) Less(i, j int) bool {
+	if h[i].prio != h[j].prio {
+		return h[i].prio > h[j].prio
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waitHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waitHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waitHeap) Pop() any {
+	old := *h
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*h = old[:len(old)-1]
+	return w
+}
@@ -0,0 +1,204 @@
+package shelly
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedRPC blocks each call until a value is sent on release, recording the order of calls.
+type gatedRPC struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	started []string
+}
+
+func (g *gatedRPC) Call(ctx context.Context, f *RPCFrame, _ GetCredsCallback) (*RPCFrame, error) {
+	g.mu.Lock()
+	g.started = append(g.started, f.Method)
+	g.mu.Unlock()
+	<-g.release
+	return &RPCFrame{Result: []byte(`{}`)}, nil
+}
+
+func (g *gatedRPC) calls() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.started...)
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	g := &gatedRPC{release: make(chan struct{})}
+	l := NewLimiterGroup(1).Wrap("shellypro4pm-f008d1d8b8b8", g)
+
+	var wg sync.WaitGroup
+	call := func(req RPCRequestBody) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Do(ctx, l, nil, req, &struct{}{})
+			assert.NoError(t, err)
+		}()
+	}
+	waitFor := func(n int) {
+		require.Eventually(t, func() bool { return len(g.calls()) == n }, time.Second, time.Millisecond)
+	}
+
+	// The first call takes the only slot; the rest queue.
+	call(&SysGetConfigRequest{})
+	waitFor(1)
+	call(&ShellyGetStatusRequest{})
+	call(&ShellyGetStatusRequest{})
+	call(&SwitchSetRequest{ID: 0, On: true})
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, g.calls(), 1)
+
+	// The control command jumps the queue and the duplicate reads are merged.
+	g.release <- struct{}{}
+	waitFor(2)
+	g.release <- struct{}{}
+	waitFor(3)
+	g.release <- struct{}{}
+	wg.Wait()
+	assert.Equal(t, []string{"Sys.GetConfig", "Switch.Set", "Shelly.GetStatus"}, g.calls())
+}
+
+func TestLimiterCancelQueued(t *testing.T) {
+	g := &gatedRPC{release: make(chan struct{})}
+	l := NewLimiter(g, 1)
+	go Do(context.Background(), l, nil, &SwitchSetRequest{ID: 0, On: true}, &struct{}{})
+	require.Eventually(t, func() bool { return len(g.calls()) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := Do(ctx, l, nil, &SwitchSetRequest{ID: 0, On: false}, &struct{}{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	g.release <- struct{}{}
+	go func() { g.release <- struct{}{} }()
+	_, err = Do(context.Background(), l, nil, &SwitchSetRequest{ID: 0, On: false}, &struct{}{})
+	assert.NoError(t, err)
+}
+
+// ctxRPC blocks each call until a value is sent on release or its context is done.
+type ctxRPC struct {
+	release chan struct{}
+
+	mu        sync.Mutex
+	calls     int
+	cancelled int
+}
+
+func (c *ctxRPC) Call(ctx context.Context, f *RPCFrame, _ GetCredsCallback) (*RPCFrame, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	select {
+	case <-c.release:
+		return &RPCFrame{Result: []byte(`{}`)}, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		c.cancelled++
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (c *ctxRPC) counts() (calls, cancelled int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls, c.cancelled
+}
+
+func TestLimiterSharedCall(t *testing.T) {
+	rpc := &ctxRPC{release: make(chan struct{})}
+	l := NewLimiter(rpc, 2)
+	started := func(n int) {
+		require.Eventually(t, func() bool {
+			calls, _ := rpc.counts()
+			return calls == n
+		}, time.Second, time.Millisecond)
+	}
+
+	// The shared call continues when its first caller gives up.
+	first, cancelFirst := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := Do(first, l, nil, &ShellyGetStatusRequest{}, &struct{}{})
+		errs <- err
+	}()
+	started(1)
+	go func() {
+		_, err := Do(context.Background(), l, nil, &ShellyGetStatusRequest{}, &struct{}{})
+		errs <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancelFirst()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	rpc.release <- struct{}{}
+	assert.NoError(t, <-errs)
+	calls, cancelled := rpc.counts()
+	assert.Equal(t, 1, calls)
+	assert.Zero(t, cancelled)
+
+	// It's cancelled once every caller has given up.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := Do(ctx, l, nil, &ShellyGetStatusRequest{}, &struct{}{})
+		errs <- err
+	}()
+	started(2)
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	require.Eventually(t, func() bool {
+		_, cancelled := rpc.counts()
+		return cancelled == 1
+	}, time.Second, time.Millisecond)
+
+	// Requests which aren't idempotent are never merged.
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := Do(context.Background(), l, nil, &CoverGoToPositionRequest{Rel: Float64Ptr(10)}, &struct{}{})
+			errs <- err
+		}()
+	}
+	started(4)
+	rpc.release <- struct{}{}
+	rpc.release <- struct{}{}
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+
+	// Neither are idempotent writes, nor reads made with different credentials.
+	go func() {
+		_, err := Do(context.Background(), l, nil, &SwitchSetRequest{ID: 0, On: true}, &struct{}{})
+		errs <- err
+	}()
+	go func() {
+		_, err := Do(context.Background(), l, nil, &SwitchSetRequest{ID: 0, On: true}, &struct{}{})
+		errs <- err
+	}()
+	started(6)
+	rpc.release <- struct{}{}
+	rpc.release <- struct{}{}
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+	for _, password := range []string{"hunter2", "hunter3"} {
+		password := password
+		creds := func() (string, string, error) { return DefaultAuthenticationUsername, password, nil }
+		go func() {
+			_, err := Do(context.Background(), l, creds, &ShellyGetStatusRequest{}, &struct{}{})
+			errs <- err
+		}()
+	}
+	started(8)
+	rpc.release <- struct{}{}
+	rpc.release <- struct{}{}
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+}
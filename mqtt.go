@@ -2,11 +2,7 @@ package shelly
 
 import (
 	"context"
-	"errors"
 	"strings"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type MQTTSetConfigRequest struct {
@@ -17,6 +13,10 @@ func (r *MQTTSetConfigRequest) Method() string {
 	return "MQTT.SetConfig"
 }
 
+func (r *MQTTSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *MQTTSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -27,16 +27,28 @@ func (r *MQTTSetConfigRequest) NewResponse() any {
 
 func (r *MQTTSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *MQTTSetConfigRequest) Validate() error {
+	v := newValidation(r)
+	if prefix, ok := r.Config.TopicPrefix.Get(); ok {
+		v.maxLen("config.topic_prefix", r.Config.TopicPrefix, 300)
+		if strings.HasPrefix(prefix, "$") {
+			v.addf("config.topic_prefix", "must not start with $")
+		}
+		if i := strings.IndexAny(prefix, "#+%?"); i >= 0 {
+			v.addf("config.topic_prefix", "must not contain %q", prefix[i])
+		}
+	}
+	return v.err()
 }
 
 type MQTTGetConfigRequest struct {
@@ -47,6 +59,10 @@ func (r *MQTTGetConfigRequest) Method() string {
 	return "MQTT.GetConfig"
 }
 
+func (r *MQTTGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *MQTTGetConfigRequest) NewTypedResponse() *MQTTConfig {
 	return &MQTTConfig{}
 }
@@ -57,29 +73,21 @@ func (r *MQTTGetConfigRequest) NewResponse() any {
 
 func (r *MQTTGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*MQTTConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
-// MQTT_SSL_CA is a type to differentiate between not-set (empty string), null (no TLS), and string
-// values.
+// MQTT_SSL_CA is the type of TLS connection made to the MQTT server. A null MQTTConfig.SSL_CA
+// disables TLS.
 type MQTT_SSL_CA string
 
 const (
-	// MQTT_SSL_CA_NULL will disable the TLS on the MQTT connection
-	MQTT_SSL_CA_NULL MQTT_SSL_CA = "null"
-
-	// MQTT_SSL_CA_NOT_SET will not send a value for the `ssl_ca` property, leaving it unchanged.
-	MQTT_SSL_CA_NOT_SET MQTT_SSL_CA = ""
-
 	// MQTT_SSL_CA_NO_VERIFY will enable TLS but CA skip verification of the server certificate.
 	MQTT_SSL_CA_NO_VERIFY MQTT_SSL_CA = "*"
 
@@ -91,56 +99,27 @@ const (
 	MQTT_SSL_CA_USER_CA MQTT_SSL_CA = "user_ca.pem"
 )
 
-func (ca *MQTT_SSL_CA) UnmarshalJSON(b []byte) error {
-	// NOTE if the balue is unset, this UnmarshallJSON method will not be called which is why
-	// MQTT_SSL_CA_NOT_SET is absent.
-	s := strings.TrimSpace(string(b))
-	switch s {
-	case string(MQTT_SSL_CA_NULL):
-		*ca = MQTT_SSL_CA_NULL
-		return nil
-	case `"` + string(MQTT_SSL_CA_NO_VERIFY) + `"`:
-		*ca = MQTT_SSL_CA_NO_VERIFY
-		return nil
-	case `"` + string(MQTT_SSL_CA_DEFAULT_CA) + `"`:
-		*ca = MQTT_SSL_CA_DEFAULT_CA
-		return nil
-	case `"` + string(MQTT_SSL_CA_USER_CA) + `"`:
-		*ca = MQTT_SSL_CA_USER_CA
-		return nil
-	default:
-		return errors.New("unknown value for MQTTConfig.SSL_CA")
-	}
-}
-
-func (ca *MQTT_SSL_CA) MarshalJSON() ([]byte, error) {
-	if ca == nil || *ca == MQTT_SSL_CA_NULL {
-		return []byte("null"), nil
-	}
-	return []byte(`"` + *ca + `"`), nil
-}
-
 // MQTTConfig configures MQTT for Shelly.
 type MQTTConfig struct {
 	// Enbable is true if MQTT connection is enabled, false otherwise
 	Enable *bool `json:"enabled,omitempty"`
 	// Server is the hostname of the MQTT server. Can be followed by port number - host:port
-	Server *NullString `json:"server,omitempty"`
+	Server Optional[string] `json:"server,omitempty"`
 	// ClientID identifies each MQTT client that connects to an MQTT brokers. Defaults if null to device id.
-	ClientID *NullString `json:"client_id,omitempty"`
+	ClientID Optional[string] `json:"client_id,omitempty"`
 	// User is the username.
-	User *string `json:"user,omitempty"`
+	User Optional[string] `json:"user,omitempty"`
 	// Pass is the password.
-	Pass *NullString `json:"pass,omitempty"`
+	Pass Optional[string] `json:"pass,omitempty"`
 	// SSL_CA determines the type of connection to make.
 	// If null, no TLS will be used.
 	// If `*` TLS connections will be made without server verification.
 	// If `user_ca.pem` TLS connection will be verified by the user-provided CA.
 	// If `ca.pem` TLS connections will be verified against the default CA list.
-	SSL_CA MQTT_SSL_CA `json:"ssl_ca,omitempty"`
+	SSL_CA Optional[MQTT_SSL_CA] `json:"ssl_ca,omitempty"`
 	// TopicPrefix is the prefix of the topics on which device publish/subscribe. Limited to 300
 	// characters. Could not start with $ and #, +, %, ? are not allowed.
-	TopicPrefix *NullString `json:"topic_prefix,omitempty"`
+	TopicPrefix Optional[string] `json:"topic_prefix,omitempty"`
 	// RPC_NTF enables RPC notifications (NotifyStatus and NotifyEvent) to be published on
 	// <device_id|topic_prefix>/events/rpc (<topic_prefix> when a custom prefix is set, <device_id>
 	// otherwise). Default value: true.
@@ -164,6 +143,10 @@ func (r *MQTTGetStatusRequest) Method() string {
 	return "MQTT.GetStatus"
 }
 
+func (r *MQTTGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *MQTTGetStatusRequest) NewTypedResponse() *MQTTStatus {
 	return &MQTTStatus{}
 }
@@ -174,16 +157,14 @@ func (r *MQTTGetStatusRequest) NewResponse() any {
 
 func (r *MQTTGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*MQTTStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type MQTTStatus struct {
@@ -1,6 +1,9 @@
 package shelly
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // NotifyStatus implements the NotifyStatus and NotifyFullStatus payload types.
 type NotifyStatus struct {
@@ -48,4 +51,92 @@ type Event struct {
 
 	// Event name.
 	Event string `json:"event"`
+
+	// Data is the event specific payload, decoded based on Component and Event. Events without a
+	// registered type, or whose payload doesn't match it, are decoded as RawEventData.
+	Data EventData `json:"-"`
+}
+
+// EventData is implemented by the typed payloads of events.
+type EventData interface {
+	eventData()
+}
+
+// RawEventData holds the complete JSON object of an event without a registered type.
+type RawEventData json.RawMessage
+
+func (RawEventData) eventData() {}
+
+// MarshalJSON implements json.Marshaler.
+func (r RawEventData) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// eventDecoders maps component types to the constructors of their event payloads, keyed by event
+// name. Component files contribute their own tables.
+var eventDecoders = map[string]map[string]func() EventData{
+	"input":  inputEvents,
+	"cover":  coverEvents,
+	"switch": switchEvents,
+	"sys":    sysEvents,
+}
+
+// ComponentType returns the type portion of the component key, ex. "input" for "input:0".
+func (e *Event) ComponentType() string {
+	t, _, _ := strings.Cut(e.Component, ":")
+	return t
+}
+
+// eventBase avoids recursion into Event's json methods.
+type eventBase struct {
+	TS        float64 `json:"ts"`
+	Component string  `json:"component,omitempty"`
+	ID        int     `json:"id,omitempty"`
+	Event     string  `json:"event"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var base eventBase
+	if err := json.Unmarshal(b, &base); err != nil {
+		return err
+	}
+	e.TS, e.Component, e.ID, e.Event = base.TS, base.Component, base.ID, base.Event
+	if newData, ok := eventDecoders[e.ComponentType()][e.Event]; ok {
+		// Firmware may change a payload; keep it raw rather than losing the whole notification.
+		data := newData()
+		if err := json.Unmarshal(b, data); err == nil {
+			e.Data = data
+			return nil
+		}
+	}
+	e.Data = RawEventData(append([]byte(nil), b...))
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Fields of Data are merged into the event object.
+func (e Event) MarshalJSON() ([]byte, error) {
+	out := map[string]json.RawMessage{}
+	if e.Data != nil {
+		b, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		if string(b) != "null" {
+			if err := json.Unmarshal(b, &out); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b, err := json.Marshal(eventBase{TS: e.TS, Component: e.Component, ID: e.ID, Event: e.Event})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
 }
@@ -0,0 +1,59 @@
+package shelly
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyEventUnmarshal(t *testing.T) {
+	in := `{"ts":1703811195.5,"events":[
+		{"component":"input:0","event":"single_push","ts":1703811195.5},
+		{"component":"sys","event":"ota_progress","msg":"Waiting for data","progress_percent":42,"ts":1703811195.5},
+		{"component":"sys","event":"config_changed","restart_required":false,"cfg_rev":31,"ts":1703811195.5},
+		{"component":"sys","event":"scheduled_restart","time_ms":995,"ts":1703811195.5},
+		{"component":"cover:0","event":"opening","target_pos":80,"ts":1703811195.5},
+		{"component":"script:1","id":1,"event":"my_event","data":{"a":1},"ts":1703811195.5}
+	]}`
+	var ne NotifyEvent
+	require.NoError(t, json.Unmarshal([]byte(in), &ne))
+	require.Len(t, ne.Events, 6)
+
+	assert.Equal(t, "input", ne.Events[0].ComponentType())
+	assert.IsType(t, &InputButtonEvent{}, ne.Events[0].Data)
+
+	ota, ok := ne.Events[1].Data.(*SysOTAEvent)
+	require.True(t, ok)
+	assert.Equal(t, "Waiting for data", ota.Msg)
+	assert.Equal(t, 42, *ota.ProgressPercent)
+
+	assert.Equal(t, &SysConfigChangedEvent{CfgRev: 31}, ne.Events[2].Data)
+	assert.Equal(t, &SysScheduledRestartEvent{TimeMS: 995}, ne.Events[3].Data)
+	assert.Equal(t, &CoverMovingEvent{TargetPos: Float64Ptr(80)}, ne.Events[4].Data)
+
+	raw, ok := ne.Events[5].Data.(RawEventData)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"component":"script:1","id":1,"event":"my_event","data":{"a":1},"ts":1703811195.5}`, string(raw))
+
+	out, err := json.Marshal(&ne)
+	require.NoError(t, err)
+	assert.JSONEq(t, in, string(out))
+}
+
+func TestNotifyEventUnmarshalMismatch(t *testing.T) {
+	in := `{"ts":1703811195.5,"events":[
+		{"component":"sys","event":"config_changed","cfg_rev":"31","ts":1703811195.5},
+		{"component":"input:0","event":"single_push","ts":1703811195.5}
+	]}`
+	var ne NotifyEvent
+	require.NoError(t, json.Unmarshal([]byte(in), &ne))
+	require.Len(t, ne.Events, 2)
+
+	assert.Equal(t, "config_changed", ne.Events[0].Event)
+	raw, ok := ne.Events[0].Data.(RawEventData)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"component":"sys","event":"config_changed","cfg_rev":"31","ts":1703811195.5}`, string(raw))
+	assert.IsType(t, &InputButtonEvent{}, ne.Events[1].Data)
+}
@@ -0,0 +1,94 @@
+package shelly
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Optional is a config value with three states: unset, null and a value. Shelly distinguishes
+// an omitted key, which leaves the existing value unchanged, from `null`, which clears it. With
+// the `omitempty` flag an unset Optional is omitted, a null Optional encodes to `null`, and a
+// set Optional encodes its value. Decoding preserves the distinction.
+//
+// The zero value is unset. Optional is a map so that `omitempty` applies to it; use Some and
+// Null to build values rather than map literals.
+type Optional[T any] map[bool]T
+
+// Some returns an Optional holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{true: v}
+}
+
+// Null returns an Optional which encodes to `null`.
+func Null[T any]() Optional[T] {
+	return Optional[T]{false: *new(T)}
+}
+
+// IsSet is true if o is null or holds a value.
+func (o Optional[T]) IsSet() bool {
+	return len(o) > 0
+}
+
+// IsNull is true if o is null.
+func (o Optional[T]) IsNull() bool {
+	_, ok := o[false]
+	return ok
+}
+
+// Get returns the value of o, and false if it's unset or null.
+func (o Optional[T]) Get() (T, bool) {
+	v, ok := o[true]
+	return v, ok
+}
+
+// Or returns the value of o, or def if it's unset or null.
+func (o Optional[T]) Or(def T) T {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return def
+}
+
+// Ptr returns a pointer to a copy of the value of o, or nil if it's unset or null.
+func (o Optional[T]) Ptr() *T {
+	if v, ok := o.Get(); ok {
+		return &v
+	}
+	return nil
+}
+
+// Set sets o to v.
+func (o *Optional[T]) Set(v T) {
+	*o = Some(v)
+}
+
+// SetNull sets o to null.
+func (o *Optional[T]) SetNull() {
+	*o = Null[T]()
+}
+
+// Unset clears o, so it's omitted.
+func (o *Optional[T]) Unset() {
+	*o = nil
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	v, ok := o.Get()
+	if !ok {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v)
+}
+
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		o.SetNull()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	o.Set(v)
+	return nil
+}
@@ -0,0 +1,68 @@
+package shelly
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionalMarshal(t *testing.T) {
+	b, err := json.Marshal(&WifiSetConfigRequest{Config: WifiConfig{STA: &WifiStationConfig{
+		SSID:     Some("home"),
+		Pass:     Some("hunter2"),
+		IPv4Mode: StrPtr("dhcp"),
+		IP:       Null[string](),
+	}}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"config":{"sta":{"ssid":"home","pass":"hunter2","ipv4mode":"dhcp","ip":null}}}`, string(b))
+
+	// An unset name is omitted rather than clearing the existing name.
+	b, err = json.Marshal(&SwitchConfig{ID: 1})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1}`, string(b))
+
+	b, err = json.Marshal(&SysLocationConfig{Lat: Some(45.5), Lon: Some(-73.6), TZ: Null[string]()})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"lat":45.5,"lon":-73.6,"tz":null}`, string(b))
+}
+
+func TestOptionalUnmarshal(t *testing.T) {
+	var cfg EthConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"ip":"192.168.1.20","netmask":null}`), &cfg))
+
+	ip, ok := cfg.IP.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "192.168.1.20", ip)
+	assert.True(t, cfg.Netmask.IsSet())
+	assert.True(t, cfg.Netmask.IsNull())
+	assert.Nil(t, cfg.Netmask.Ptr())
+	assert.False(t, cfg.GW.IsSet())
+	assert.False(t, cfg.GW.IsNull())
+	assert.Equal(t, "8.8.8.8", cfg.Nameserver.Or("8.8.8.8"))
+
+	var mqtt MQTTConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"ssl_ca":"ca.pem","server":null}`), &mqtt))
+	assert.Equal(t, Some(MQTT_SSL_CA_DEFAULT_CA), mqtt.SSL_CA)
+	assert.Equal(t, Null[string](), mqtt.Server)
+	assert.Error(t, json.Unmarshal([]byte(`{"server":1}`), &mqtt))
+
+	// Round trips preserve all three states.
+	b, err := json.Marshal(&cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ip":"192.168.1.20","netmask":null}`, string(b))
+}
+
+func TestOptionalSet(t *testing.T) {
+	var o Optional[int]
+	assert.False(t, o.IsSet())
+	o.Set(3)
+	assert.Equal(t, 3, *o.Ptr())
+	o.SetNull()
+	assert.True(t, o.IsNull())
+	_, ok := o.Get()
+	assert.False(t, ok)
+	o.Unset()
+	assert.False(t, o.IsSet())
+}
@@ -0,0 +1,262 @@
+// Package backup saves the configuration of a Gen2 device to a single versioned JSON document
+// and replays it onto the same or a replacement device:
+//
+//	doc, err := backup.Backup(ctx, dev)
+//	...
+//	report, err := backup.Restore(ctx, replacement, doc)
+//
+// A Document holds the output of Shelly.GetConfig along with the state which it doesn't
+// include: script code, schedules, webhooks, KVS entries, dynamic components (virtual
+// components, BTHome devices and sensors) and the device profile. Sections the device doesn't
+// support are left empty.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+const (
+	// FormatVersion is the version of the Document format written by Backup. Restore rejects
+	// documents with other versions.
+	FormatVersion = 1
+)
+
+var (
+	// ErrUnsupportedVersion is returned by Restore for documents written in another format.
+	ErrUnsupportedVersion = errors.New("unsupported backup format version")
+)
+
+// Document is a backup of a device.
+type Document struct {
+	// Version is the format version, FormatVersion when written by Backup.
+	Version int `json:"version"`
+
+	// CreatedAt is the time the backup was taken.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Device identifies the device which was backed up.
+	Device *shelly.ShellyGetDeviceInfoResponse `json:"device"`
+
+	// Profile is the device profile, empty for devices with a single profile.
+	Profile string `json:"profile,omitempty"`
+
+	// Config is the result of Shelly.GetConfig, keyed by component (ex. "switch:0").
+	Config map[string]json.RawMessage `json:"config"`
+
+	// Scripts are the scripts with their code.
+	Scripts []Script `json:"scripts,omitempty"`
+
+	// Schedules are the scheduled jobs.
+	Schedules []shelly.Schedule `json:"schedules,omitempty"`
+
+	// Webhooks are the configured webhooks.
+	Webhooks []shelly.Webhook `json:"webhooks,omitempty"`
+
+	// KVS are the entries of the Key-Value Store.
+	KVS []KVSEntry `json:"kvs,omitempty"`
+
+	// Virtual are the virtual components (ex. boolean:200).
+	Virtual []Component `json:"virtual,omitempty"`
+
+	// BTHomeDevices are the BTHomeDevice components.
+	BTHomeDevices []Component `json:"bthome_devices,omitempty"`
+
+	// BTHomeSensors are the BTHomeSensor components.
+	BTHomeSensors []Component `json:"bthome_sensors,omitempty"`
+}
+
+// Script is a backed up script.
+type Script struct {
+	// ID of the script on the device which was backed up.
+	ID int `json:"id"`
+
+	// Name of the script.
+	Name string `json:"name"`
+
+	// Enable is true if the script runs on boot.
+	Enable bool `json:"enable"`
+
+	// Code is the script's source.
+	Code string `json:"code"`
+}
+
+// KVSEntry is a backed up Key-Value Store entry.
+type KVSEntry struct {
+	// Key of the entry.
+	Key string `json:"key"`
+
+	// Value is the stored JSON value.
+	Value json.RawMessage `json:"value"`
+}
+
+// Component is a backed up dynamic component.
+type Component struct {
+	// Key of the component, ex. "boolean:200".
+	Key string `json:"key"`
+
+	// Config is the component's configuration, as returned by its GetConfig method.
+	Config json.RawMessage `json:"config"`
+}
+
+// Backup reads the configuration of dev into a Document.
+func Backup(ctx context.Context, dev *shelly.Device) (*Document, error) {
+	info, err := dev.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading device info: %w", err)
+	}
+	doc := &Document{
+		Version:   FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Device:    info,
+		Profile:   info.Profile,
+	}
+	if _, err := dev.Do(ctx, &shelly.RawRequest{Cmd: "Shelly.GetConfig"}, &doc.Config); err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	sections := []struct {
+		name   string
+		backup func(context.Context, *shelly.Device, *Document) error
+	}{
+		{"scripts", backupScripts},
+		{"schedules", backupSchedules},
+		{"webhooks", backupWebhooks},
+		{"kvs", backupKVS},
+		{"components", backupComponents},
+	}
+	for _, s := range sections {
+		if err := s.backup(ctx, dev, doc); err != nil && !unsupported(err) {
+			return nil, fmt.Errorf("backing up %s: %w", s.name, err)
+		}
+	}
+	return doc, nil
+}
+
+func backupScripts(ctx context.Context, dev *shelly.Device, doc *Document) error {
+	scripts, err := dev.Scripts().List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range scripts {
+		code, err := dev.Scripts().GetCode(ctx, s.ID)
+		if err != nil {
+			return fmt.Errorf("reading code of script %d: %w", s.ID, err)
+		}
+		doc.Scripts = append(doc.Scripts, Script{ID: s.ID, Name: s.Name, Enable: s.Enable, Code: code})
+	}
+	return nil
+}
+
+func backupSchedules(ctx context.Context, dev *shelly.Device, doc *Document) error {
+	resp, err := call(ctx, dev, &shelly.ScheduleListRequest{})
+	if err != nil {
+		return err
+	}
+	doc.Schedules = resp.Jobs
+	return nil
+}
+
+func backupWebhooks(ctx context.Context, dev *shelly.Device, doc *Document) error {
+	resp, err := call(ctx, dev, &shelly.WebhookListRequest{})
+	if err != nil {
+		return err
+	}
+	doc.Webhooks = resp.Hooks
+	return nil
+}
+
+func backupKVS(ctx context.Context, dev *shelly.Device, doc *Document) error {
+	for {
+		resp, err := call(ctx, dev, &shelly.KVSGetManyRequest{Offset: shelly.IntPtr(len(doc.KVS))})
+		if err != nil {
+			return err
+		}
+		for _, item := range resp.Items {
+			doc.KVS = append(doc.KVS, KVSEntry{Key: item.Key, Value: item.Value})
+		}
+		if len(resp.Items) == 0 || len(doc.KVS) >= resp.Total {
+			return nil
+		}
+	}
+}
+
+func backupComponents(ctx context.Context, dev *shelly.Device, doc *Document) error {
+	// Dynamic components are included in doc.Config; they're grouped by type so Restore can
+	// recreate them in order.
+	for key, config := range doc.Config {
+		if !isDynamic(key) {
+			continue
+		}
+		c := Component{Key: key, Config: config}
+		switch kind(key) {
+		case "bthomedevice":
+			doc.BTHomeDevices = append(doc.BTHomeDevices, c)
+		case "bthomesensor":
+			doc.BTHomeSensors = append(doc.BTHomeSensors, c)
+		default:
+			doc.Virtual = append(doc.Virtual, c)
+		}
+	}
+	for _, list := range [][]Component{doc.Virtual, doc.BTHomeDevices, doc.BTHomeSensors} {
+		sortComponents(list)
+	}
+	return nil
+}
+
+// sortComponents orders components by key, with groups last as they refer to other components.
+func sortComponents(list []Component) {
+	sort.Slice(list, func(i, j int) bool {
+		gi, gj := kind(list[i].Key) == "group", kind(list[j].Key) == "group"
+		if gi != gj {
+			return gj
+		}
+		return list[i].Key < list[j].Key
+	})
+}
+
+// call makes req on dev, returning its typed response.
+func call[Req shelly.TypedRequest[Resp], Resp any](
+	ctx context.Context,
+	dev *shelly.Device,
+	req Req,
+) (*Resp, error) {
+	resp := req.NewTypedResponse()
+	_, err := dev.Do(ctx, req, resp)
+	return resp, err
+}
+
+// unsupported is true if err indicates the device doesn't implement a method.
+func unsupported(err error) bool {
+	return errors.Is(err, shelly.ErrRPCNoHandler) || errors.Is(err, shelly.ErrComponentNotPresent)
+}
+
+// kind returns the type of the component with key, ex. "switch" for "switch:0".
+func kind(key string) string {
+	k, _, _ := strings.Cut(key, ":")
+	return k
+}
+
+// componentID returns the id of the component with key, and false for singletons.
+func componentID(key string) (int, bool) {
+	_, id, ok := strings.Cut(key, ":")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(id)
+	return n, err == nil
+}
+
+// isDynamic is true for components created at runtime, which have ids from 200.
+func isDynamic(key string) bool {
+	id, ok := componentID(key)
+	return ok && id >= shelly.MinVirtualID
+}
@@ -0,0 +1,170 @@
+package backup_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/jcodybaker/go-shelly/pkg/backup"
+	"github.com/jcodybaker/go-shelly/pkg/shellysim"
+)
+
+func newDevice(t *testing.T, id string) (*shellysim.Device, *shelly.Device) {
+	specs, err := shelly.AppToDeviceSpecs("Pro4PM", "")
+	require.NoError(t, err)
+	sim := shellysim.New(specs, shellysim.WithModel("Pro4PM", "SPSW-104PE16EU"), shellysim.WithID(id),
+		shellysim.WithPassword("hunter2"))
+	creds := func() (string, string, error) { return shelly.DefaultAuthenticationUsername, "hunter2", nil }
+	return sim, shelly.NewDevice(sim, creds)
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	_, src := newDevice(t, "shellypro4pm-b8d61a000001")
+	dstSim, dst := newDevice(t, "shellypro4pm-b8d61a000002")
+
+	_, err := src.Sys().SetConfig(ctx, &shelly.SysConfig{
+		Device: &shelly.SysDeviceConfig{Name: shelly.Some("garage")},
+	})
+	require.NoError(t, err)
+	_, err = src.Switch(1).SetConfig(ctx, &shelly.SwitchConfig{ID: 1, Name: shelly.Some("door")})
+	require.NoError(t, err)
+
+	// The restored script gets a new id, which the schedule must follow.
+	scratch, err := src.Scripts().Create(ctx, "scratch")
+	require.NoError(t, err)
+	id, err := src.Scripts().Create(ctx, "lights")
+	require.NoError(t, err)
+	require.NoError(t, src.Scripts().Delete(ctx, scratch))
+	require.NoError(t, src.Scripts().PutCode(ctx, id, "print('hello');"))
+	_, err = src.Do(ctx, &shelly.ScriptSetConfigRequest{
+		ID:     id,
+		Config: shelly.ScriptConfig{ID: id, Enable: shelly.BoolPtr(true)},
+	}, &shelly.SetConfigResponse{})
+	require.NoError(t, err)
+
+	start := json.RawMessage(fmt.Sprintf(`{"id":%d}`, id))
+	_, err = src.Do(ctx, &shelly.ScheduleCreateRequest{
+		TimeSpec: shelly.StrPtr("0 0 7 * * *"),
+		Calls:    []shelly.ScheduleCall{{Method: "Script.Start", Params: &start}},
+	}, &shelly.ScheduleCreateResponse{})
+	require.NoError(t, err)
+	_, err = src.Do(ctx, &shelly.WebhookCreateRequest{
+		CID:   shelly.IntPtr(1),
+		Event: shelly.StrPtr("switch.on"),
+		URLs:  []string{"http://example.com/on"},
+	}, &shelly.WebhookCreateResponse{})
+	require.NoError(t, err)
+	_, err = src.Do(ctx, &shelly.KVSSetRequest{Key: "scene", Value: map[string]any{"brightness": 40}},
+		&shelly.KVSSetResponse{})
+	require.NoError(t, err)
+	_, err = src.Do(ctx, &shelly.VirtualAddRequest{
+		Type:   "boolean",
+		ID:     shelly.IntPtr(200),
+		Config: map[string]any{"name": "away"},
+	}, &shelly.VirtualAddResponse{})
+	require.NoError(t, err)
+	_, err = src.Do(ctx, &shelly.BTHomeAddDeviceRequest{
+		ID:     shelly.IntPtr(200),
+		Config: shelly.BTHomeDeviceConfig{Addr: "3c:2e:f5:71:d5:2a"},
+	}, &shelly.BTHomeAddDeviceResponse{})
+	require.NoError(t, err)
+
+	doc, err := backup.Backup(ctx, src)
+	require.NoError(t, err)
+	assert.Equal(t, backup.FormatVersion, doc.Version)
+	assert.Empty(t, doc.Profile)
+	require.Len(t, doc.Scripts, 1)
+	assert.Equal(t, "print('hello');", doc.Scripts[0].Code)
+	assert.Len(t, doc.Schedules, 1)
+	assert.Len(t, doc.Webhooks, 1)
+	assert.Len(t, doc.KVS, 1)
+	assert.Len(t, doc.Virtual, 1)
+	assert.Len(t, doc.BTHomeDevices, 1)
+
+	// Documents are stored as JSON.
+	b, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var stored backup.Document
+	require.NoError(t, json.Unmarshal(b, &stored))
+
+	report, err := backup.Restore(ctx, dst, &stored)
+	require.NoError(t, err)
+	assert.False(t, report.ProfileChanged)
+	assert.Contains(t, report.Skipped, "wifi")
+	assert.Equal(t, map[int]int{id: 1}, report.ScriptIDs)
+
+	sys, ok := dstSim.Config("sys")
+	require.True(t, ok)
+	device := sys["device"].(map[string]any)
+	assert.Equal(t, "garage", device["name"])
+	assert.Equal(t, "B8D61A000002", device["mac"])
+	sw, _ := dstSim.Config("switch:1")
+	assert.Equal(t, "door", sw["name"])
+	away, ok := dstSim.Config("boolean:200")
+	require.True(t, ok)
+	assert.Equal(t, "away", away["name"])
+	_, ok = dstSim.Config("bthomedevice:200")
+	assert.True(t, ok)
+
+	code, err := dst.Scripts().GetCode(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "print('hello');", code)
+	scripts, err := dst.Scripts().List(ctx)
+	require.NoError(t, err)
+	require.Len(t, scripts, 1)
+	assert.True(t, scripts[0].Enable)
+	assert.True(t, scripts[0].Running)
+
+	var jobs shelly.ScheduleListResponse
+	_, err = dst.Do(ctx, &shelly.ScheduleListRequest{}, &jobs)
+	require.NoError(t, err)
+	require.Len(t, jobs.Jobs, 1)
+	assert.JSONEq(t, `{"id":1}`, string(*jobs.Jobs[0].Calls[0].Params))
+	var hooks shelly.WebhookListResponse
+	_, err = dst.Do(ctx, &shelly.WebhookListRequest{}, &hooks)
+	require.NoError(t, err)
+	require.Len(t, hooks.Hooks, 1)
+	assert.Equal(t, []string{"http://example.com/on"}, hooks.Hooks[0].URLs)
+	var kv shelly.KVSGetResponse
+	_, err = dst.Do(ctx, &shelly.KVSGetRequest{Key: "scene"}, &kv)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"brightness":40}`, string(kv.Value))
+
+	// Restoring again converges rather than duplicating.
+	_, err = backup.Restore(ctx, dst, &stored)
+	require.NoError(t, err)
+	_, err = dst.Do(ctx, &shelly.ScheduleListRequest{}, &jobs)
+	require.NoError(t, err)
+	assert.Len(t, jobs.Jobs, 1)
+}
+
+func TestRestoreProfile(t *testing.T) {
+	ctx := context.Background()
+	specs, err := shelly.AppToDeviceSpecs("Plus2PM", "switch")
+	require.NoError(t, err)
+	src := shelly.NewDevice(shellysim.New(specs, shellysim.WithModel("Plus2PM", "SNSW-102P16EU")), nil)
+	dstSim := shellysim.New(specs, shellysim.WithModel("Plus2PM", "SNSW-102P16EU"))
+	dst := shelly.NewDevice(dstSim, nil)
+
+	_, err = src.Do(ctx, &shelly.ShellySetProfileRequest{Profile: "cover"}, &shelly.ShellySetProfileResponse{})
+	require.NoError(t, err)
+	doc, err := backup.Backup(ctx, src)
+	require.NoError(t, err)
+
+	report, err := backup.Restore(ctx, dst, doc, backup.WithoutIdentity())
+	require.NoError(t, err)
+	assert.True(t, report.ProfileChanged)
+	info, err := dst.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "cover", info.Profile)
+
+	doc.Version = backup.FormatVersion + 1
+	_, err = backup.Restore(ctx, dst, doc)
+	assert.ErrorIs(t, err, backup.ErrUnsupportedVersion)
+}
@@ -0,0 +1,382 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+const (
+	// DefaultPollInterval is how often Restore checks whether the device is back after a
+	// profile change reboots it, unless WithPollInterval is given.
+	DefaultPollInterval = 2 * time.Second
+)
+
+// networkTypes are the component types restored only with WithNetwork.
+var networkTypes = map[string]bool{
+	"wifi": true,
+	"eth":  true,
+}
+
+// RestoreOption configures Restore.
+type RestoreOption func(*restorer)
+
+// WithNetwork restores the wifi and eth components. They're skipped by default as a mistake can
+// make the device unreachable, and Shelly.GetConfig doesn't return passwords, so restored
+// networks which require one must be completed with Wifi.SetConfig.
+func WithNetwork() RestoreOption {
+	return func(r *restorer) {
+		r.network = true
+	}
+}
+
+// WithoutIdentity skips fields which identify the device: sys.device.name and the MQTT client
+// id and topic prefix. Use it to copy a configuration to an additional device rather than a
+// replacement. The MAC, firmware and Wi-Fi AP name are never restored.
+func WithoutIdentity() RestoreOption {
+	return func(r *restorer) {
+		r.withoutIdentity = true
+	}
+}
+
+// WithPollInterval sets how often the device is polled while it reboots after a profile change.
+func WithPollInterval(d time.Duration) RestoreOption {
+	return func(r *restorer) {
+		r.pollInterval = d
+	}
+}
+
+// Report describes the outcome of Restore.
+type Report struct {
+	// ProfileChanged is true if the profile was changed, which reboots the device.
+	ProfileChanged bool
+
+	// RestartRequired lists the components (ex. "sys") whose SetConfig reported
+	// restart_required. Changes to them take effect after the device is rebooted.
+	RestartRequired []string
+
+	// Skipped lists the components and sections which weren't restored, ex. "wifi" without
+	// WithNetwork, or "webhooks" if the device doesn't support them.
+	Skipped []string
+
+	// ScriptIDs maps the ids of scripts in the document to their ids on the device. Schedules
+	// which call Script methods are updated to match.
+	ScriptIDs map[int]int
+}
+
+type restorer struct {
+	dev *shelly.Device
+	doc *Document
+
+	network         bool
+	withoutIdentity bool
+	pollInterval    time.Duration
+
+	report *Report
+}
+
+// Restore replays doc onto dev in dependency order: the profile, then component configs
+// (creating missing dynamic components), KVS entries, scripts, schedules and webhooks. Existing
+// schedules and webhooks are replaced; scripts with the same id are overwritten and others are
+// created. The Report lists what requires a restart; Restore doesn't reboot the device.
+func Restore(ctx context.Context, dev *shelly.Device, doc *Document, opts ...RestoreOption) (*Report, error) {
+	if doc.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, doc.Version)
+	}
+	r := &restorer{
+		dev:          dev,
+		doc:          doc,
+		pollInterval: DefaultPollInterval,
+		report:       &Report{ScriptIDs: make(map[int]int)},
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	steps := []struct {
+		name    string
+		restore func(context.Context) error
+	}{
+		{"profile", r.restoreProfile},
+		{"components", r.restoreComponents},
+		{"dynamic components", r.restoreDynamic},
+		{"kvs", r.restoreKVS},
+		{"scripts", r.restoreScripts},
+		{"schedules", r.restoreSchedules},
+		{"webhooks", r.restoreWebhooks},
+	}
+	for _, s := range steps {
+		if err := s.restore(ctx); err != nil {
+			if unsupported(err) {
+				r.report.Skipped = append(r.report.Skipped, s.name)
+				continue
+			}
+			return r.report, fmt.Errorf("restoring %s: %w", s.name, err)
+		}
+	}
+	return r.report, nil
+}
+
+func (r *restorer) restoreProfile(ctx context.Context) error {
+	if r.doc.Profile == "" {
+		return nil
+	}
+	info, err := r.dev.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if info.Profile == r.doc.Profile {
+		return nil
+	}
+	if _, err := call(ctx, r.dev, &shelly.ShellySetProfileRequest{Profile: r.doc.Profile}); err != nil {
+		return err
+	}
+	r.report.ProfileChanged = true
+	// The device reboots into the new profile; wait until it reports it.
+	for {
+		info, err := r.dev.Info(ctx)
+		if err == nil && info.Profile == r.doc.Profile {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for profile %q: %w", r.doc.Profile, ctx.Err())
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+func (r *restorer) restoreComponents(ctx context.Context) error {
+	keys := make([]string, 0, len(r.doc.Config))
+	for key := range r.doc.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		k := kind(key)
+		switch {
+		case k == "script" || isDynamic(key):
+			// Restored with their sections.
+			continue
+		case networkTypes[k] && !r.network:
+			r.report.Skipped = append(r.report.Skipped, key)
+			continue
+		}
+		var config map[string]any
+		if err := json.Unmarshal(r.doc.Config[key], &config); err != nil {
+			return fmt.Errorf("decoding config of %s: %w", key, err)
+		}
+		r.stripIdentity(key, config)
+		if err := r.setConfig(ctx, key, config); err != nil {
+			if unsupported(err) {
+				r.report.Skipped = append(r.report.Skipped, key)
+				continue
+			}
+			return fmt.Errorf("configuring %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// stripIdentity removes the fields of config which are read-only or specific to the device
+// which was backed up.
+func (r *restorer) stripIdentity(key string, config map[string]any) {
+	shelly.StripReadOnlyConfig(key, config)
+	switch kind(key) {
+	case "sys":
+		if dev, ok := config["device"].(map[string]any); ok && r.withoutIdentity {
+			delete(dev, "name")
+		}
+	case "mqtt":
+		for _, field := range []string{"client_id", "topic_prefix"} {
+			// Defaults are the device id; keep the new device's.
+			if v, _ := config[field].(string); r.withoutIdentity || (r.doc.Device != nil && v == r.doc.Device.ID) {
+				delete(config, field)
+			}
+		}
+	}
+}
+
+// setConfig calls the SetConfig method of the component with key, recording whether a restart
+// is required.
+func (r *restorer) setConfig(ctx context.Context, key string, config map[string]any) error {
+	resp, err := call(ctx, r.dev, &shelly.ComponentSetConfigRequest{Key: key, Config: config})
+	if err != nil {
+		return err
+	}
+	if resp.RestartRequired {
+		r.report.RestartRequired = append(r.report.RestartRequired, key)
+	}
+	return nil
+}
+
+func (r *restorer) restoreDynamic(ctx context.Context) error {
+	var components []Component
+	for _, list := range [][]Component{r.doc.Virtual, r.doc.BTHomeDevices, r.doc.BTHomeSensors} {
+		components = append(components, list...)
+	}
+	if len(components) == 0 {
+		return nil
+	}
+	var existing map[string]json.RawMessage
+	if _, err := r.dev.Do(ctx, &shelly.RawRequest{Cmd: "Shelly.GetConfig"}, &existing); err != nil {
+		return err
+	}
+	for _, c := range components {
+		var config map[string]any
+		if err := json.Unmarshal(c.Config, &config); err != nil {
+			return fmt.Errorf("decoding config of %s: %w", c.Key, err)
+		}
+		delete(config, "id")
+		if _, ok := existing[c.Key]; ok {
+			if err := r.setConfig(ctx, c.Key, config); err != nil {
+				return fmt.Errorf("configuring %s: %w", c.Key, err)
+			}
+			continue
+		}
+		if err := r.add(ctx, c.Key, config); err != nil {
+			return fmt.Errorf("adding %s: %w", c.Key, err)
+		}
+	}
+	return nil
+}
+
+// add creates the dynamic component with key.
+func (r *restorer) add(ctx context.Context, key string, config map[string]any) error {
+	id, _ := componentID(key)
+	params := map[string]any{"id": id, "config": config}
+	var method string
+	switch kind(key) {
+	case "bthomedevice":
+		method = "BTHome.AddDevice"
+	case "bthomesensor":
+		method = "BTHome.AddSensor"
+	default:
+		method = "Virtual.Add"
+		params["type"] = kind(key)
+	}
+	args, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	var resp json.RawMessage
+	_, err = r.dev.Do(ctx, &shelly.RawRequest{Cmd: method, Args: args}, &resp)
+	return err
+}
+
+func (r *restorer) restoreKVS(ctx context.Context) error {
+	for _, e := range r.doc.KVS {
+		if _, err := call(ctx, r.dev, &shelly.KVSSetRequest{Key: e.Key, Value: e.Value}); err != nil {
+			return fmt.Errorf("setting %q: %w", e.Key, err)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreScripts(ctx context.Context) error {
+	if len(r.doc.Scripts) == 0 {
+		return nil
+	}
+	scripts := r.dev.Scripts()
+	list, err := scripts.List(ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[int]bool, len(list))
+	for _, s := range list {
+		existing[s.ID] = true
+	}
+	for _, s := range r.doc.Scripts {
+		id := s.ID
+		if existing[id] {
+			if _, err := scripts.Stop(ctx, id); err != nil {
+				return fmt.Errorf("stopping script %d: %w", id, err)
+			}
+		} else if id, err = scripts.Create(ctx, s.Name); err != nil {
+			return fmt.Errorf("creating script %q: %w", s.Name, err)
+		}
+		r.report.ScriptIDs[s.ID] = id
+		if err := scripts.PutCode(ctx, id, s.Code); err != nil {
+			return fmt.Errorf("uploading script %q: %w", s.Name, err)
+		}
+		name, enable := s.Name, s.Enable
+		req := &shelly.ScriptSetConfigRequest{
+			ID:     id,
+			Config: shelly.ScriptConfig{ID: id, Name: &name, Enable: &enable},
+		}
+		if _, err := call(ctx, r.dev, req); err != nil {
+			return fmt.Errorf("configuring script %q: %w", s.Name, err)
+		}
+		if enable {
+			if _, err := scripts.Start(ctx, id); err != nil {
+				return fmt.Errorf("starting script %q: %w", s.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreSchedules(ctx context.Context) error {
+	if _, err := call(ctx, r.dev, &shelly.ScheduleDeleteAllRequest{}); err != nil {
+		return err
+	}
+	for _, s := range r.doc.Schedules {
+		job := shelly.ScheduleCreateRequest(s)
+		job.ID = nil
+		job.Calls = r.remapScriptCalls(s.Calls)
+		if _, err := call(ctx, r.dev, &job); err != nil {
+			return fmt.Errorf("creating schedule: %w", err)
+		}
+	}
+	return nil
+}
+
+// remapScriptCalls returns calls with the ids of Script methods (ex. Script.Start) replaced by
+// the ids of the restored scripts.
+func (r *restorer) remapScriptCalls(calls []shelly.ScheduleCall) []shelly.ScheduleCall {
+	out := make([]shelly.ScheduleCall, len(calls))
+	for i, c := range calls {
+		out[i] = c
+		if !strings.HasPrefix(strings.ToLower(c.Method), "script.") || c.Params == nil {
+			continue
+		}
+		var params map[string]any
+		if err := json.Unmarshal(*c.Params, &params); err != nil {
+			continue
+		}
+		id, ok := params["id"].(float64)
+		if !ok {
+			continue
+		}
+		newID, ok := r.report.ScriptIDs[int(id)]
+		if !ok || newID == int(id) {
+			continue
+		}
+		params["id"] = newID
+		b, err := json.Marshal(params)
+		if err != nil {
+			continue
+		}
+		raw := json.RawMessage(b)
+		out[i].Params = &raw
+	}
+	return out
+}
+
+func (r *restorer) restoreWebhooks(ctx context.Context) error {
+	if _, err := call(ctx, r.dev, &shelly.WebhookDeleteAllRequest{}); err != nil {
+		return err
+	}
+	for _, h := range r.doc.Webhooks {
+		hook := shelly.WebhookCreateRequest(h)
+		hook.ID = nil
+		if _, err := call(ctx, r.dev, &hook); err != nil {
+			return fmt.Errorf("creating webhook: %w", err)
+		}
+	}
+	return nil
+}
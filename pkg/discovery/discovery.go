@@ -0,0 +1,228 @@
+// Package discovery finds Shelly devices on the local network with mDNS (multicast DNS service
+// discovery).
+//
+// Gen2 devices advertise `_shelly._tcp` and `_http._tcp` services, with TXT records describing
+// the generation (`gen`), device model (`app`) and firmware version (`ver`). A Browser queries
+// for those services and streams Events as devices appear, change, and leave the network. The
+// `app` field is resolved to a shelly.DeviceSpecs with shelly.AppToDeviceSpecs.
+//
+// Where multicast is blocked, Sweep probes each address of a subnet with the unauthenticated
+// /shelly endpoint instead.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+const (
+	// ShellyService is the service advertised by Gen2 devices.
+	ShellyService = "_shelly._tcp"
+
+	// HTTPService is the generic web service, which Shelly devices also advertise.
+	HTTPService = "_http._tcp"
+
+	// DefaultQueryInterval is the initial interval between queries. It doubles after each
+	// query up to maxQueryInterval, as recommended by RFC 6762.
+	DefaultQueryInterval = time.Second
+
+	maxQueryInterval = time.Minute
+)
+
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// EventType describes how a device changed.
+type EventType int
+
+const (
+	// EventAdded is sent when a device is first resolved.
+	EventAdded EventType = iota
+
+	// EventUpdated is sent when the address or TXT records of a resolved device change.
+	EventUpdated
+
+	// EventRemoved is sent when a device says goodbye, or its records expire.
+	EventRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventUpdated:
+		return "updated"
+	case EventRemoved:
+		return "removed"
+	}
+	return "EventType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Event is a change to the set of discovered devices.
+type Event struct {
+	Type EventType
+
+	// Device is the device's latest state. For EventRemoved, it's the last state seen.
+	Device *DiscoveredDevice
+}
+
+// DiscoveredDevice is a device resolved from mDNS records.
+type DiscoveredDevice struct {
+	// ID identifies the device, ex. shellypro4pm-f008d1d8b8b8. It's the `id` TXT record if
+	// present, or the lowercased instance name.
+	ID string
+
+	// Instance is the service instance name, ex. ShellyPro4PM-F008D1D8B8B8.
+	Instance string
+
+	// Services are the services which advertised the device, ex. _shelly._tcp.
+	Services []string
+
+	// Host is the device's host name, ex. ShellyPro4PM-F008D1D8B8B8.local.
+	Host string
+
+	// Addrs are the device's addresses, IPv4 first.
+	Addrs []netip.Addr
+
+	// Port is the port of the device's web server.
+	Port int
+
+	// Gen is the device generation from the `gen` TXT record, or 0 if it's not present.
+	Gen int
+
+	// App is the device model from the `app` TXT record, ex. Pro4PM.
+	App string
+
+	// Version is the firmware version from the `ver` TXT record.
+	Version string
+
+	// TXT holds all TXT records, with lowercased keys.
+	TXT map[string]string
+
+	// Specs describes the device's components, or is nil if App is unknown.
+	Specs *shelly.DeviceSpecs
+}
+
+// RPCURL returns the URL of the device's HTTP RPC endpoint, preferring IPv4, or "" if no
+// address is known.
+func (d *DiscoveredDevice) RPCURL() string {
+	if len(d.Addrs) == 0 {
+		return ""
+	}
+	port := d.Port
+	if port == 0 {
+		port = 80
+	}
+	return fmt.Sprintf("http://%s/rpc", netip.AddrPortFrom(d.Addrs[0], uint16(port)))
+}
+
+// Option configures a Browser.
+type Option func(*Browser)
+
+// WithServices sets the services to browse, ex. ShellyService. By default both ShellyService
+// and HTTPService are browsed.
+func WithServices(services ...string) Option {
+	return func(b *Browser) {
+		b.services = services
+	}
+}
+
+// WithInterface sets the network interface to browse on. By default the system chooses.
+func WithInterface(ifi *net.Interface) Option {
+	return func(b *Browser) {
+		b.ifi = ifi
+	}
+}
+
+// WithConn browses with conn, sending queries to dst, rather than joining the mDNS multicast
+// group. The caller retains ownership of conn. This is useful for tests, or unicast queries.
+func WithConn(conn net.PacketConn, dst net.Addr) Option {
+	return func(b *Browser) {
+		b.conn = conn
+		b.dst = dst
+	}
+}
+
+// WithQueryInterval sets the initial interval between queries.
+func WithQueryInterval(d time.Duration) Option {
+	return func(b *Browser) {
+		b.queryInterval = d
+	}
+}
+
+// Browser discovers devices with mDNS.
+type Browser struct {
+	services      []string
+	ifi           *net.Interface
+	conn          net.PacketConn
+	dst           net.Addr
+	queryInterval time.Duration
+}
+
+// NewBrowser returns a Browser configured with opts.
+func NewBrowser(opts ...Option) *Browser {
+	b := &Browser{
+		services:      []string{ShellyService, HTTPService},
+		queryInterval: DefaultQueryInterval,
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+// Browse queries for devices until ctx is done, streaming changes to the returned channel. The
+// channel is closed when browsing stops.
+func (b *Browser) Browse(ctx context.Context) (<-chan Event, error) {
+	conn, dst := b.conn, b.dst
+	owned := conn == nil
+	if owned {
+		mc, err := net.ListenMulticastUDP("udp4", b.ifi, mdnsAddr)
+		if err != nil {
+			return nil, fmt.Errorf("joining mdns multicast group: %w", err)
+		}
+		conn, dst = mc, mdnsAddr
+	}
+	events := make(chan Event, 16)
+	go func() {
+		defer close(events)
+		if owned {
+			defer conn.Close()
+		}
+		b.run(ctx, conn, dst, events)
+	}()
+	return events, nil
+}
+
+// Discover browses until ctx is done, then returns the devices present.
+func Discover(ctx context.Context, opts ...Option) ([]*DiscoveredDevice, error) {
+	events, err := NewBrowser(opts...).Browse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var order []string
+	devices := make(map[string]*DiscoveredDevice)
+	for ev := range events {
+		if _, ok := devices[ev.Device.ID]; !ok && ev.Type != EventRemoved {
+			order = append(order, ev.Device.ID)
+		}
+		if ev.Type == EventRemoved {
+			delete(devices, ev.Device.ID)
+		} else {
+			devices[ev.Device.ID] = ev.Device
+		}
+	}
+	var out []*DiscoveredDevice
+	for _, id := range order {
+		if d, ok := devices[id]; ok {
+			out = append(out, d)
+			delete(devices, id)
+		}
+	}
+	return out, nil
+}
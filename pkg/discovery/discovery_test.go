@@ -0,0 +1,230 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// responder answers mDNS queries on a loopback socket.
+type responder struct {
+	t    *testing.T
+	conn net.PacketConn
+
+	// ptrOnly answers PTR queries without additional records, so browsers must query for them.
+	ptrOnly bool
+
+	mu      sync.Mutex
+	records []dnsmessage.Resource
+}
+
+func newResponder(t *testing.T) *responder {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	r := &responder{t: t, conn: conn}
+	t.Cleanup(func() { conn.Close() })
+	go r.serve()
+	return r
+}
+
+func (r *responder) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf[:n]); err != nil {
+			continue
+		}
+		resp := dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}}
+		r.mu.Lock()
+		for _, q := range query.Questions {
+			for _, rr := range r.records {
+				if !strings.EqualFold(rr.Header.Name.String(), q.Name.String()) || rr.Header.Type != q.Type {
+					continue
+				}
+				resp.Answers = append(resp.Answers, rr)
+				if q.Type == dnsmessage.TypePTR && !r.ptrOnly {
+					resp.Additionals = append(resp.Additionals, r.related(rr.Body.(*dnsmessage.PTRResource).PTR.String())...)
+				}
+			}
+		}
+		r.mu.Unlock()
+		if len(resp.Answers) > 0 {
+			r.send(src, resp)
+		}
+	}
+}
+
+// related returns the SRV, TXT and address records of an instance. r.mu must be held.
+func (r *responder) related(instance string) []dnsmessage.Resource {
+	var out []dnsmessage.Resource
+	var target string
+	for _, rr := range r.records {
+		if rr.Header.Name.String() != instance {
+			continue
+		}
+		out = append(out, rr)
+		if srv, ok := rr.Body.(*dnsmessage.SRVResource); ok {
+			target = srv.Target.String()
+		}
+	}
+	for _, rr := range r.records {
+		if rr.Header.Name.String() == target {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+func (r *responder) send(dst net.Addr, msg dnsmessage.Message) {
+	b, err := msg.Pack()
+	require.NoError(r.t, err)
+	_, err = r.conn.WriteTo(b, dst)
+	require.NoError(r.t, err)
+}
+
+// advertise adds the records of a device instance to each service.
+func (r *responder) advertise(instance, host string, addr netip.Addr, txt []string, services ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hostName := dnsmessage.MustNewName(host)
+	r.records = append(r.records, dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: hostName, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 120},
+		Body:   &dnsmessage.AResource{A: addr.As4()},
+	})
+	for _, s := range services {
+		inst := dnsmessage.MustNewName(instance + "." + s + ".local.")
+		r.records = append(r.records,
+			dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(s + ".local."), Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: 4500},
+				Body:   &dnsmessage.PTRResource{PTR: inst},
+			},
+			dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: inst, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 120},
+				Body:   &dnsmessage.SRVResource{Target: hostName, Port: 80},
+			},
+			dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: inst, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET, TTL: 4500},
+				Body:   &dnsmessage.TXTResource{TXT: txt},
+			},
+		)
+	}
+}
+
+// announce sends the PTR records of an instance and its related records unsolicited, with
+// the given TTL for the PTR records.
+func (r *responder) announce(dst net.Addr, instance string, ttl uint32) {
+	r.mu.Lock()
+	msg := dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}}
+	for _, rr := range r.records {
+		ptr, ok := rr.Body.(*dnsmessage.PTRResource)
+		if !ok || !strings.HasPrefix(ptr.PTR.String(), instance+".") {
+			continue
+		}
+		rr.Header.TTL = ttl
+		msg.Answers = append(msg.Answers, rr)
+		msg.Additionals = append(msg.Additionals, r.related(ptr.PTR.String())...)
+	}
+	r.mu.Unlock()
+	r.send(dst, msg)
+}
+
+// setTXT replaces the TXT records of an instance.
+func (r *responder) setTXT(instance string, txt []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rr := range r.records {
+		if body, ok := rr.Body.(*dnsmessage.TXTResource); ok && strings.HasPrefix(rr.Header.Name.String(), instance+".") {
+			body.TXT = txt
+		}
+	}
+}
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "events closed")
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no discovery event")
+	}
+	return Event{}
+}
+
+func TestBrowse(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResponder(t)
+	txt := []string{"gen=2", "app=Pro4PM", "ver=1.4.4"}
+	r.advertise("ShellyPro4PM-F008D1D8B8B8", "ShellyPro4PM-F008D1D8B8B8.local.",
+		netip.MustParseAddr("192.168.1.23"), txt, ShellyService, HTTPService)
+	r.advertise("Printer", "printer.local.", netip.MustParseAddr("192.168.1.40"), []string{"note=hall"}, HTTPService)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	events, err := NewBrowser(WithConn(conn, r.conn.LocalAddr()), WithQueryInterval(50*time.Millisecond)).Browse(ctx)
+	require.NoError(t, err)
+
+	ev := nextEvent(t, events)
+	assert.Equal(t, EventAdded, ev.Type)
+	d := ev.Device
+	assert.Equal(t, "shellypro4pm-f008d1d8b8b8", d.ID)
+	assert.Equal(t, "ShellyPro4PM-F008D1D8B8B8", d.Instance)
+	assert.Equal(t, []string{HTTPService, ShellyService}, d.Services)
+	assert.Equal(t, 2, d.Gen)
+	assert.Equal(t, "Pro4PM", d.App)
+	assert.Equal(t, "1.4.4", d.Version)
+	require.NotNil(t, d.Specs)
+	assert.Equal(t, 4, d.Specs.Switches)
+	assert.Equal(t, "http://192.168.1.23:80/rpc", d.RPCURL())
+
+	r.setTXT("ShellyPro4PM-F008D1D8B8B8", []string{"gen=2", "app=Pro4PM", "ver=1.5.0"})
+	r.announce(conn.LocalAddr(), "ShellyPro4PM-F008D1D8B8B8", 4500)
+	ev = nextEvent(t, events)
+	assert.Equal(t, EventUpdated, ev.Type)
+	assert.Equal(t, "1.5.0", ev.Device.Version)
+
+	r.announce(conn.LocalAddr(), "ShellyPro4PM-F008D1D8B8B8", 0)
+	ev = nextEvent(t, events)
+	assert.Equal(t, EventRemoved, ev.Type)
+	assert.Equal(t, "shellypro4pm-f008d1d8b8b8", ev.Device.ID)
+
+	cancel()
+	for ev := range events {
+		// Repeated queries may rediscover the device before the browser stops.
+		assert.Equal(t, "shellypro4pm-f008d1d8b8b8", ev.Device.ID)
+	}
+}
+
+func TestDiscoverQueriesMissingRecords(t *testing.T) {
+	r := newResponder(t)
+	r.ptrOnly = true
+	r.advertise("shellyplus1-a8032ab12345", "shellyplus1-a8032ab12345.local.",
+		netip.MustParseAddr("192.168.1.24"), []string{"gen=2", "app=Plus1", "ver=1.4.4"}, ShellyService)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	devices, err := Discover(ctx, WithConn(conn, r.conn.LocalAddr()), WithServices(ShellyService))
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "shellyplus1-a8032ab12345", devices[0].ID)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("192.168.1.24")}, devices[0].Addrs)
+	require.NotNil(t, devices[0].Specs)
+	assert.Equal(t, 1, devices[0].Specs.Switches)
+}
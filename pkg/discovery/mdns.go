@@ -0,0 +1,412 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// expiryInterval is how often expired records are removed.
+	expiryInterval = time.Second
+
+	// requeryInterval limits how often missing records for a name are queried.
+	requeryInterval = time.Second
+)
+
+// run browses until ctx is done or conn fails.
+func (b *Browser) run(ctx context.Context, conn net.PacketConn, dst net.Addr, events chan<- Event) {
+	packets := make(chan []byte)
+	go func() {
+		defer close(packets)
+		buf := make([]byte, 9000)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			select {
+			case packets <- append([]byte(nil), buf[:n]...):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	defer func() {
+		// Unblock the reader; an owned conn is also closed by the caller.
+		conn.SetReadDeadline(time.Now())
+		for range packets {
+		}
+	}()
+
+	c := newCache(b.services)
+	var services []string
+	for s := range c.services {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	send := func(questions []dnsmessage.Question) {
+		if msg, err := buildQuery(questions); err == nil {
+			conn.WriteTo(msg, dst)
+		}
+	}
+	send(ptrQuestions(services))
+	interval := b.queryInterval
+	query := time.NewTimer(interval)
+	defer query.Stop()
+	expire := time.NewTicker(expiryInterval)
+	defer expire.Stop()
+
+	emit := func(changes []Event) bool {
+		for _, ev := range changes {
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p, ok := <-packets:
+			if !ok {
+				return
+			}
+			var msg dnsmessage.Message
+			if err := msg.Unpack(p); err != nil || !msg.Header.Response {
+				continue
+			}
+			now := time.Now()
+			c.update(&msg, now)
+			if q := c.missing(now); len(q) > 0 {
+				send(q)
+			}
+			if !emit(c.resolve(now)) {
+				return
+			}
+		case <-query.C:
+			send(ptrQuestions(services))
+			interval = min(interval*2, maxQueryInterval)
+			query.Reset(interval)
+		case <-expire.C:
+			if !emit(c.resolve(time.Now())) {
+				return
+			}
+		}
+	}
+}
+
+func ptrQuestions(services []string) []dnsmessage.Question {
+	var q []dnsmessage.Question
+	for _, s := range services {
+		if name, err := dnsmessage.NewName(s); err == nil {
+			q = append(q, dnsmessage.Question{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+		}
+	}
+	return q
+}
+
+func buildQuery(questions []dnsmessage.Question) ([]byte, error) {
+	msg := dnsmessage.Message{Questions: questions}
+	return msg.Pack()
+}
+
+// cache holds unexpired mDNS records and the devices last resolved from them. Names are
+// lowercased fully-qualified names.
+type cache struct {
+	// services are the browsed services, ex. _shelly._tcp.local.
+	services map[string]bool
+
+	// pointers maps service instances to the PTR records naming them.
+	pointers map[string]ptrRecord
+	srvs     map[string]srvRecord
+	txts     map[string]txtRecord
+	addrs    map[string]map[netip.Addr]time.Time
+
+	asked   map[string]time.Time
+	devices map[string]*DiscoveredDevice
+}
+
+type ptrRecord struct {
+	// instance is the instance name as advertised, ex. ShellyPro4PM-F008D1D8B8B8.
+	instance string
+	expiry   time.Time
+}
+
+type srvRecord struct {
+	// host is the target as advertised, and target is its lowercased form.
+	host   string
+	target string
+	port   int
+	expiry time.Time
+}
+
+type txtRecord struct {
+	txt    map[string]string
+	expiry time.Time
+}
+
+func newCache(services []string) *cache {
+	c := &cache{
+		services: make(map[string]bool),
+		pointers: make(map[string]ptrRecord),
+		srvs:     make(map[string]srvRecord),
+		txts:     make(map[string]txtRecord),
+		addrs:    make(map[string]map[netip.Addr]time.Time),
+		asked:    make(map[string]time.Time),
+		devices:  make(map[string]*DiscoveredDevice),
+	}
+	for _, s := range services {
+		c.services[fqdn(s+".local")] = true
+	}
+	return c
+}
+
+// fqdn lowercases name and adds the trailing dot.
+func fqdn(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// update stores the records in msg. Records with a TTL of 0 are goodbyes, and are removed.
+func (c *cache) update(msg *dnsmessage.Message, now time.Time) {
+	records := append(append(append([]dnsmessage.Resource(nil), msg.Answers...), msg.Authorities...), msg.Additionals...)
+	for _, r := range records {
+		name := fqdn(r.Header.Name.String())
+		expiry := now.Add(time.Duration(r.Header.TTL) * time.Second)
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if !c.services[name] {
+				continue
+			}
+			inst := body.PTR.String()
+			label, _ := splitInstance(inst)
+			c.pointers[fqdn(inst)] = ptrRecord{instance: label, expiry: expiry}
+		case *dnsmessage.SRVResource:
+			if !c.isInstance(name) {
+				continue
+			}
+			host := body.Target.String()
+			c.srvs[name] = srvRecord{host: host, target: fqdn(host), port: int(body.Port), expiry: expiry}
+		case *dnsmessage.TXTResource:
+			if !c.isInstance(name) {
+				continue
+			}
+			c.txts[name] = txtRecord{txt: parseTXT(body.TXT), expiry: expiry}
+		case *dnsmessage.AResource:
+			c.addAddr(name, netip.AddrFrom4(body.A), expiry)
+		case *dnsmessage.AAAAResource:
+			c.addAddr(name, netip.AddrFrom16(body.AAAA), expiry)
+		}
+	}
+}
+
+func (c *cache) addAddr(host string, addr netip.Addr, expiry time.Time) {
+	if c.addrs[host] == nil {
+		c.addrs[host] = make(map[netip.Addr]time.Time)
+	}
+	c.addrs[host][addr] = expiry
+}
+
+// isInstance returns true if name is an instance of a browsed service.
+func (c *cache) isInstance(name string) bool {
+	_, service := splitInstance(name)
+	return c.services[service]
+}
+
+// splitInstance splits a service instance name into the instance and service, ex.
+// "shellypro4pm-f008d1d8b8b8._shelly._tcp.local." into "shellypro4pm-f008d1d8b8b8" and
+// "_shelly._tcp.local.".
+func splitInstance(name string) (string, string) {
+	i := strings.Index(name, "._")
+	if i < 0 {
+		return name, ""
+	}
+	return name[:i], name[i+1:]
+}
+
+func parseTXT(records []string) map[string]string {
+	txt := make(map[string]string, len(records))
+	for _, r := range records {
+		k, v, _ := strings.Cut(r, "=")
+		txt[strings.ToLower(k)] = v
+	}
+	return txt
+}
+
+// missing returns queries for the SRV, TXT and address records of instances which can't yet
+// be resolved.
+func (c *cache) missing(now time.Time) []dnsmessage.Question {
+	var q []dnsmessage.Question
+	ask := func(name string, t dnsmessage.Type) {
+		key := name + "/" + t.String()
+		if now.Sub(c.asked[key]) < requeryInterval {
+			return
+		}
+		if n, err := dnsmessage.NewName(name); err == nil {
+			c.asked[key] = now
+			q = append(q, dnsmessage.Question{Name: n, Type: t, Class: dnsmessage.ClassINET})
+		}
+	}
+	for _, inst := range sortedKeys(c.pointers) {
+		if !c.pointers[inst].expiry.After(now) {
+			continue
+		}
+		srv, ok := c.srvs[inst]
+		if !ok {
+			ask(inst, dnsmessage.TypeSRV)
+		} else if len(c.addrs[srv.target]) == 0 {
+			ask(srv.target, dnsmessage.TypeA)
+		}
+		if _, ok := c.txts[inst]; !ok {
+			ask(inst, dnsmessage.TypeTXT)
+		}
+	}
+	return q
+}
+
+// resolve drops expired records, then returns events describing the differences between the
+// devices which can now be resolved and those resolved previously.
+func (c *cache) resolve(now time.Time) []Event {
+	c.expire(now)
+	devices := make(map[string]*DiscoveredDevice)
+	for _, inst := range sortedKeys(c.pointers) {
+		srv, hasSRV := c.srvs[inst]
+		txt, hasTXT := c.txts[inst]
+		if !hasSRV || !hasTXT || len(c.addrs[srv.target]) == 0 {
+			continue
+		}
+		_, service := splitInstance(inst)
+		d := c.device(inst, c.pointers[inst].instance, srv, txt.txt)
+		if d == nil {
+			continue
+		}
+		service = strings.TrimSuffix(service, ".local.")
+		if prev, ok := devices[d.ID]; ok {
+			// The same device advertised with another service.
+			prev.Services = append(prev.Services, service)
+			continue
+		}
+		d.Services = []string{service}
+		devices[d.ID] = d
+	}
+
+	var events []Event
+	for _, id := range sortedKeys(devices) {
+		d := devices[id]
+		sort.Strings(d.Services)
+		prev, ok := c.devices[id]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventAdded, Device: d})
+		case !reflect.DeepEqual(prev, d):
+			events = append(events, Event{Type: EventUpdated, Device: d})
+		default:
+			continue
+		}
+		c.devices[id] = d
+	}
+	for _, id := range sortedKeys(c.devices) {
+		if _, ok := devices[id]; !ok {
+			events = append(events, Event{Type: EventRemoved, Device: c.devices[id]})
+			delete(c.devices, id)
+		}
+	}
+	return events
+}
+
+// device builds a device from an instance's records, or returns nil if it isn't a Shelly.
+func (c *cache) device(inst, instance string, srv srvRecord, txt map[string]string) *DiscoveredDevice {
+	label, service := splitInstance(inst)
+	_, hasGen := txt["gen"]
+	_, hasApp := txt["app"]
+	if service != fqdn(ShellyService+".local") && !hasGen && !hasApp && !strings.HasPrefix(label, "shelly") {
+		return nil
+	}
+	d := &DiscoveredDevice{
+		ID:       strings.ToLower(txt["id"]),
+		Instance: instance,
+		Host:     srv.host,
+		Port:     srv.port,
+		App:      txt["app"],
+		Version:  txt["ver"],
+		TXT:      txt,
+	}
+	if d.ID == "" {
+		d.ID = label
+	}
+	if gen, err := strconv.Atoi(txt["gen"]); err == nil {
+		d.Gen = gen
+	}
+	if d.App != "" {
+		if specs, err := shelly.AppToDeviceSpecs(d.App, ""); err == nil {
+			d.Specs = &specs
+		}
+	}
+	for addr := range c.addrs[srv.target] {
+		d.Addrs = append(d.Addrs, addr)
+	}
+	sort.Slice(d.Addrs, func(i, j int) bool {
+		if d.Addrs[i].Is4() != d.Addrs[j].Is4() {
+			return d.Addrs[i].Is4()
+		}
+		return d.Addrs[i].Less(d.Addrs[j])
+	})
+	return d
+}
+
+func (c *cache) expire(now time.Time) {
+	for k, r := range c.pointers {
+		if !r.expiry.After(now) {
+			delete(c.pointers, k)
+		}
+	}
+	for k, r := range c.srvs {
+		if !r.expiry.After(now) {
+			delete(c.srvs, k)
+		}
+	}
+	for k, r := range c.txts {
+		if !r.expiry.After(now) {
+			delete(c.txts, k)
+		}
+	}
+	for host, addrs := range c.addrs {
+		for addr, expiry := range addrs {
+			if !expiry.After(now) {
+				delete(addrs, addr)
+			}
+		}
+		if len(addrs) == 0 {
+			delete(c.addrs, host)
+		}
+	}
+	for k, t := range c.asked {
+		if now.Sub(t) >= requeryInterval {
+			delete(c.asked, k)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
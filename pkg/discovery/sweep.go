@@ -0,0 +1,296 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/jcodybaker/go-shelly/pkg/gen1"
+)
+
+const (
+	// DefaultSweepConcurrency is the default number of concurrent probes.
+	DefaultSweepConcurrency = 32
+
+	// DefaultSweepRate is the default maximum number of probes started per second.
+	DefaultSweepRate = 100
+
+	// DefaultProbeTimeout is the default timeout of each probe.
+	DefaultProbeTimeout = 2 * time.Second
+
+	// maxSweepAddrs limits the size of a swept prefix.
+	maxSweepAddrs = 1 << 16
+
+	// maxInfoSize limits the size of a /shelly response.
+	maxInfoSize = 64 << 10
+)
+
+var (
+	// ErrPrefixTooLarge is returned when sweeping a prefix with more than 65536 addresses.
+	ErrPrefixTooLarge = errors.New("prefix too large to sweep")
+)
+
+// SweptDevice is a device found by Sweep.
+type SweptDevice struct {
+	// MAC is the device's MAC address, uppercase without separators, ex. F008D1D8B8B8.
+	MAC string
+
+	// Addrs are the addresses where the device answered, ex. its Wifi and Ethernet addresses.
+	Addrs []netip.Addr
+
+	// Port is the port probed.
+	Port int
+
+	// Gen is the device generation. Gen1 devices don't report one, and are classified by the
+	// `type` field of their response.
+	Gen int
+
+	// AuthEnabled is true if the device requires authentication.
+	AuthEnabled bool
+
+	// Info is the decoded /shelly response. For Gen1 devices, Model, MAC, Ver and AuthEn are
+	// filled from the `type`, `mac`, `fw` and `auth` fields.
+	Info *shelly.ShellyGetDeviceInfoResponse
+
+	// Specs describes the device's components, or is nil if its app or profile is unknown.
+	Specs *shelly.DeviceSpecs
+}
+
+// RPCURL returns the URL of the device's HTTP RPC endpoint. Gen1 devices don't have one.
+func (d *SweptDevice) RPCURL() string {
+	if len(d.Addrs) == 0 || d.Gen < 2 {
+		return ""
+	}
+	return fmt.Sprintf("http://%s/rpc", netip.AddrPortFrom(d.Addrs[0], uint16(d.Port)))
+}
+
+// Inventory holds swept devices keyed by MAC.
+type Inventory map[string]*SweptDevice
+
+// Devices returns the devices sorted by MAC.
+func (inv Inventory) Devices() []*SweptDevice {
+	out := make([]*SweptDevice, 0, len(inv))
+	for _, mac := range sortedKeys(inv) {
+		out = append(out, inv[mac])
+	}
+	return out
+}
+
+// SweepOption configures Sweep.
+type SweepOption func(*sweeper)
+
+// WithSweepConcurrency sets the maximum number of concurrent probes.
+func WithSweepConcurrency(n int) SweepOption {
+	return func(s *sweeper) {
+		s.concurrency = n
+	}
+}
+
+// WithSweepRate sets the maximum number of probes started per second. Zero is unlimited.
+func WithSweepRate(perSecond float64) SweepOption {
+	return func(s *sweeper) {
+		s.rate = perSecond
+	}
+}
+
+// WithProbeTimeout sets the timeout of each probe.
+func WithProbeTimeout(d time.Duration) SweepOption {
+	return func(s *sweeper) {
+		s.timeout = d
+	}
+}
+
+// WithHTTPClient sets the client used for probes.
+func WithHTTPClient(c *http.Client) SweepOption {
+	return func(s *sweeper) {
+		s.client = c
+	}
+}
+
+// WithPort sets the port probed. The default is 80.
+func WithPort(port int) SweepOption {
+	return func(s *sweeper) {
+		s.port = port
+	}
+}
+
+type sweeper struct {
+	concurrency int
+	rate        float64
+	timeout     time.Duration
+	client      *http.Client
+	port        int
+}
+
+// Sweep probes `http://<addr>/shelly` for each address in prefix, excluding the network and
+// broadcast addresses of IPv4 prefixes. The unauthenticated /shelly endpoint identifies both
+// Gen1 and Gen2 devices. Devices answering at several addresses are reported once. Addresses
+// which don't answer, or answer with something other than a Shelly device, are skipped. If ctx
+// is done before the sweep completes, the devices found so far are returned with ctx's error.
+func Sweep(ctx context.Context, prefix netip.Prefix, opts ...SweepOption) (Inventory, error) {
+	s := &sweeper{
+		concurrency: DefaultSweepConcurrency,
+		rate:        DefaultSweepRate,
+		timeout:     DefaultProbeTimeout,
+		client:      http.DefaultClient,
+		port:        80,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	addrs, err := sweepAddrs(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var tick <-chan time.Time
+	if s.rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / s.rate))
+		defer t.Stop()
+		tick = t.C
+	}
+	var mu sync.Mutex
+	inv := make(Inventory)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(s.concurrency, 1))
+probes:
+	for i, addr := range addrs {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				break probes
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break probes
+		}
+		wg.Add(1)
+		go func(addr netip.Addr) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d := s.probe(ctx, addr)
+			if d == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if prev, ok := inv[d.MAC]; ok {
+				prev.Addrs = append(prev.Addrs, addr)
+				sort.Slice(prev.Addrs, func(i, j int) bool { return prev.Addrs[i].Less(prev.Addrs[j]) })
+				return
+			}
+			inv[d.MAC] = d
+		}(addr)
+	}
+	wg.Wait()
+	return inv, ctx.Err()
+}
+
+// sweepAddrs lists the host addresses of prefix.
+func sweepAddrs(prefix netip.Prefix) ([]netip.Addr, error) {
+	if !prefix.IsValid() {
+		return nil, fmt.Errorf("invalid prefix %q", prefix)
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("%w: %s", ErrPrefixTooLarge, prefix)
+	}
+	var addrs []netip.Addr
+	for a := prefix.Addr(); a.IsValid() && prefix.Contains(a) && len(addrs) < maxSweepAddrs; a = a.Next() {
+		addrs = append(addrs, a)
+	}
+	if prefix.Addr().Is4() && hostBits >= 2 {
+		// Skip the network and broadcast addresses.
+		addrs = addrs[1 : len(addrs)-1]
+	}
+	return addrs, nil
+}
+
+// probe identifies the device at addr, or returns nil if it isn't a Shelly.
+func (s *sweeper) probe(ctx context.Context, addr netip.Addr) *SweptDevice {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	u := "http://" + net.JoinHostPort(addr.String(), strconv.Itoa(s.port)) + "/shelly"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxInfoSize))
+	if err != nil {
+		return nil
+	}
+	return classify(body, addr, s.port)
+}
+
+// classify decodes a /shelly response, returning nil if it isn't from a Shelly.
+func classify(body []byte, addr netip.Addr, port int) *SweptDevice {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+	d := &SweptDevice{Addrs: []netip.Addr{addr}, Port: port}
+	switch {
+	case fields["gen"] != nil:
+		var info shelly.ShellyGetDeviceInfoResponse
+		if err := json.Unmarshal(body, &info); err != nil {
+			return nil
+		}
+		gen, err := info.Gen.Int64()
+		if err != nil {
+			return nil
+		}
+		d.Gen = int(gen)
+		d.Info = &info
+		if specs, err := shelly.AppToDeviceSpecs(info.App, info.Profile); err == nil {
+			d.Specs = &specs
+		}
+	case fields["type"] != nil:
+		var info gen1.ShellyInfo
+		if err := json.Unmarshal(body, &info); err != nil {
+			return nil
+		}
+		d.Gen = 1
+		d.Info = &shelly.ShellyGetDeviceInfoResponse{
+			MAC:    info.MAC,
+			Model:  info.Type,
+			Gen:    "1",
+			Ver:    info.FW,
+			AuthEn: info.Auth,
+		}
+	default:
+		return nil
+	}
+	d.MAC = normalizeMAC(d.Info.MAC)
+	if d.MAC == "" {
+		return nil
+	}
+	d.AuthEnabled = d.Info.AuthEn
+	return d
+}
+
+func normalizeMAC(mac string) string {
+	return strings.ToUpper(strings.NewReplacer(":", "", "-", "").Replace(mac))
+}
@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveLoopback serves body from /shelly at each loopback address, on a shared port.
+func serveLoopback(t *testing.T, bodies map[string]string) int {
+	var port int
+	for _, ip := range sortedKeys(bodies) {
+		l, err := net.Listen("tcp4", net.JoinHostPort(ip, strconv.Itoa(port)))
+		require.NoError(t, err)
+		port = l.Addr().(*net.TCPAddr).Port
+		body := bodies[ip]
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/shelly" || body == "" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(body))
+		})}
+		go srv.Serve(l)
+		t.Cleanup(func() { srv.Close() })
+	}
+	return port
+}
+
+func TestSweep(t *testing.T) {
+	gen2 := `{"name":null,"id":"shellypro4pm-f008d1d8b8b8","mac":"F008D1D8B8B8","slot":0,"model":"SPSW-104PE16EU","gen":2,"fw_id":"20241011-114455/1.4.4-g6d2a586","ver":"1.4.4","app":"Pro4PM","auth_en":true,"auth_domain":"shellypro4pm-f008d1d8b8b8"}`
+	port := serveLoopback(t, map[string]string{
+		"127.0.0.2": gen2,
+		"127.0.0.3": `{"type":"SHSW-1","mac":"a4:cf:12:34:56:78","auth":false,"fw":"20230913-112003/v1.14.0-gcb84623","num_outputs":1}`,
+		"127.0.0.4": gen2,
+		"127.0.0.5": `{"hello":"world"}`,
+		"127.0.0.6": "",
+	})
+
+	inv, err := Sweep(context.Background(), netip.MustParsePrefix("127.0.0.0/29"),
+		WithPort(port), WithSweepRate(0), WithProbeTimeout(time.Second))
+	require.NoError(t, err)
+	require.Len(t, inv, 2)
+	devices := inv.Devices()
+
+	gen1 := devices[0]
+	assert.Equal(t, "A4CF12345678", gen1.MAC)
+	assert.Equal(t, 1, gen1.Gen)
+	assert.Equal(t, "SHSW-1", gen1.Info.Model)
+	assert.False(t, gen1.AuthEnabled)
+	assert.Empty(t, gen1.RPCURL())
+
+	pro := devices[1]
+	assert.Equal(t, "F008D1D8B8B8", pro.MAC)
+	assert.Equal(t, 2, pro.Gen)
+	assert.True(t, pro.AuthEnabled)
+	assert.Equal(t, "shellypro4pm-f008d1d8b8b8", pro.Info.ID)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("127.0.0.2"), netip.MustParseAddr("127.0.0.4")}, pro.Addrs)
+	require.NotNil(t, pro.Specs)
+	assert.Equal(t, 4, pro.Specs.Switches)
+	assert.Equal(t, "http://127.0.0.2:"+strconv.Itoa(port)+"/rpc", pro.RPCURL())
+}
+
+func TestSweepAddrs(t *testing.T) {
+	addrs, err := sweepAddrs(netip.MustParsePrefix("192.168.1.7/30"))
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("192.168.1.5"), netip.MustParseAddr("192.168.1.6")}, addrs)
+
+	addrs, err = sweepAddrs(netip.MustParsePrefix("192.168.1.7/32"))
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("192.168.1.7")}, addrs)
+
+	_, err = sweepAddrs(netip.MustParsePrefix("10.0.0.0/8"))
+	assert.ErrorIs(t, err, ErrPrefixTooLarge)
+}
@@ -0,0 +1,223 @@
+// Package fwmirror serves Shelly firmware bundles from a local directory so devices can be
+// updated via Shelly.Update without access to the internet.
+//
+// Bundles are discovered from a directory tree laid out as <root>/<model>/<version>/<bundle>.zip.
+// Each bundle must be accompanied by a <bundle>.zip.sha256 file containing the hex encoded
+// SHA-256 of the bundle (the output of `sha256sum` is accepted). The model directory may be
+// either the device `app` (ex. Pro4PM) or `model` (ex. SPSW-104PE16EU) as reported by
+// Shelly.GetDeviceInfo.
+package fwmirror
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ChecksumSuffix is the suffix of the file containing the SHA-256 of a bundle.
+	ChecksumSuffix = ".sha256"
+
+	bundleSuffix = ".zip"
+)
+
+var (
+	// ErrUnknownModel is returned when no bundles are indexed for the requested model.
+	ErrUnknownModel = errors.New("no firmware bundles for model")
+
+	// ErrUnknownVersion is returned when the requested version is not indexed for the model.
+	ErrUnknownVersion = errors.New("no firmware bundle for version")
+
+	// ErrChecksumMismatch is returned when a bundle does not match its recorded checksum.
+	ErrChecksumMismatch = errors.New("firmware bundle checksum mismatch")
+)
+
+// Bundle describes a single firmware image available from the mirror.
+type Bundle struct {
+	// Model is the name of the model directory containing the bundle.
+	Model string
+
+	// Version is the firmware version, taken from the version directory.
+	Version string
+
+	// Path is the location of the bundle on the local filesystem.
+	Path string
+
+	// SHA256 is the expected hex encoded SHA-256 of the bundle.
+	SHA256 string
+}
+
+// FileName returns the base name of the bundle file.
+func (b *Bundle) FileName() string {
+	return filepath.Base(b.Path)
+}
+
+// Index is an immutable catalog of the firmware bundles found in a directory.
+type Index struct {
+	bundles map[string]map[string]*Bundle
+}
+
+// LoadIndex scans dir for firmware bundles. Bundles without a checksum file are skipped.
+func LoadIndex(dir string) (*Index, error) {
+	idx := &Index{bundles: make(map[string]map[string]*Bundle)}
+	models, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading firmware directory: %w", err)
+	}
+	for _, m := range models {
+		if !m.IsDir() {
+			continue
+		}
+		versions, err := os.ReadDir(filepath.Join(dir, m.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading model directory %q: %w", m.Name(), err)
+		}
+		for _, v := range versions {
+			if !v.IsDir() {
+				continue
+			}
+			b, err := loadBundle(filepath.Join(dir, m.Name(), v.Name()), m.Name(), v.Name())
+			if err != nil {
+				return nil, err
+			}
+			if b == nil {
+				continue
+			}
+			if idx.bundles[b.Model] == nil {
+				idx.bundles[b.Model] = make(map[string]*Bundle)
+			}
+			idx.bundles[b.Model][b.Version] = b
+		}
+	}
+	return idx, nil
+}
+
+func loadBundle(dir, model, version string) (*Bundle, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading version directory %q: %w", dir, err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), bundleSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		sum, err := readChecksum(path + ChecksumSuffix)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return &Bundle{
+			Model:   model,
+			Version: version,
+			Path:    path,
+			SHA256:  sum,
+		}, nil
+	}
+	return nil, nil
+}
+
+func readChecksum(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Accept both a bare checksum and `sha256sum` output ("<sum>  <filename>").
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 || len(fields[0]) != 64 {
+		return "", fmt.Errorf("invalid checksum file %q", path)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// Models returns the sorted list of indexed models.
+func (i *Index) Models() []string {
+	out := make([]string, 0, len(i.bundles))
+	for m := range i.bundles {
+		out = append(out, m)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Versions returns the versions indexed for a model, from oldest to newest.
+func (i *Index) Versions(model string) []string {
+	out := make([]string, 0, len(i.bundles[model]))
+	for v := range i.bundles[model] {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(a, b int) bool {
+		return CompareVersions(out[a], out[b]) < 0
+	})
+	return out
+}
+
+// Lookup returns the bundle for the model and version.
+func (i *Index) Lookup(model, version string) (*Bundle, error) {
+	versions, ok := i.bundles[model]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownModel, model)
+	}
+	b, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %q", ErrUnknownVersion, model, version)
+	}
+	return b, nil
+}
+
+// Latest returns the newest bundle for the model. Pre-release versions (ex. 1.5.0-beta1) are
+// only considered if includePreRelease is true.
+func (i *Index) Latest(model string, includePreRelease bool) (*Bundle, error) {
+	if _, ok := i.bundles[model]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownModel, model)
+	}
+	versions := i.Versions(model)
+	for j := len(versions) - 1; j >= 0; j-- {
+		if !includePreRelease && strings.Contains(versions[j], "-") {
+			continue
+		}
+		return i.bundles[model][versions[j]], nil
+	}
+	return nil, fmt.Errorf("%w: %s latest", ErrUnknownVersion, model)
+}
+
+// CompareVersions compares two firmware versions of the form 1.4.4 or 1.5.0-beta1, returning
+// -1, 0, or 1. Pre-release versions sort before the release they precede.
+func CompareVersions(a, b string) int {
+	aRel, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bRel, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	aParts := strings.Split(aRel, ".")
+	bParts := strings.Split(bRel, ".")
+	for j := 0; j < len(aParts) || j < len(bParts); j++ {
+		var an, bn int
+		if j < len(aParts) {
+			an, _ = strconv.Atoi(aParts[j])
+		}
+		if j < len(bParts) {
+			bn, _ = strconv.Atoi(bParts[j])
+		}
+		if an != bn {
+			if an < bn {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
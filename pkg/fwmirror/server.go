@@ -0,0 +1,181 @@
+package fwmirror
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// Server is an http.Handler which serves indexed firmware bundles at
+// <BaseURL>/<model>/<version>/<bundle>.zip. Each bundle is read into memory and verified against
+// its checksum on every request, and the verified bytes are served; bundles which fail
+// verification are never sent to a device, even if they're replaced while being served.
+type Server struct {
+	dir     string
+	baseURL *url.URL
+
+	mu    sync.RWMutex
+	index *Index
+}
+
+// NewServer indexes dir and returns a Server. baseURL is the externally reachable URL at which
+// the Server is mounted (ex. http://192.168.1.10:8080/firmware); it is used to build the URLs
+// handed to devices.
+func NewServer(dir, baseURL string) (*Server, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing base url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("base url %q must be absolute", baseURL)
+	}
+	s := &Server{
+		dir:     dir,
+		baseURL: u,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload rescans the firmware directory.
+func (s *Server) Reload() error {
+	idx, err := LoadIndex(s.dir)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index = idx
+	return nil
+}
+
+// Index returns the current index of bundles.
+func (s *Server) Index() *Index {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index
+}
+
+// URL returns the URL a device should use to download the bundle for model and version.
+func (s *Server) URL(model, version string) (string, error) {
+	b, err := s.Index().Lookup(model, version)
+	if err != nil {
+		return "", err
+	}
+	return s.bundleURL(b), nil
+}
+
+func (s *Server) bundleURL(b *Bundle) string {
+	return s.baseURL.JoinPath(b.Model, b.Version, b.FileName()).String()
+}
+
+// UpdateRequest builds a Shelly.Update request directing the device to install the given
+// version from the mirror. The bundle is verified before the request is returned.
+func (s *Server) UpdateRequest(model, version string) (*shelly.ShellyUpdateRequest, error) {
+	b, err := s.Index().Lookup(model, version)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := s.read(b); err != nil {
+		return nil, err
+	}
+	return &shelly.ShellyUpdateRequest{URL: s.bundleURL(b)}, nil
+}
+
+// UpdateRequestForDevice builds a Shelly.Update request for the device described by info,
+// matching bundles first by `app` and then by `model`. If version is empty the newest stable
+// version is selected.
+func (s *Server) UpdateRequestForDevice(
+	info *shelly.ShellyGetDeviceInfoResponse,
+	version string,
+) (*shelly.ShellyUpdateRequest, error) {
+	idx := s.Index()
+	var lastErr error
+	for _, model := range []string{info.App, info.Model} {
+		if model == "" {
+			continue
+		}
+		v := version
+		if v == "" {
+			b, err := idx.Latest(model, false)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			v = b.Version
+		}
+		req, err := s.UpdateRequest(model, v)
+		if errors.Is(err, ErrUnknownModel) {
+			lastErr = err
+			continue
+		}
+		return req, err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("%w: device reports neither app nor model", ErrUnknownModel)
+	}
+	return nil, lastErr
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rel := strings.TrimPrefix(path.Clean(r.URL.Path), path.Clean("/"+s.baseURL.Path))
+	parts := strings.Split(strings.Trim(rel, "/"), "/")
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+	b, err := s.Index().Lookup(parts[0], parts[1])
+	if err != nil || b.FileName() != parts[2] {
+		http.NotFound(w, r)
+		return
+	}
+	data, modTime, err := s.read(b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	http.ServeContent(w, r, b.FileName(), modTime, bytes.NewReader(data))
+}
+
+// read reads the bundle and confirms it matches its recorded checksum, returning its contents
+// and modification time.
+func (s *Server) read(b *Bundle) ([]byte, time.Time, error) {
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("opening firmware bundle: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("checking firmware bundle: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("reading firmware bundle: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != b.SHA256 {
+		return nil, time.Time{}, fmt.Errorf("%w: %s %s", ErrChecksumMismatch, b.Model, b.Version)
+	}
+	return data, fi.ModTime(), nil
+}
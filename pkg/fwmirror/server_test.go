@@ -0,0 +1,93 @@
+package fwmirror
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+func writeBundle(t *testing.T, root, model, version, contents string) string {
+	dir := filepath.Join(root, model, version)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, model+".zip")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	sum := fmt.Sprintf("%x  %s.zip\n", sha256.Sum256([]byte(contents)), model)
+	require.NoError(t, os.WriteFile(path+ChecksumSuffix, []byte(sum), 0o644))
+	return path
+}
+
+func TestServer(t *testing.T) {
+	root := t.TempDir()
+	writeBundle(t, root, "Pro4PM", "1.4.4", "pro4pm 1.4.4")
+	writeBundle(t, root, "Pro4PM", "1.5.0-beta1", "pro4pm 1.5.0-beta1")
+	corrupt := writeBundle(t, root, "Pro4PM", "1.3.3", "pro4pm 1.3.3")
+	require.NoError(t, os.WriteFile(corrupt, []byte("corrupted"), 0o644))
+
+	s, err := NewServer(root, "http://mirror.local/firmware")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pro4PM"}, s.Index().Models())
+	assert.Equal(t, []string{"1.3.3", "1.4.4", "1.5.0-beta1"}, s.Index().Versions("Pro4PM"))
+
+	req, err := s.UpdateRequestForDevice(&shelly.ShellyGetDeviceInfoResponse{
+		App:   "Pro4PM",
+		Model: "SPSW-104PE16EU",
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "http://mirror.local/firmware/Pro4PM/1.4.4/Pro4PM.zip", req.URL)
+
+	_, err = s.UpdateRequest("Pro4PM", "1.3.3")
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = s.UpdateRequest("Plus1", "1.4.4")
+	assert.ErrorIs(t, err, ErrUnknownModel)
+
+	mux := http.NewServeMux()
+	mux.Handle("/firmware/", s)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/firmware/Pro4PM/1.4.4/Pro4PM.zip")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "pro4pm 1.4.4", string(body))
+
+	resp, err = http.Get(ts.URL + "/firmware/Pro4PM/1.3.3/Pro4PM.zip")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// A bundle replaced with the same size and modification time is verified again.
+	good := filepath.Join(root, "Pro4PM", "1.4.4", "Pro4PM.zip")
+	fi, err := os.Stat(good)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(good, []byte("pro4pm 6.6.6"), 0o644))
+	require.NoError(t, os.Chtimes(good, fi.ModTime(), fi.ModTime()))
+	resp, err = http.Get(ts.URL + "/firmware/Pro4PM/1.4.4/Pro4PM.zip")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/firmware/Pro4PM/1.4.4/other.zip")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, -1, CompareVersions("1.4.4", "1.10.0"))
+	assert.Equal(t, 1, CompareVersions("1.5.0", "1.5.0-beta1"))
+	assert.Equal(t, -1, CompareVersions("1.5.0-beta1", "1.5.0-beta2"))
+	assert.Equal(t, 0, CompareVersions("1.4.4", "1.4.4"))
+}
@@ -0,0 +1,290 @@
+// Package gen1 is a client for the REST API of Gen1 (legacy) Shelly devices, ex. the Shelly 1,
+// Shelly 2.5 and Shelly EM.
+//
+// Gen1 devices answer HTTP GET requests with JSON documents, and optionally require HTTP basic
+// authentication. Client implements shelly.GenericDevice, so tools can handle Gen1 and Gen2
+// devices uniformly.
+//
+// https://shelly-api-docs.shelly.cloud/gen1/
+package gen1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+const (
+	// DefaultUsername is the username used by the device web UI.
+	DefaultUsername = "admin"
+
+	// maxResponseSize limits the size of a response.
+	maxResponseSize = 1 << 20
+)
+
+var (
+	// ErrUnauthorized is returned when the device rejects the request's credentials.
+	ErrUnauthorized = errors.New("gen1 request unauthorized")
+)
+
+// StatusError is returned when the device responds with an unexpected HTTP status.
+type StatusError struct {
+	// StatusCode is the HTTP status.
+	StatusCode int
+
+	// Body is the start of the response body, which describes the error.
+	Body string
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("gen1 request failed with status %d: %s", err.StatusCode, err.Body)
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithCredentials sets the basic authentication credentials.
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithHTTPClient sets the client used for requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.hc = hc
+	}
+}
+
+// Client makes requests to a Gen1 device.
+type Client struct {
+	base     string
+	hc       *http.Client
+	username string
+	password string
+}
+
+var _ shelly.GenericDevice = (*Client)(nil)
+
+// NewClient returns a client for the device at addr, which is a host (ex. 192.168.1.30) or a
+// base URL (ex. http://192.168.1.30:8080).
+func NewClient(addr string, opts ...Option) *Client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	c := &Client{base: strings.TrimSuffix(addr, "/"), hc: http.DefaultClient}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Shelly returns the device identification from /shelly, which doesn't require authentication.
+func (c *Client) Shelly(ctx context.Context) (*ShellyInfo, error) {
+	info := &ShellyInfo{}
+	return info, c.get(ctx, "/shelly", nil, info)
+}
+
+// Status returns the state of all components from /status.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	status := &Status{}
+	return status, c.get(ctx, "/status", nil, status)
+}
+
+// Settings returns the device configuration from /settings.
+func (c *Client) Settings(ctx context.Context) (*Settings, error) {
+	settings := &Settings{}
+	return settings, c.get(ctx, "/settings", nil, settings)
+}
+
+// Relay applies cmd to the relay with the given id and returns its state. A nil cmd only reads
+// the state.
+func (c *Client) Relay(ctx context.Context, id int, cmd *RelayCommand) (*RelayStatus, error) {
+	status := &RelayStatus{}
+	return status, c.get(ctx, "/relay/"+strconv.Itoa(id), cmd, status)
+}
+
+// Roller applies cmd to the roller with the given id and returns its state. A nil cmd only
+// reads the state.
+func (c *Client) Roller(ctx context.Context, id int, cmd *RollerCommand) (*RollerStatus, error) {
+	status := &RollerStatus{}
+	return status, c.get(ctx, "/roller/"+strconv.Itoa(id), cmd, status)
+}
+
+// Light applies cmd to the light channel with the given id and returns its state. A nil cmd
+// only reads the state.
+func (c *Client) Light(ctx context.Context, id int, cmd *LightCommand) (*LightStatus, error) {
+	status := &LightStatus{}
+	return status, c.get(ctx, "/light/"+strconv.Itoa(id), cmd, status)
+}
+
+// Meter returns the reading of the power meter with the given id.
+func (c *Client) Meter(ctx context.Context, id int) (*MeterStatus, error) {
+	status := &MeterStatus{}
+	return status, c.get(ctx, "/meter/"+strconv.Itoa(id), nil, status)
+}
+
+// EMeter returns the reading of the energy meter with the given id.
+func (c *Client) EMeter(ctx context.Context, id int) (*EMeterStatus, error) {
+	status := &EMeterStatus{}
+	return status, c.get(ctx, "/emeter/"+strconv.Itoa(id), nil, status)
+}
+
+// Summary implements shelly.GenericDevice. The device ID is the hostname from /settings.
+func (c *Client) Summary(ctx context.Context) (*shelly.DeviceSummary, error) {
+	info, err := c.Shelly(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := c.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &shelly.DeviceSummary{
+		Gen:             1,
+		MAC:             info.MAC,
+		Model:           info.Type,
+		FirmwareVersion: info.FW,
+		AuthEnabled:     info.Auth,
+	}
+	if settings.Device != nil {
+		s.ID = settings.Device.Hostname
+	}
+	return s, nil
+}
+
+// Outputs implements shelly.GenericDevice.
+func (c *Client) Outputs(ctx context.Context) ([]*shelly.OutputStatus, error) {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]*shelly.OutputStatus, 0, len(status.Relays))
+	for i, r := range status.Relays {
+		outputs = append(outputs, &shelly.OutputStatus{ID: i, On: r.IsOn, Source: r.Source})
+	}
+	return outputs, nil
+}
+
+// SetOutput implements shelly.GenericDevice.
+func (c *Client) SetOutput(ctx context.Context, id int, on bool) error {
+	turn := "off"
+	if on {
+		turn = "on"
+	}
+	_, err := c.Relay(ctx, id, &RelayCommand{Turn: turn})
+	return err
+}
+
+// Meters implements shelly.GenericDevice. Power meter totals are converted from Watt-minutes
+// to Watt-hours.
+func (c *Client) Meters(ctx context.Context) ([]*shelly.MeterReading, error) {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var readings []*shelly.MeterReading
+	for i, m := range status.Meters {
+		total := m.Total / 60
+		readings = append(readings, &shelly.MeterReading{
+			Component:   fmt.Sprintf("meter:%d", i),
+			Power:       m.Power,
+			TotalEnergy: &total,
+		})
+	}
+	for i, m := range status.EMeters {
+		voltage, total := m.Voltage, m.Total
+		readings = append(readings, &shelly.MeterReading{
+			Component:   fmt.Sprintf("emeter:%d", i),
+			Power:       m.Power,
+			Voltage:     &voltage,
+			Current:     m.Current,
+			PF:          m.PF,
+			TotalEnergy: &total,
+		})
+	}
+	return readings, nil
+}
+
+// get requests path with the fields of params as query parameters, decoding the response into
+// resp.
+func (c *Client) get(ctx context.Context, path string, params any, resp any) error {
+	u := c.base + path
+	if q, err := encodeQuery(params); err != nil {
+		return err
+	} else if q != "" {
+		u += "?" + q
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("building gen1 request: %w", err)
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	httpResp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("making gen1 request: %w", err)
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("reading gen1 response: %w", err)
+	}
+	switch {
+	case httpResp.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case httpResp.StatusCode != http.StatusOK:
+		if len(body) > 256 {
+			body = body[:256]
+		}
+		return &StatusError{StatusCode: httpResp.StatusCode, Body: string(bytes.TrimSpace(body))}
+	}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return fmt.Errorf("failed to unmarshal gen1 response body: %w", err)
+	}
+	return nil
+}
+
+// encodeQuery encodes the JSON fields of params as query parameters. Unlike Gen2 GET requests,
+// strings are sent unquoted.
+func encodeQuery(params any) (string, error) {
+	if params == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("marshalling gen1 request: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil || fields == nil {
+		return "", nil
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	q := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := string(fields[k])
+		var s string
+		if json.Unmarshal(fields[k], &s) == nil {
+			v = s
+		}
+		q = append(q, url.QueryEscape(k)+"="+url.QueryEscape(v))
+	}
+	return strings.Join(q, "&"), nil
+}
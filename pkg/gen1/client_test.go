@@ -0,0 +1,116 @@
+package gen1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shelly25 serves canned responses from a Shelly 2.5 in relay mode.
+func shelly25(t *testing.T) (*httptest.Server, *[]string) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/shelly" {
+			if user, pass, ok := r.BasicAuth(); !ok || user != DefaultUsername || pass != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("401 Unauthorized"))
+				return
+			}
+		}
+		queries = append(queries, r.URL.RequestURI())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/shelly":
+			w.Write([]byte(`{"type":"SHSW-25","mac":"98CDAC1F2E3D","auth":true,"fw":"20230913-112234/v1.14.0-gcb84623","discoverable":false,"longid":1,"num_outputs":2,"num_meters":2,"num_rollers":1}`))
+		case "/settings":
+			w.Write([]byte(`{"device":{"type":"SHSW-25","mac":"98CDAC1F2E3D","hostname":"shellyswitch25-98CDAC1F2E3D","num_outputs":2,"num_meters":2,"num_rollers":1},"name":"Kitchen","fw":"20230913-112234/v1.14.0-gcb84623","mode":"relay","timezone":"America/New_York","lat":40.7,"lng":-74.0,"relays":[{"name":"Lights","ison":true,"default_state":"last","btn_type":"toggle","btn_reverse":0,"auto_on":0,"auto_off":0,"schedule":false,"schedule_rules":[]},{"name":null,"ison":false,"default_state":"off","btn_type":"momentary","btn_reverse":0,"auto_on":0,"auto_off":300,"schedule":false,"schedule_rules":[]}]}`))
+		case "/status":
+			w.Write([]byte(`{"wifi_sta":{"connected":true,"ssid":"iot","ip":"192.168.1.30","rssi":-61},"cloud":{"enabled":false,"connected":false},"mqtt":{"connected":false},"time":"12:00","unixtime":1717243200,"serial":1,"has_update":false,"mac":"98CDAC1F2E3D","cfg_changed_cnt":3,"relays":[{"ison":true,"has_timer":false,"timer_started":0,"timer_duration":0,"timer_remaining":0,"overpower":false,"overtemperature":false,"is_valid":true,"source":"input"},{"ison":false,"has_timer":false,"timer_started":0,"timer_duration":0,"timer_remaining":0,"overpower":false,"overtemperature":false,"is_valid":true,"source":"http"}],"meters":[{"power":42.5,"overpower":0,"is_valid":true,"timestamp":1717228800,"counters":[700.1,698.3,702.9],"total":6000},{"power":0,"overpower":0,"is_valid":true,"timestamp":1717228800,"counters":[0,0,0],"total":0}],"inputs":[{"input":0,"event":"","event_cnt":0},{"input":1,"event":"","event_cnt":0}],"temperature":51.2,"overtemperature":false,"voltage":230.1,"update":{"status":"idle","has_update":false,"new_version":"20230913-112234/v1.14.0-gcb84623","old_version":"20230913-112234/v1.14.0-gcb84623"},"ram_total":49600,"ram_free":35616,"fs_size":233681,"fs_free":146082,"uptime":3600}`))
+		case "/relay/1":
+			w.Write([]byte(`{"ison":true,"has_timer":true,"timer_started":1717243200,"timer_duration":30,"timer_remaining":30,"overpower":false,"source":"http"}`))
+		case "/roller/0":
+			w.Write([]byte(`{"state":"close","power":110.2,"is_valid":true,"safety_switch":false,"overtemperature":false,"stop_reason":"normal","last_direction":"close","current_pos":80,"calibrating":false,"positioning":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &queries
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	ts, queries := shelly25(t)
+
+	info, err := NewClient(ts.URL).Shelly(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "SHSW-25", info.Type)
+	assert.True(t, info.Auth)
+	assert.Equal(t, 2, info.NumOutputs)
+
+	_, err = NewClient(ts.URL).Status(ctx)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	c := NewClient(ts.URL, WithCredentials(DefaultUsername, "hunter2"))
+	status, err := c.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status.Relays, 2)
+	assert.True(t, status.Relays[0].IsOn)
+	assert.Equal(t, 42.5, status.Meters[0].Power)
+	assert.Equal(t, 230.1, *status.Voltage)
+
+	settings, err := c.Settings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "relay", settings.Mode)
+	assert.Equal(t, "Lights", *settings.Relays[0].Name)
+	assert.Equal(t, 300.0, settings.Relays[1].AutoOff)
+
+	timer := 30.0
+	relay, err := c.Relay(ctx, 1, &RelayCommand{Turn: "on", Timer: &timer})
+	require.NoError(t, err)
+	assert.True(t, relay.HasTimer)
+	pos := 80
+	roller, err := c.Roller(ctx, 0, &RollerCommand{Go: "to_pos", RollerPos: &pos})
+	require.NoError(t, err)
+	assert.Equal(t, "close", roller.State)
+	assert.Equal(t, []string{
+		"/shelly", "/status", "/settings", "/relay/1?timer=30&turn=on", "/roller/0?go=to_pos&roller_pos=80",
+	}, *queries)
+
+	_, err = c.EMeter(ctx, 0)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+}
+
+func TestClientGenericDevice(t *testing.T) {
+	ctx := context.Background()
+	ts, queries := shelly25(t)
+	c := NewClient(ts.URL, WithCredentials(DefaultUsername, "hunter2"))
+
+	summary, err := c.Summary(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Gen)
+	assert.Equal(t, "shellyswitch25-98CDAC1F2E3D", summary.ID)
+	assert.Equal(t, "SHSW-25", summary.Model)
+	assert.True(t, summary.AuthEnabled)
+
+	outputs, err := c.Outputs(ctx)
+	require.NoError(t, err)
+	require.Len(t, outputs, 2)
+	assert.True(t, outputs[0].On)
+	assert.Equal(t, "input", outputs[0].Source)
+
+	meters, err := c.Meters(ctx)
+	require.NoError(t, err)
+	require.Len(t, meters, 2)
+	assert.Equal(t, "meter:0", meters[0].Component)
+	assert.Equal(t, 100.0, *meters[0].TotalEnergy)
+
+	require.NoError(t, c.SetOutput(ctx, 1, true))
+	assert.Equal(t, "/relay/1?turn=on", (*queries)[len(*queries)-1])
+}
@@ -0,0 +1,425 @@
+package gen1
+
+// ShellyInfo is the response of the unauthenticated /shelly endpoint.
+type ShellyInfo struct {
+	// Type is the device model, ex. SHSW-1 or SHSW-25.
+	Type string `json:"type"`
+
+	// MAC of the device.
+	MAC string `json:"mac"`
+
+	// Auth is true if HTTP authentication is enabled.
+	Auth bool `json:"auth"`
+
+	// FW is the firmware version.
+	FW string `json:"fw"`
+
+	// Discoverable is true if the device is shown in 'Discovered devices'.
+	Discoverable *bool `json:"discoverable,omitempty"`
+
+	// LongID is 1 if the device identifies itself with its full MAC.
+	LongID *int `json:"longid,omitempty"`
+
+	// NumOutputs is the number of relays.
+	NumOutputs int `json:"num_outputs"`
+
+	// NumMeters is the number of power meters.
+	NumMeters int `json:"num_meters"`
+
+	// NumRollers is the number of rollers, present in roller mode.
+	NumRollers int `json:"num_rollers"`
+
+	// NumEMeters is the number of energy meters, present on EM devices.
+	NumEMeters int `json:"num_emeters"`
+}
+
+// Status is the response of /status.
+type Status struct {
+	WifiSta *WifiStaStatus `json:"wifi_sta,omitempty"`
+
+	Cloud *CloudStatus `json:"cloud,omitempty"`
+
+	MQTT *MQTTStatus `json:"mqtt,omitempty"`
+
+	// Time is the local time, ex. 15:04.
+	Time string `json:"time"`
+
+	// Unixtime is the current unix timestamp.
+	Unixtime int64 `json:"unixtime"`
+
+	// Serial is the cloud serial number.
+	Serial int `json:"serial"`
+
+	// HasUpdate is true if a firmware update is available.
+	HasUpdate bool `json:"has_update"`
+
+	// MAC of the device.
+	MAC string `json:"mac"`
+
+	// CfgChangedCnt is incremented when the settings change.
+	CfgChangedCnt int `json:"cfg_changed_cnt"`
+
+	Relays []*RelayStatus `json:"relays,omitempty"`
+
+	Rollers []*RollerStatus `json:"rollers,omitempty"`
+
+	Lights []*LightStatus `json:"lights,omitempty"`
+
+	Meters []*MeterStatus `json:"meters,omitempty"`
+
+	EMeters []*EMeterStatus `json:"emeters,omitempty"`
+
+	Inputs []*InputStatus `json:"inputs,omitempty"`
+
+	// Temperature is the internal temperature in Celsius, where measured.
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	// Overtemperature is true if the device has overheated.
+	Overtemperature *bool `json:"overtemperature,omitempty"`
+
+	// Voltage is the supply voltage, where measured (ex. Shelly 2.5).
+	Voltage *float64 `json:"voltage,omitempty"`
+
+	Update *UpdateStatus `json:"update,omitempty"`
+
+	RAMTotal int `json:"ram_total"`
+	RAMFree  int `json:"ram_free"`
+	FSSize   int `json:"fs_size"`
+	FSFree   int `json:"fs_free"`
+
+	// Uptime in seconds.
+	Uptime int `json:"uptime"`
+}
+
+// WifiStaStatus describes the Wifi station connection.
+type WifiStaStatus struct {
+	Connected bool   `json:"connected"`
+	SSID      string `json:"ssid,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	RSSI      int    `json:"rssi,omitempty"`
+}
+
+// CloudStatus describes the Shelly Cloud connection.
+type CloudStatus struct {
+	Enabled   bool `json:"enabled"`
+	Connected bool `json:"connected"`
+}
+
+// MQTTStatus describes the MQTT connection.
+type MQTTStatus struct {
+	Connected bool `json:"connected"`
+}
+
+// UpdateStatus describes firmware update availability.
+type UpdateStatus struct {
+	// Status is one of idle, pending, updating or unknown.
+	Status     string `json:"status"`
+	HasUpdate  bool   `json:"has_update"`
+	NewVersion string `json:"new_version"`
+	OldVersion string `json:"old_version"`
+}
+
+// RelayStatus is the state of a relay, returned by /relay/N and included in /status.
+type RelayStatus struct {
+	// IsOn is true if the relay is on.
+	IsOn bool `json:"ison"`
+
+	// HasTimer is true if a timer is armed.
+	HasTimer bool `json:"has_timer"`
+
+	// TimerStarted is the unix timestamp of the timer's start.
+	TimerStarted int64 `json:"timer_started"`
+
+	// TimerDuration is the timer's duration in seconds.
+	TimerDuration float64 `json:"timer_duration"`
+
+	// TimerRemaining is the time remaining on the timer in seconds.
+	TimerRemaining float64 `json:"timer_remaining"`
+
+	// Overpower is true if the relay was turned off due to overpower.
+	Overpower bool `json:"overpower"`
+
+	// Source of the last change, ex. http, input or timer.
+	Source string `json:"source"`
+}
+
+// RollerStatus is the state of a roller, returned by /roller/N and included in /status.
+type RollerStatus struct {
+	// State is one of open, close or stop.
+	State string `json:"state"`
+
+	// Power is the consumption in Watts.
+	Power float64 `json:"power"`
+
+	// IsValid is true if the power meter is functioning.
+	IsValid bool `json:"is_valid"`
+
+	// SafetySwitch is true if the safety switch is triggered.
+	SafetySwitch bool `json:"safety_switch"`
+
+	Overtemperature bool `json:"overtemperature"`
+
+	// StopReason is one of normal, safety_switch, obstacle or overpower.
+	StopReason string `json:"stop_reason"`
+
+	// LastDirection is open or close.
+	LastDirection string `json:"last_direction"`
+
+	// CurrentPos is the position in percent, or -1 if not calibrated.
+	CurrentPos int `json:"current_pos"`
+
+	Calibrating bool `json:"calibrating"`
+
+	// Positioning is true if the roller is calibrated for positioning.
+	Positioning bool `json:"positioning"`
+}
+
+// LightStatus is the state of a light channel, returned by /light/N and included in /status.
+type LightStatus struct {
+	IsOn   bool   `json:"ison"`
+	Source string `json:"source"`
+
+	HasTimer       bool    `json:"has_timer"`
+	TimerStarted   int64   `json:"timer_started"`
+	TimerDuration  float64 `json:"timer_duration"`
+	TimerRemaining float64 `json:"timer_remaining"`
+
+	// Mode is color or white, on RGBW devices.
+	Mode string `json:"mode,omitempty"`
+
+	Red   *int `json:"red,omitempty"`
+	Green *int `json:"green,omitempty"`
+	Blue  *int `json:"blue,omitempty"`
+	White *int `json:"white,omitempty"`
+
+	// Gain is the color mode brightness in percent.
+	Gain *int `json:"gain,omitempty"`
+
+	// Temp is the white color temperature in Kelvin.
+	Temp *int `json:"temp,omitempty"`
+
+	// Brightness is the white mode brightness in percent.
+	Brightness *int `json:"brightness,omitempty"`
+
+	Effect     *int `json:"effect,omitempty"`
+	Transition *int `json:"transition,omitempty"`
+}
+
+// MeterStatus is a power meter reading, returned by /meter/N and included in /status.
+type MeterStatus struct {
+	// Power is the current consumption in Watts.
+	Power float64 `json:"power"`
+
+	// Overpower is the value in Watts, on which an overpower condition is detected.
+	Overpower float64 `json:"overpower"`
+
+	// IsValid is true if the meter is functioning.
+	IsValid bool `json:"is_valid"`
+
+	// Timestamp of the last counter, in local time.
+	Timestamp int64 `json:"timestamp"`
+
+	// Counters are the energy consumed in Watt-minutes over the last three minutes.
+	Counters []float64 `json:"counters"`
+
+	// Total energy consumed in Watt-minutes.
+	Total float64 `json:"total"`
+}
+
+// EMeterStatus is an energy meter reading, returned by /emeter/N and included in /status.
+type EMeterStatus struct {
+	// Power is the instantaneous active power in Watts.
+	Power float64 `json:"power"`
+
+	// Reactive is the instantaneous reactive power in VAR.
+	Reactive float64 `json:"reactive"`
+
+	// PF is the power factor.
+	PF *float64 `json:"pf,omitempty"`
+
+	// Voltage is the RMS voltage in Volts.
+	Voltage float64 `json:"voltage"`
+
+	// Current is the RMS current in Amperes, where measured.
+	Current *float64 `json:"current,omitempty"`
+
+	// IsValid is true if the meter is functioning.
+	IsValid bool `json:"is_valid"`
+
+	// Total energy consumed in Watt-hours.
+	Total float64 `json:"total"`
+
+	// TotalReturned is the energy returned to the grid in Watt-hours.
+	TotalReturned float64 `json:"total_returned"`
+}
+
+// InputStatus is the state of an input.
+type InputStatus struct {
+	Input    int    `json:"input"`
+	Event    string `json:"event"`
+	EventCnt int    `json:"event_cnt"`
+}
+
+// Settings is the response of /settings.
+type Settings struct {
+	Device *DeviceSettings `json:"device,omitempty"`
+
+	// Name is the user-assigned device name.
+	Name *string `json:"name,omitempty"`
+
+	// FW is the firmware version.
+	FW string `json:"fw"`
+
+	// Mode is relay or roller on devices which support both, or color or white on RGBW devices.
+	Mode string `json:"mode,omitempty"`
+
+	// Timezone is the Olson timezone name.
+	Timezone string `json:"timezone"`
+
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+
+	Relays []*RelaySettings `json:"relays,omitempty"`
+
+	Rollers []*RollerSettings `json:"rollers,omitempty"`
+
+	Lights []*LightSettings `json:"lights,omitempty"`
+
+	EMeters []*EMeterSettings `json:"emeters,omitempty"`
+}
+
+// DeviceSettings identifies the device.
+type DeviceSettings struct {
+	Type string `json:"type"`
+	MAC  string `json:"mac"`
+
+	// Hostname is the device ID, ex. shelly1-34945472A3B4.
+	Hostname   string `json:"hostname"`
+	NumOutputs int    `json:"num_outputs"`
+	NumMeters  int    `json:"num_meters"`
+	NumRollers int    `json:"num_rollers"`
+	NumEMeters int    `json:"num_emeters"`
+}
+
+// RelaySettings configures a relay.
+type RelaySettings struct {
+	Name *string `json:"name,omitempty"`
+
+	// ApplianceType describes the attached load, ex. General or Lights.
+	ApplianceType string `json:"appliance_type,omitempty"`
+
+	IsOn bool `json:"ison"`
+
+	// DefaultState is off, on, last or switch.
+	DefaultState string `json:"default_state"`
+
+	// BtnType is momentary, toggle, edge, detached or action.
+	BtnType string `json:"btn_type"`
+
+	BtnReverse int `json:"btn_reverse"`
+
+	// AutoOn and AutoOff are the auto on/off timers in seconds, or 0 if disabled.
+	AutoOn  float64 `json:"auto_on"`
+	AutoOff float64 `json:"auto_off"`
+
+	MaxPower *float64 `json:"max_power,omitempty"`
+
+	Schedule      bool     `json:"schedule"`
+	ScheduleRules []string `json:"schedule_rules"`
+}
+
+// RollerSettings configures a roller.
+type RollerSettings struct {
+	// MaxTime is the maximum travel time in seconds.
+	MaxTime      float64 `json:"maxtime"`
+	MaxTimeOpen  float64 `json:"maxtime_open"`
+	MaxTimeClose float64 `json:"maxtime_close"`
+
+	// DefaultState is stop, open or close.
+	DefaultState string `json:"default_state"`
+
+	Swap       bool   `json:"swap"`
+	SwapInputs bool   `json:"swap_inputs"`
+	InputMode  string `json:"input_mode"`
+	BtnType    string `json:"button_type"`
+
+	// ObstacleMode is disabled, while_opening, while_closing or while_moving.
+	ObstacleMode   string  `json:"obstacle_mode"`
+	ObstacleAction string  `json:"obstacle_action"`
+	ObstaclePower  float64 `json:"obstacle_power"`
+	ObstacleDelay  float64 `json:"obstacle_delay"`
+
+	// SafetyMode is disabled, while_opening, while_closing or while_moving.
+	SafetyMode   string `json:"safety_mode"`
+	SafetyAction string `json:"safety_action"`
+
+	// Positioning is true if the roller is calibrated.
+	Positioning bool `json:"positioning"`
+}
+
+// LightSettings configures a light channel.
+type LightSettings struct {
+	Name         *string `json:"name,omitempty"`
+	IsOn         bool    `json:"ison"`
+	DefaultState string  `json:"default_state"`
+	AutoOn       float64 `json:"auto_on"`
+	AutoOff      float64 `json:"auto_off"`
+	BtnType      string  `json:"btn_type,omitempty"`
+}
+
+// EMeterSettings configures an energy meter.
+type EMeterSettings struct {
+	// ApplianceType describes the measured load, ex. General.
+	ApplianceType string `json:"appliance_type"`
+
+	MaxPower float64 `json:"max_power"`
+}
+
+// RelayCommand changes a relay via /relay/N.
+type RelayCommand struct {
+	// Turn is on, off or toggle.
+	Turn string `json:"turn,omitempty"`
+
+	// Timer flips the relay back after the given number of seconds.
+	Timer *float64 `json:"timer,omitempty"`
+}
+
+// RollerCommand moves a roller via /roller/N.
+type RollerCommand struct {
+	// Go is open, close, stop or to_pos.
+	Go string `json:"go,omitempty"`
+
+	// RollerPos is the target position in percent for `to_pos`.
+	RollerPos *int `json:"roller_pos,omitempty"`
+
+	// Duration limits the movement in seconds for `open` and `close`.
+	Duration *float64 `json:"duration,omitempty"`
+
+	// Offset moves the roller by a relative amount in percent.
+	Offset *int `json:"offset,omitempty"`
+}
+
+// LightCommand changes a light channel via /light/N.
+type LightCommand struct {
+	// Turn is on, off or toggle.
+	Turn string `json:"turn,omitempty"`
+
+	// Mode is color or white, on RGBW devices.
+	Mode string `json:"mode,omitempty"`
+
+	Red   *int `json:"red,omitempty"`
+	Green *int `json:"green,omitempty"`
+	Blue  *int `json:"blue,omitempty"`
+	White *int `json:"white,omitempty"`
+	Gain  *int `json:"gain,omitempty"`
+
+	Brightness *int `json:"brightness,omitempty"`
+	Temp       *int `json:"temp,omitempty"`
+	Effect     *int `json:"effect,omitempty"`
+
+	// Transition is the transition time in milliseconds.
+	Transition *int `json:"transition,omitempty"`
+
+	// Timer flips the light back after the given number of seconds.
+	Timer *float64 `json:"timer,omitempty"`
+}
@@ -0,0 +1,150 @@
+// Package mgrpcadapter makes shelly requests over mgrpc.MgRPC channels, ex. those built with
+// mgrpc.New. It's separate from the shelly package so programs which only use shelly.Client
+// don't link mgrpc and its dependencies.
+//
+//	c, err := mgrpc.New(ctx, "http://192.168.1.20/rpc", mgrpc.UseHTTPPost())
+//	...
+//	status, _, err := (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, mgrpcadapter.New(c), nil)
+//
+// Do and Call accept the channel directly, returning the mgrpc response frame.
+package mgrpcadapter
+
+import (
+	"context"
+	"sync"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/mongoose-os/mos/common/mgrpc"
+	"github.com/mongoose-os/mos/common/mgrpc/frame"
+)
+
+// notifyMethods are the methods subscribed to when Subscribe is called without a method, as
+// mgrpc only delivers notifications to handlers registered by method.
+var notifyMethods = []string{
+	(&shelly.NotifyStatus{}).Method(),
+	shelly.NotifyFullStatusMethod,
+	(&shelly.NotifyEvent{}).Method(),
+}
+
+// Caller implements shelly.Caller and shelly.Subscriber by making calls on an mgrpc channel.
+type Caller struct {
+	c mgrpc.MgRPC
+
+	mu            sync.Mutex
+	handled       map[string]bool
+	notifications shelly.NotificationHub
+}
+
+var (
+	_ shelly.Caller     = (*Caller)(nil)
+	_ shelly.Subscriber = (*Caller)(nil)
+)
+
+// New returns a Caller which makes calls on c.
+func New(c mgrpc.MgRPC) *Caller {
+	return &Caller{c: c, handled: make(map[string]bool)}
+}
+
+// Channel returns the wrapped mgrpc channel.
+func (c *Caller) Channel() mgrpc.MgRPC {
+	return c.c
+}
+
+// Call implements shelly.Caller.
+func (c *Caller) Call(
+	ctx context.Context,
+	f *shelly.RPCFrame,
+	getCreds shelly.GetCredsCallback,
+) (*shelly.RPCFrame, error) {
+	var creds mgrpc.GetCredsCallback
+	if getCreds != nil {
+		creds = mgrpc.GetCredsCallback(getCreds)
+	}
+	resp, err := c.c.Call(ctx, f.Dst, &frame.Command{Cmd: f.Method, Args: f.Params}, creds)
+	if resp == nil {
+		return nil, err
+	}
+	return FromResponse(resp), err
+}
+
+// Subscribe implements shelly.Subscriber. If method is empty, the channel receives
+// NotifyStatus, NotifyFullStatus and NotifyEvent notifications.
+func (c *Caller) Subscribe(ctx context.Context, method string) <-chan *shelly.RPCFrame {
+	ch := c.notifications.Subscribe(ctx, method)
+	methods := []string{method}
+	if method == "" {
+		methods = notifyMethods
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range methods {
+		if c.handled[m] {
+			continue
+		}
+		c.handled[m] = true
+		c.c.AddHandler(m, func(_ mgrpc.MgRPC, f *frame.Frame) *frame.Frame {
+			c.notifications.Publish(&shelly.RPCFrame{Src: f.Src, Dst: f.Dst, Method: f.Method, Params: f.Params})
+			return nil
+		})
+	}
+	return ch
+}
+
+// Disconnect disconnects the wrapped channel.
+func (c *Caller) Disconnect(ctx context.Context) error {
+	return c.c.Disconnect(ctx)
+}
+
+// Do makes req on the mgrpc channel c, decoding the result into resp. It behaves as shelly.Do.
+func Do(
+	ctx context.Context,
+	c mgrpc.MgRPC,
+	credsCallback mgrpc.GetCredsCallback,
+	req shelly.RPCRequestBody,
+	resp any,
+) (*frame.Response, error) {
+	var creds shelly.GetCredsCallback
+	if credsCallback != nil {
+		creds = shelly.GetCredsCallback(credsCallback)
+	}
+	raw, err := shelly.Do(ctx, New(c), creds, req, resp)
+	return ToResponse(raw), err
+}
+
+// Call makes req on the mgrpc channel c and returns its typed response. It behaves as
+// shelly.Call.
+func Call[Req shelly.TypedRequest[Resp], Resp any](
+	ctx context.Context,
+	c mgrpc.MgRPC,
+	credsCallback mgrpc.GetCredsCallback,
+	req Req,
+) (*Resp, *frame.Response, error) {
+	resp := req.NewTypedResponse()
+	raw, err := Do(ctx, c, credsCallback, req, resp)
+	return resp, raw, err
+}
+
+// FromResponse converts an mgrpc response to a JSON-RPC response frame.
+func FromResponse(resp *frame.Response) *shelly.RPCFrame {
+	f := &shelly.RPCFrame{ID: resp.ID}
+	if resp.Status != 0 {
+		f.Error = &shelly.RPCFrameError{Code: resp.Status, Message: resp.StatusMsg}
+	} else {
+		f.Result = resp.Response
+	}
+	return f
+}
+
+// ToResponse converts a JSON-RPC response frame to an mgrpc response. It returns nil for a nil
+// frame.
+func ToResponse(f *shelly.RPCFrame) *frame.Response {
+	if f == nil {
+		return nil
+	}
+	resp := &frame.Response{ID: f.ID, Response: f.Result}
+	if f.Error != nil {
+		resp.Status = f.Error.Code
+		resp.StatusMsg = f.Error.Message
+	}
+	return resp
+}
@@ -0,0 +1,99 @@
+package mgrpcadapter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mongoose-os/mos/common/mgrpc"
+	"github.com/mongoose-os/mos/common/mgrpc/codec"
+	"github.com/mongoose-os/mos/common/mgrpc/frame"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// stubRPC answers every call with a fixed response per method.
+type stubRPC struct {
+	responses map[string]*frame.Response
+	commands  []*frame.Command
+	user      string
+	handlers  map[string]mgrpc.Handler
+}
+
+func (s *stubRPC) Call(
+	ctx context.Context,
+	dst string,
+	cmd *frame.Command,
+	getCreds mgrpc.GetCredsCallback,
+) (*frame.Response, error) {
+	s.commands = append(s.commands, cmd)
+	if getCreds != nil {
+		s.user, _, _ = getCreds()
+	}
+	return s.responses[cmd.Cmd], nil
+}
+
+func (s *stubRPC) AddHandler(method string, handler mgrpc.Handler) {
+	s.handlers[method] = handler
+}
+
+func (s *stubRPC) Disconnect(ctx context.Context) error {
+	return nil
+}
+
+func (s *stubRPC) IsConnected() bool {
+	return true
+}
+
+func (s *stubRPC) SetCodecOptions(opts *codec.Options) error {
+	return nil
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubRPC{responses: map[string]*frame.Response{
+		"Switch.Set":    {Response: json.RawMessage(`{"was_on":true}`)},
+		"Switch.Toggle": {Status: int(shelly.ErrRPCUnknownComponentID), StatusMsg: "switch:9 not found"},
+	}}
+	creds := func() (string, string, error) { return "admin", "hunter2", nil }
+
+	resp, raw, err := Call(ctx, stub, creds, &shelly.SwitchSetRequest{ID: 1, On: true})
+	require.NoError(t, err)
+	assert.True(t, resp.WasOn)
+	assert.Equal(t, 0, raw.Status)
+	assert.Equal(t, "admin", stub.user)
+	require.Len(t, stub.commands, 1)
+	assert.JSONEq(t, `{"id":1,"on":true}`, string(stub.commands[0].Args))
+
+	raw, err = Do(ctx, stub, nil, &shelly.SwitchToggleRequest{ID: 9}, &shelly.SwitchActionResponse{})
+	assert.ErrorIs(t, err, shelly.ErrRPCUnknownComponentID)
+	assert.Equal(t, int(shelly.ErrRPCUnknownComponentID), raw.Status)
+
+	// Request types' Do methods accept the adapted channel.
+	resp, _, err = (&shelly.SwitchSetRequest{ID: 1, On: true}).Do(ctx, New(stub), nil)
+	require.NoError(t, err)
+	assert.True(t, resp.WasOn)
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stub := &stubRPC{handlers: make(map[string]mgrpc.Handler)}
+	c := New(stub)
+	all := c.Subscribe(ctx, "")
+	events := c.Subscribe(ctx, "NotifyEvent")
+	assert.Len(t, stub.handlers, 3)
+
+	stub.handlers["NotifyEvent"](stub, &frame.Frame{
+		Src:    "shellypro4pm-f008d1d8b8b8",
+		Method: "NotifyEvent",
+		Params: json.RawMessage(`{"ts":1.5,"events":[]}`),
+	})
+	for _, ch := range []<-chan *shelly.RPCFrame{all, events} {
+		f := <-ch
+		assert.Equal(t, "shellypro4pm-f008d1d8b8b8", f.Src)
+		assert.JSONEq(t, `{"ts":1.5,"events":[]}`, string(f.Params))
+	}
+}
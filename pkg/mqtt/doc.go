@@ -1,3 +1,9 @@
+// Package mqtt carries Gen2 JSON-RPC calls and notifications over an MQTT broker.
+//
+// Devices with MQTT enabled accept requests published to `<prefix>/rpc` and publish responses to
+// `<src>/rpc`, where src is the source named in the request. With `rpc_ntf` enabled,
+// NotifyStatus and NotifyEvent frames are published to `<prefix>/events/rpc`; with `status_ntf`
+// enabled, the full status of each component is published to `<prefix>/status/<component>:<id>`.
+//
+// https://shelly-api-docs.shelly.cloud/gen2/General/RPCChannels#mqtt
 package mqtt
-
-import _ "github.com/eclipse/paho.mqtt.golang"
@@ -0,0 +1,247 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// Transport implements shelly.NotifyingTransport over an MQTT broker. The paho client is owned
+// by the caller and must already be connected; Close only removes the transport's
+// subscriptions.
+type Transport struct {
+	client paho.Client
+	prefix string
+	qos    byte
+	status bool
+
+	mu          sync.Mutex
+	pending     map[int64]chan *shelly.RPCFrame
+	subscribed  []string
+	responseSrc map[string]bool
+	onNotify    func(*shelly.RPCFrame)
+}
+
+// Option configures a Transport.
+type Option func(*Transport)
+
+// WithQoS sets the QoS used for publishing requests and subscribing to topics. Defaults to 1.
+func WithQoS(qos byte) Option {
+	return func(t *Transport) {
+		t.qos = qos
+	}
+}
+
+// WithStatusNotifications subscribes to the per-component status topics which the device
+// publishes when `status_ntf` is enabled. Each status message is delivered to the notification
+// handler as a NotifyStatus frame containing the single component.
+func WithStatusNotifications(enabled bool) Option {
+	return func(t *Transport) {
+		t.status = enabled
+	}
+}
+
+// WithMQTTConfig applies the device's MQTT configuration, using its topic prefix (if set) and
+// subscribing to status topics if `status_ntf` is enabled.
+func WithMQTTConfig(cfg *shelly.MQTTConfig) Option {
+	return func(t *Transport) {
+		if cfg == nil {
+			return
+		}
+		if prefix := cfg.TopicPrefix.Or(""); prefix != "" {
+			t.prefix = prefix
+		}
+		if cfg.Status_NTF != nil {
+			t.status = *cfg.Status_NTF
+		}
+	}
+}
+
+// NewTransport builds a transport for the device using the topic prefix (by default the device
+// id, ex. shellypro4pm-f008d1d8b8b8) and subscribes to its notification topics.
+func NewTransport(ctx context.Context, client paho.Client, prefix string, opts ...Option) (*Transport, error) {
+	t := &Transport{
+		client:      client,
+		prefix:      prefix,
+		qos:         1,
+		pending:     make(map[int64]chan *shelly.RPCFrame),
+		responseSrc: make(map[string]bool),
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	if err := t.subscribe(ctx, t.prefix+"/events/rpc", t.handleEvent); err != nil {
+		return nil, err
+	}
+	if t.status {
+		if err := t.subscribe(ctx, t.prefix+"/status/+", t.handleStatus); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// NewClient builds a shelly.Client which calls the device with the given topic prefix via the
+// broker. Use NewTransport with shelly.NewClient to configure the client.
+func NewClient(ctx context.Context, client paho.Client, prefix string, opts ...Option) (*shelly.Client, error) {
+	t, err := NewTransport(ctx, client, prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return shelly.NewClient(t), nil
+}
+
+// Prefix returns the device topic prefix.
+func (t *Transport) Prefix() string {
+	return t.prefix
+}
+
+func (t *Transport) subscribe(ctx context.Context, topic string, h paho.MessageHandler) error {
+	if err := wait(ctx, t.client.Subscribe(topic, t.qos, h)); err != nil {
+		return fmt.Errorf("subscribing to %q: %w", topic, err)
+	}
+	t.mu.Lock()
+	t.subscribed = append(t.subscribed, topic)
+	t.mu.Unlock()
+	return nil
+}
+
+// ensureResponseTopic subscribes to `<src>/rpc` the first time src is used.
+func (t *Transport) ensureResponseTopic(ctx context.Context, src string) error {
+	t.mu.Lock()
+	done := t.responseSrc[src]
+	t.mu.Unlock()
+	if done {
+		return nil
+	}
+	if err := t.subscribe(ctx, src+"/rpc", t.handleResponse); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.responseSrc[src] = true
+	t.mu.Unlock()
+	return nil
+}
+
+// RoundTrip implements shelly.Transport.
+func (t *Transport) RoundTrip(ctx context.Context, req *shelly.RPCFrame) (*shelly.RPCFrame, error) {
+	if req.Src == "" {
+		return nil, fmt.Errorf("mqtt requests require a src for the response topic")
+	}
+	if err := t.ensureResponseTopic(ctx, req.Src); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding rpc frame: %w", err)
+	}
+
+	ch := make(chan *shelly.RPCFrame, 1)
+	t.mu.Lock()
+	t.pending[req.ID] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, req.ID)
+		t.mu.Unlock()
+	}()
+
+	if err := wait(ctx, t.client.Publish(t.prefix+"/rpc", t.qos, false, body)); err != nil {
+		return nil, fmt.Errorf("publishing rpc request: %w", err)
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *Transport) handleResponse(_ paho.Client, msg paho.Message) {
+	var f shelly.RPCFrame
+	if err := json.Unmarshal(msg.Payload(), &f); err != nil {
+		return
+	}
+	t.mu.Lock()
+	ch, ok := t.pending[f.ID]
+	delete(t.pending, f.ID)
+	t.mu.Unlock()
+	if ok {
+		ch <- &f
+	}
+}
+
+func (t *Transport) handleEvent(_ paho.Client, msg paho.Message) {
+	var f shelly.RPCFrame
+	if err := json.Unmarshal(msg.Payload(), &f); err != nil || !f.IsNotification() {
+		return
+	}
+	t.notify(&f)
+}
+
+// handleStatus wraps a `<prefix>/status/<component>:<id>` message as a NotifyStatus frame.
+func (t *Transport) handleStatus(_ paho.Client, msg paho.Message) {
+	component := msg.Topic()[strings.LastIndex(msg.Topic(), "/")+1:]
+	params, err := json.Marshal(map[string]json.RawMessage{component: msg.Payload()})
+	if err != nil {
+		return
+	}
+	t.notify(&shelly.RPCFrame{
+		JSONRPC: shelly.JSONRPCVersion,
+		Src:     t.prefix,
+		Method:  (&shelly.NotifyStatus{}).Method(),
+		Params:  params,
+	})
+}
+
+func (t *Transport) notify(f *shelly.RPCFrame) {
+	t.mu.Lock()
+	h := t.onNotify
+	t.mu.Unlock()
+	if h != nil {
+		h(f)
+	}
+}
+
+// SetNotificationHandler implements shelly.NotifyingTransport.
+func (t *Transport) SetNotificationHandler(h func(*shelly.RPCFrame)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onNotify = h
+}
+
+// IsConnected reports whether the broker connection is open.
+func (t *Transport) IsConnected() bool {
+	return t.client.IsConnectionOpen()
+}
+
+// Close implements shelly.Transport. It unsubscribes from the transport's topics but leaves
+// the broker connection open.
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	topics := t.subscribed
+	t.subscribed = nil
+	t.responseSrc = make(map[string]bool)
+	t.mu.Unlock()
+	if len(topics) == 0 || !t.client.IsConnectionOpen() {
+		return nil
+	}
+	tok := t.client.Unsubscribe(topics...)
+	tok.Wait()
+	return tok.Error()
+}
+
+func wait(ctx context.Context, tok paho.Token) error {
+	select {
+	case <-tok.Done():
+		return tok.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
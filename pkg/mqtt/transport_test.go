@@ -0,0 +1,187 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrefix = "shellypro4pm-f008d1d8b8b8"
+
+// testBroker is a minimal in-process MQTT 3.1.1 broker. Messages are delivered at QoS 0.
+type testBroker struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	subs map[net.Conn][]string
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &testBroker{ln: ln, subs: make(map[net.Conn][]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *testBroker) addr() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+func (b *testBroker) write(conn net.Conn, p packets.ControlPacket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p.Write(conn)
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+	for {
+		cp, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := cp.(type) {
+		case *packets.ConnectPacket:
+			b.write(conn, packets.NewControlPacket(packets.Connack))
+		case *packets.PingreqPacket:
+			b.write(conn, packets.NewControlPacket(packets.Pingresp))
+		case *packets.SubscribePacket:
+			b.mu.Lock()
+			b.subs[conn] = append(b.subs[conn], p.Topics...)
+			b.mu.Unlock()
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			ack.ReturnCodes = make([]byte, len(p.Topics))
+			b.write(conn, ack)
+		case *packets.UnsubscribePacket:
+			ack := packets.NewControlPacket(packets.Unsuback).(*packets.UnsubackPacket)
+			ack.MessageID = p.MessageID
+			b.write(conn, ack)
+		case *packets.PublishPacket:
+			if p.Qos == 1 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				b.write(conn, ack)
+			}
+			b.route(p)
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func (b *testBroker) route(p *packets.PublishPacket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for conn, filters := range b.subs {
+		for _, f := range filters {
+			if topicMatches(f, p.TopicName) {
+				out := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+				out.TopicName = p.TopicName
+				out.Payload = p.Payload
+				out.Write(conn)
+				break
+			}
+		}
+	}
+}
+
+func topicMatches(filter, topic string) bool {
+	fp, tp := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, f := range fp {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tp) || (f != "+" && f != tp[i]) {
+			return false
+		}
+	}
+	return len(fp) == len(tp)
+}
+
+func connect(t *testing.T, b *testBroker, id string) paho.Client {
+	c := paho.NewClient(paho.NewClientOptions().AddBroker(b.addr()).SetClientID(id))
+	tok := c.Connect()
+	require.True(t, tok.WaitTimeout(5*time.Second))
+	require.NoError(t, tok.Error())
+	t.Cleanup(func() { c.Disconnect(0) })
+	return c
+}
+
+// fakeDevice answers Switch.GetStatus requests and publishes notifications.
+func fakeDevice(t *testing.T, c paho.Client) {
+	tok := c.Subscribe(testPrefix+"/rpc", 0, func(c paho.Client, msg paho.Message) {
+		var req shelly.RPCFrame
+		require.NoError(t, json.Unmarshal(msg.Payload(), &req))
+		resp := shelly.RPCFrame{ID: req.ID, Src: testPrefix, Dst: req.Src}
+		if req.Method == "Switch.GetStatus" {
+			resp.Result = json.RawMessage(`{"id":0,"output":true,"apower":12.5}`)
+		} else {
+			resp.Error = &shelly.RPCFrameError{Code: int(shelly.ErrRPCNoHandler), Message: "No handler for " + req.Method}
+		}
+		b, _ := json.Marshal(&resp)
+		c.Publish(req.Src+"/rpc", 0, false, b)
+		c.Publish(testPrefix+"/events/rpc", 0, false,
+			`{"src":"`+testPrefix+`","dst":"`+testPrefix+`/events","method":"NotifyEvent",`+
+				`"params":{"ts":1.5,"events":[{"component":"input:0","id":0,"event":"single_push"}]}}`)
+		c.Publish(testPrefix+"/status/switch:0", 0, false, `{"id":0,"output":false}`)
+	})
+	require.True(t, tok.WaitTimeout(5*time.Second))
+	require.NoError(t, tok.Error())
+}
+
+func TestTransport(t *testing.T) {
+	b := newTestBroker(t)
+	fakeDevice(t, connect(t, b, "device"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := NewClient(ctx, connect(t, b, "client"), testPrefix,
+		WithMQTTConfig(&shelly.MQTTConfig{Status_NTF: shelly.BoolPtr(true)}))
+	require.NoError(t, err)
+	defer c.Disconnect(ctx)
+
+	events := c.SubscribeEvent(ctx)
+	statuses := c.SubscribeStatus(ctx)
+
+	status, _, err := (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, c, nil)
+	require.NoError(t, err)
+	assert.True(t, *status.Output)
+	assert.Equal(t, 12.5, *status.APower)
+
+	event := <-events
+	require.Len(t, event.Value.Events, 1)
+	assert.Equal(t, "single_push", event.Value.Events[0].Event)
+
+	ns := <-statuses
+	require.Len(t, ns.Value.Switches, 1)
+	assert.False(t, *ns.Value.Switches[0].Output)
+
+	_, _, err = (&shelly.SwitchToggleRequest{ID: 0}).Do(ctx, c, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCNoHandler)
+}
@@ -0,0 +1,144 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+const (
+	// DefaultRebootBatchSize is the number of devices rebooted at once, unless
+	// WithRebootBatchSize is given.
+	DefaultRebootBatchSize = 1
+
+	// DefaultPollInterval is how often a rebooting device is checked, unless WithPollInterval
+	// is given.
+	DefaultPollInterval = 2 * time.Second
+)
+
+// Option configures Apply.
+type Option func(*applier)
+
+// WithRebootBatchSize sets the number of devices rebooted at once. Each batch must come back
+// before the next is rebooted, so a fleet is never down at once.
+func WithRebootBatchSize(n int) Option {
+	return func(a *applier) {
+		a.batchSize = max(n, 1)
+	}
+}
+
+// WithoutReboot leaves devices which require a restart running; they're listed in
+// Result.RestartRequired for a later reboot.
+func WithoutReboot() Option {
+	return func(a *applier) {
+		a.reboot = false
+	}
+}
+
+// WithPollInterval sets how often a rebooting device is checked.
+func WithPollInterval(d time.Duration) Option {
+	return func(a *applier) {
+		a.pollInterval = d
+	}
+}
+
+// Result describes the outcome of Apply.
+type Result struct {
+	// Changed lists the targets which were configured.
+	Changed []string
+
+	// RestartRequired maps targets to the components (ex. "ble") whose SetConfig reported
+	// restart_required.
+	RestartRequired map[string][]string
+
+	// Rebooted lists the targets which were rebooted.
+	Rebooted []string
+}
+
+type applier struct {
+	batchSize    int
+	reboot       bool
+	pollInterval time.Duration
+}
+
+// Apply makes the changes of each plan. A device which reports restart_required is rebooted
+// once after all its components are configured; reboots are batched across plans (see
+// WithRebootBatchSize), and Apply waits for each batch to answer again. On error, Result
+// describes the changes already made.
+func Apply(ctx context.Context, plans []*Plan, opts ...Option) (*Result, error) {
+	a := &applier{
+		batchSize:    DefaultRebootBatchSize,
+		reboot:       true,
+		pollInterval: DefaultPollInterval,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	result := &Result{RestartRequired: make(map[string][]string)}
+	var pending []*Plan
+	for _, p := range plans {
+		if p.Empty() {
+			continue
+		}
+		restart, err := p.apply(ctx)
+		if len(restart) > 0 {
+			result.RestartRequired[p.Target] = restart
+			pending = append(pending, p)
+		}
+		if err != nil {
+			return result, err
+		}
+		result.Changed = append(result.Changed, p.Target)
+	}
+	if !a.reboot {
+		return result, nil
+	}
+	for len(pending) > 0 {
+		batch := pending[:min(a.batchSize, len(pending))]
+		pending = pending[len(batch):]
+		for _, p := range batch {
+			if err := p.dev.Reboot(ctx); err != nil {
+				return result, fmt.Errorf("rebooting %s: %w", p.Target, err)
+			}
+			result.Rebooted = append(result.Rebooted, p.Target)
+		}
+		for _, p := range batch {
+			if err := a.waitForDevice(ctx, p); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// apply configures the changed components, returning those which require a restart.
+func (p *Plan) apply(ctx context.Context) ([]string, error) {
+	var restart []string
+	for _, key := range p.Keys() {
+		req := &shelly.ComponentSetConfigRequest{Key: key, Config: p.patches[key]}
+		var resp shelly.SetConfigResponse
+		if _, err := p.dev.Do(ctx, req, &resp); err != nil {
+			return restart, fmt.Errorf("configuring %s on %s: %w", key, p.Target, err)
+		}
+		if resp.RestartRequired {
+			restart = append(restart, key)
+		}
+	}
+	return restart, nil
+}
+
+// waitForDevice polls the device until it answers after a reboot.
+func (a *applier) waitForDevice(ctx context.Context, p *Plan) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s to reboot: %w", p.Target, ctx.Err())
+		case <-time.After(a.pollInterval):
+		}
+		if _, err := p.dev.Info(ctx); err == nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// Change is a field whose value differs from the desired state.
+type Change struct {
+	// Key of the component, ex. "switch:0".
+	Key string
+
+	// Path of the field within the component's config, ex. "night_mode.brightness".
+	Path string
+
+	// Old is the current value, nil if the field is null or not reported.
+	Old any
+
+	// New is the desired value.
+	New any
+}
+
+// Plan is the changes which bring a device to its desired state.
+type Plan struct {
+	// Target names the device in diffs and results.
+	Target string
+
+	// Changes are ordered by component key and path.
+	Changes []Change
+
+	dev *shelly.Device
+
+	// patches are the SetConfig params of each changed component.
+	patches map[string]map[string]any
+
+	writeOnly bool
+}
+
+// PlanOption configures NewPlan.
+type PlanOption func(*Plan)
+
+// WithWriteOnlyFields plans the write-only fields of desired, ex. Wi-Fi and MQTT passwords. The
+// device never reports them, so they can't be compared and are always sent; by default they're
+// left out so a plan for a device in its desired state is empty.
+func WithWriteOnlyFields() PlanOption {
+	return func(p *Plan) {
+		p.writeOnly = true
+	}
+}
+
+// NewPlan reads the config of dev and computes the changes needed to reach desired. It fails
+// with shelly.ErrComponentNotPresent if desired configures a component the device doesn't have.
+// Read-only fields of desired, ex. the id of typed configs, are ignored.
+func NewPlan(
+	ctx context.Context,
+	target string,
+	dev *shelly.Device,
+	desired State,
+	opts ...PlanOption,
+) (*Plan, error) {
+	var current map[string]map[string]any
+	if _, err := dev.Do(ctx, &shelly.RawRequest{Cmd: "Shelly.GetConfig"}, &current); err != nil {
+		return nil, fmt.Errorf("reading config of %s: %w", target, err)
+	}
+	p := &Plan{Target: target, dev: dev, patches: make(map[string]map[string]any)}
+	for _, o := range opts {
+		o(p)
+	}
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cur, ok := current[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s on %s", shelly.ErrComponentNotPresent, key, target)
+		}
+		// Round trip so desired values have the types of decoded JSON, ex. float64 for ints.
+		want, err := toMap(desired[key])
+		if err != nil {
+			return nil, fmt.Errorf("encoding desired config of %s: %w", key, err)
+		}
+		shelly.StripReadOnlyConfig(key, want)
+		if patch := p.diff(key, "", want, cur); len(patch) > 0 {
+			p.patches[key] = patch
+		}
+	}
+	return p, nil
+}
+
+// diff records the fields of want which differ from cur, returning them as a patch.
+func (p *Plan) diff(key, prefix string, want, cur map[string]any) map[string]any {
+	fields := make([]string, 0, len(want))
+	for f := range want {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	patch := make(map[string]any)
+	for _, f := range fields {
+		path := prefix + f
+		w := want[f]
+		if wm, ok := w.(map[string]any); ok {
+			cm, _ := cur[f].(map[string]any)
+			if sub := p.diff(key, path+".", wm, cm); len(sub) > 0 {
+				patch[f] = sub
+			}
+			continue
+		}
+		if reflect.DeepEqual(w, cur[f]) || (!p.writeOnly && shelly.IsWriteOnlyConfigField(key, path)) {
+			continue
+		}
+		p.Changes = append(p.Changes, Change{Key: key, Path: path, Old: cur[f], New: w})
+		patch[f] = w
+	}
+	return patch
+}
+
+// Empty is true if the device is already in its desired state.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Keys returns the components which will be configured, sorted.
+func (p *Plan) Keys() []string {
+	keys := make([]string, 0, len(p.patches))
+	for key := range p.patches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteDiff writes the changes in a human readable form, for dry runs:
+//
+//	porch:
+//	  ~ switch:0
+//	      name: null -> "Porch"
+func (p *Plan) WriteDiff(w io.Writer) error {
+	var b bytes.Buffer
+	if p.Empty() {
+		fmt.Fprintf(&b, "%s: no changes\n", p.Target)
+	} else {
+		fmt.Fprintf(&b, "%s:\n", p.Target)
+		key := ""
+		for _, c := range p.Changes {
+			if c.Key != key {
+				key = c.Key
+				fmt.Fprintf(&b, "  ~ %s\n", key)
+			}
+			fmt.Fprintf(&b, "      %s: %s -> %s\n", c.Path, encode(c.Old), encode(c.New))
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// String returns the diff written by WriteDiff.
+func (p *Plan) String() string {
+	var b bytes.Buffer
+	p.WriteDiff(&b)
+	return b.String()
+}
+
+func encode(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
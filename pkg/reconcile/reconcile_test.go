@@ -0,0 +1,162 @@
+package reconcile_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+	"github.com/jcodybaker/go-shelly/pkg/reconcile"
+	"github.com/jcodybaker/go-shelly/pkg/shellysim"
+)
+
+func newDevice(t *testing.T, id string) (*shellysim.Device, *shelly.Device) {
+	specs, err := shelly.AppToDeviceSpecs("Pro4PM", "")
+	require.NoError(t, err)
+	sim := shellysim.New(specs, shellysim.WithModel("Pro4PM", "SPSW-104PE16EU"), shellysim.WithID(id))
+	return sim, shelly.NewDevice(sim, nil)
+}
+
+func TestPlanApply(t *testing.T) {
+	ctx := context.Background()
+	sim, dev := newDevice(t, "shellypro4pm-b8d61a000001")
+
+	desired := reconcile.State{}
+	require.NoError(t, desired.Set("switch:0", &shelly.SwitchConfig{
+		Name:    shelly.Some("Porch"),
+		AutoOff: shelly.BoolPtr(true),
+	}))
+	require.NoError(t, desired.Set("switch:0", map[string]any{"auto_off_delay": 60}))
+
+	plan, err := reconcile.NewPlan(ctx, "porch", dev, desired)
+	require.NoError(t, err)
+	// auto_off_delay is already 60.
+	assert.Equal(t, []reconcile.Change{
+		{Key: "switch:0", Path: "auto_off", Old: false, New: true},
+		{Key: "switch:0", Path: "name", Old: nil, New: "Porch"},
+	}, plan.Changes)
+	assert.Equal(t, "porch:\n  ~ switch:0\n      auto_off: false -> true\n      name: null -> \"Porch\"\n", plan.String())
+
+	// Planning is a dry run.
+	config, _ := sim.Config("switch:0")
+	assert.Nil(t, config["name"])
+
+	result, err := reconcile.Apply(ctx, []*reconcile.Plan{plan})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"porch"}, result.Changed)
+	assert.Empty(t, result.Rebooted)
+	config, _ = sim.Config("switch:0")
+	assert.Equal(t, "Porch", config["name"])
+	assert.Equal(t, true, config["auto_off"])
+
+	again, err := reconcile.NewPlan(ctx, "porch", dev, desired)
+	require.NoError(t, err)
+	assert.True(t, again.Empty())
+	assert.Equal(t, "porch: no changes\n", again.String())
+
+	_, err = reconcile.NewPlan(ctx, "porch", dev, reconcile.State{"cover:0": {"name": "x"}})
+	assert.ErrorIs(t, err, shelly.ErrComponentNotPresent)
+}
+
+func TestPlanIgnoresReadOnlyAndWriteOnlyFields(t *testing.T) {
+	ctx := context.Background()
+	sim, dev := newDevice(t, "shellypro4pm-b8d61a000001")
+
+	desired := reconcile.State{}
+	require.NoError(t, desired.Set("switch:2", &shelly.SwitchConfig{ID: 2, Name: shelly.Some("Fan")}))
+	require.NoError(t, desired.Set("wifi", &shelly.WifiConfig{STA: &shelly.WifiStationConfig{
+		SSID: shelly.Some("home"),
+		Pass: shelly.Some("hunter22"),
+	}}))
+
+	plan, err := reconcile.NewPlan(ctx, "fan", dev, desired)
+	require.NoError(t, err)
+	// The id is read-only and the password can't be compared, so neither is planned.
+	assert.Equal(t, []reconcile.Change{
+		{Key: "switch:2", Path: "name", Old: nil, New: "Fan"},
+		{Key: "wifi", Path: "sta.ssid", Old: nil, New: "home"},
+	}, plan.Changes)
+	_, err = reconcile.Apply(ctx, []*reconcile.Plan{plan})
+	require.NoError(t, err)
+
+	again, err := reconcile.NewPlan(ctx, "fan", dev, desired)
+	require.NoError(t, err)
+	assert.True(t, again.Empty())
+
+	// The password is sent when asked for, although the device never reports it.
+	forced, err := reconcile.NewPlan(ctx, "fan", dev, desired, reconcile.WithWriteOnlyFields())
+	require.NoError(t, err)
+	assert.Equal(t, []reconcile.Change{
+		{Key: "wifi", Path: "sta.pass", Old: nil, New: "hunter22"},
+	}, forced.Changes)
+	config, _ := sim.Config("wifi")
+	assert.NotContains(t, config["sta"], "pass")
+}
+
+func TestGroupRebootBatches(t *testing.T) {
+	ctx := context.Background()
+	var group reconcile.Group
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"base": {
+			"sys": {"device": {"name": "{{.Name}} ({{.Room}})"}},
+			"ble": {"enable": false},
+			"switch:0": {"auto_off": true}
+		},
+		"members": {
+			"garage": {"vars": {"Room": "outside"}},
+			"kitchen": {
+				"vars": {"Room": "inside"},
+				"overrides": {"switch:0": {"auto_off": false, "name": "{{.Name}} light"}}
+			}
+		}
+	}`), &group))
+
+	sims := map[string]*shellysim.Device{}
+	var plans []*reconcile.Plan
+	for i, name := range group.Names() {
+		sim, dev := newDevice(t, "shellypro4pm-b8d61a00000"+string(rune('1'+i)))
+		sims[name] = sim
+		desired, err := group.Resolve(name)
+		require.NoError(t, err)
+		plan, err := reconcile.NewPlan(ctx, name, dev, desired)
+		require.NoError(t, err)
+		plans = append(plans, plan)
+	}
+
+	result, err := reconcile.Apply(ctx, plans, reconcile.WithPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"garage", "kitchen"}, result.Changed)
+	assert.Equal(t, map[string][]string{"garage": {"ble"}, "kitchen": {"ble"}}, result.RestartRequired)
+	assert.Equal(t, []string{"garage", "kitchen"}, result.Rebooted)
+
+	sys, _ := sims["kitchen"].Config("sys")
+	assert.Equal(t, "kitchen (inside)", sys["device"].(map[string]any)["name"])
+	sw, _ := sims["kitchen"].Config("switch:0")
+	assert.Equal(t, "kitchen light", sw["name"])
+	assert.Equal(t, false, sw["auto_off"])
+	sw, _ = sims["garage"].Config("switch:0")
+	assert.Equal(t, true, sw["auto_off"])
+	status, _ := sims["garage"].Status("sys")
+	assert.Equal(t, false, status["restart_required"])
+
+	_, err = group.Resolve("attic")
+	assert.Error(t, err)
+}
+
+func TestApplyWithoutReboot(t *testing.T) {
+	ctx := context.Background()
+	sim, dev := newDevice(t, "shellypro4pm-b8d61a000001")
+	plan, err := reconcile.NewPlan(ctx, "porch", dev, reconcile.State{"ble": {"enable": false}})
+	require.NoError(t, err)
+
+	result, err := reconcile.Apply(ctx, []*reconcile.Plan{plan}, reconcile.WithoutReboot())
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"porch": {"ble"}}, result.RestartRequired)
+	assert.Empty(t, result.Rebooted)
+	status, _ := sim.Status("sys")
+	assert.Equal(t, true, status["restart_required"])
+}
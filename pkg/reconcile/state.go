@@ -0,0 +1,183 @@
+// Package reconcile brings Gen2 devices to a declared configuration. A State lists the desired
+// config of each component; NewPlan reads the device's config and computes the field-level
+// changes, which can be printed as a dry run, and Apply makes them with the components'
+// SetConfig methods, rebooting devices whose changes require it in batches:
+//
+//	desired := reconcile.State{}
+//	desired.Set("switch:0", &shelly.SwitchConfig{Name: shelly.Some("Porch")})
+//	plan, err := reconcile.NewPlan(ctx, "porch", dev, desired)
+//	...
+//	plan.WriteDiff(os.Stdout)
+//	result, err := reconcile.Apply(ctx, []*reconcile.Plan{plan})
+//
+// Fleets share a Group: a base State with per-device overrides and template variables.
+package reconcile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// State is the desired configuration of a device: a partial config per component key (ex.
+// "switch:0" or "sys"). Fields which aren't listed are left unchanged. It decodes from JSON in
+// the format of Shelly.GetConfig:
+//
+//	{"sys": {"device": {"name": "Porch"}}, "switch:0": {"auto_off": true}}
+type State map[string]map[string]any
+
+// Set merges config, a typed config (ex. *shelly.SwitchConfig) or a map, into the desired state
+// of the component with key. Only the fields which config encodes are set, so unset Optional
+// and nil pointer fields are left unchanged.
+func (s State) Set(key string, config any) error {
+	fields, err := toMap(config)
+	if err != nil {
+		return fmt.Errorf("encoding config of %s: %w", key, err)
+	}
+	if s[key] == nil {
+		s[key] = make(map[string]any)
+	}
+	merge(s[key], fields)
+	return nil
+}
+
+// Merge returns a copy of s with the fields of overrides merged in.
+func (s State) Merge(overrides State) State {
+	out := make(State, len(s))
+	for _, src := range []State{s, overrides} {
+		for key, config := range src {
+			if out[key] == nil {
+				out[key] = make(map[string]any)
+			}
+			merge(out[key], config)
+		}
+	}
+	return out
+}
+
+// Group is the desired state of a group of devices which share a Base state. String values in
+// the states may be text/template templates, ex. "{{.Name}} relay", which are expanded with the
+// member's Vars and its Name.
+type Group struct {
+	// Base is the state shared by every member.
+	Base State `json:"base"`
+
+	// Members are keyed by device name.
+	Members map[string]Member `json:"members"`
+}
+
+// Member is a device in a Group.
+type Member struct {
+	// Vars are the values available to templates, in addition to Name.
+	Vars map[string]any `json:"vars,omitempty"`
+
+	// Overrides are merged over the group's Base state.
+	Overrides State `json:"overrides,omitempty"`
+}
+
+// Names returns the names of the members, sorted.
+func (g *Group) Names() []string {
+	names := make([]string, 0, len(g.Members))
+	for name := range g.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the desired state of the member with name: the Base state with its Overrides
+// merged in and templates expanded.
+func (g *Group) Resolve(name string) (State, error) {
+	m, ok := g.Members[name]
+	if !ok {
+		return nil, fmt.Errorf("%q is not a member of the group", name)
+	}
+	data := map[string]any{}
+	for k, v := range m.Vars {
+		data[k] = v
+	}
+	data["Name"] = name
+	state := g.Base.Merge(m.Overrides)
+	for key, config := range state {
+		expanded, err := expand(config, data)
+		if err != nil {
+			return nil, fmt.Errorf("expanding %s for %q: %w", key, name, err)
+		}
+		state[key] = expanded.(map[string]any)
+	}
+	return state, nil
+}
+
+// expand executes the templates in the string values of v.
+func expand(v any, data map[string]any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		t, err := template.New("").Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			x, err := expand(e, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = x
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			x, err := expand(e, data)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = x
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// toMap encodes config as a JSON object, so values compare equal to decoded device config.
+func toMap(config any) (map[string]any, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// merge copies the fields of patch into dst, recursing into objects present in both.
+func merge(dst, patch map[string]any) {
+	for k, v := range patch {
+		pv, pok := v.(map[string]any)
+		dv, dok := dst[k].(map[string]any)
+		if pok && dok {
+			merge(dv, pv)
+			continue
+		}
+		if pok {
+			cp := make(map[string]any, len(pv))
+			merge(cp, pv)
+			v = cp
+		}
+		dst[k] = v
+	}
+}
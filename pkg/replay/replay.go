@@ -0,0 +1,209 @@
+// Package replay records calls made to a device into golden JSON files and serves them back,
+// so tests can run against captured device sessions without network access.
+//
+// Credentials are scrubbed from recorded params and responses with shelly.RedactMethodParams.
+// Replayed requests are matched by method and normalized params (scrubbed, with object keys
+// sorted and empty params treated as {}).
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+var (
+	// ErrNoInteraction is returned by a Player when no recorded interaction matches a call.
+	ErrNoInteraction = errors.New("no recorded interaction matches call")
+)
+
+// Session is the content of a golden file.
+type Session struct {
+	// Interactions are the recorded calls, in the order they were made.
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded call.
+type Interaction struct {
+	// Method is the name of the method called.
+	Method string `json:"method"`
+
+	// Params are the normalized, scrubbed params.
+	Params json.RawMessage `json:"params"`
+
+	// Response is the scrubbed result of a successful call.
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// Status is the device error code of a failed call.
+	Status int `json:"status,omitempty"`
+
+	// StatusMsg is the device error message of a failed call.
+	StatusMsg string `json:"status_msg,omitempty"`
+}
+
+// Normalize scrubs the params of a call to method and re-encodes them with sorted keys so that
+// equivalent params compare equal. Empty and null params are normalized to {}.
+func Normalize(method string, params json.RawMessage) json.RawMessage {
+	var v any
+	if err := json.Unmarshal(shelly.RedactMethodParams(method, params), &v); err != nil || v == nil {
+		return json.RawMessage("{}")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return b
+}
+
+// Recorder implements shelly.Caller, passing calls to a wrapped caller and recording them.
+type Recorder struct {
+	c    shelly.Caller
+	path string
+
+	mu      sync.Mutex
+	session Session
+}
+
+// NewRecorder wraps c, recording calls to the golden file at path when Save or Disconnect is
+// called.
+func NewRecorder(c shelly.Caller, path string) *Recorder {
+	return &Recorder{c: c, path: path}
+}
+
+// Call implements shelly.Caller. Calls which fail without a response are not recorded.
+func (r *Recorder) Call(
+	ctx context.Context,
+	f *shelly.RPCFrame,
+	getCreds shelly.GetCredsCallback,
+) (*shelly.RPCFrame, error) {
+	resp, err := r.c.Call(ctx, f, getCreds)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	i := &Interaction{
+		Method: f.Method,
+		Params: Normalize(f.Method, f.Params),
+	}
+	if resp.Error != nil {
+		i.Status = resp.Error.Code
+		i.StatusMsg = resp.Error.Message
+	} else {
+		i.Response = shelly.RedactMethodParams(f.Method, resp.Result)
+	}
+	r.mu.Lock()
+	r.session.Interactions = append(r.session.Interactions, i)
+	r.mu.Unlock()
+	return resp, err
+}
+
+// Session returns the interactions recorded so far.
+func (r *Recorder) Session() *Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Session{Interactions: append([]*Interaction(nil), r.session.Interactions...)}
+}
+
+// Save writes the recorded interactions to the golden file.
+func (r *Recorder) Save() error {
+	b, err := json.MarshalIndent(r.Session(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing golden file: %w", err)
+	}
+	return nil
+}
+
+// Disconnect saves the recording, then disconnects the wrapped caller if it's a *shelly.Client
+// or other caller with a Disconnect method.
+func (r *Recorder) Disconnect(ctx context.Context) error {
+	if err := r.Save(); err != nil {
+		return err
+	}
+	if d, ok := r.c.(interface{ Disconnect(context.Context) error }); ok {
+		return d.Disconnect(ctx)
+	}
+	return nil
+}
+
+// Player implements shelly.Caller, answering calls from a recorded session. Each call is
+// answered by the first unused interaction with the same method and normalized params. Once
+// all matching interactions are used the last is repeated, so polling loops can be replayed.
+type Player struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewPlayer builds a Player serving the session.
+func NewPlayer(s *Session) *Player {
+	return &Player{interactions: s.Interactions, used: make([]bool, len(s.Interactions))}
+}
+
+// Load reads a golden file and builds a Player serving it.
+func Load(path string) (*Player, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading golden file: %w", err)
+	}
+	var s Session
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("parsing golden file %q: %w", path, err)
+	}
+	return NewPlayer(&s), nil
+}
+
+// Call implements shelly.Caller.
+func (p *Player) Call(
+	ctx context.Context,
+	f *shelly.RPCFrame,
+	getCreds shelly.GetCredsCallback,
+) (*shelly.RPCFrame, error) {
+	params := string(Normalize(f.Method, f.Params))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last := -1
+	for i, in := range p.interactions {
+		if in.Method != f.Method || string(Normalize(in.Method, in.Params)) != params {
+			continue
+		}
+		last = i
+		if !p.used[i] {
+			break
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, f.Method, params)
+	}
+	p.used[last] = true
+	in := p.interactions[last]
+	if in.Status != 0 {
+		return &shelly.RPCFrame{Error: &shelly.RPCFrameError{Code: in.Status, Message: in.StatusMsg}}, nil
+	}
+	return &shelly.RPCFrame{Result: in.Response}, nil
+}
+
+// Unused returns the interactions which have not been replayed, which can indicate a test no
+// longer makes calls it used to.
+func (p *Player) Unused() []*Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []*Interaction
+	for i, in := range p.interactions {
+		if !p.used[i] {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+// Disconnect is a no-op, so a Player can stand in for a Recorder.
+func (p *Player) Disconnect(ctx context.Context) error {
+	return nil
+}
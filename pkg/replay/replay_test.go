@@ -0,0 +1,109 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// stubRPC answers every call with a fixed response per method.
+type stubRPC struct {
+	responses    map[string]*shelly.RPCFrame
+	disconnected bool
+}
+
+func (s *stubRPC) Call(
+	ctx context.Context,
+	f *shelly.RPCFrame,
+	getCreds shelly.GetCredsCallback,
+) (*shelly.RPCFrame, error) {
+	return s.responses[f.Method], nil
+}
+
+func (s *stubRPC) Disconnect(ctx context.Context) error {
+	s.disconnected = true
+	return nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	golden := filepath.Join(t.TempDir(), "session.json")
+	stub := &stubRPC{responses: map[string]*shelly.RPCFrame{
+		"Wifi.GetConfig": {
+			Result: json.RawMessage(`{"sta":{"ssid":"home","pass":"hunter2","enable":true}}`),
+		},
+		"Shelly.SetAuth": {Result: json.RawMessage(`null`)},
+		"Switch.Set":     {Error: &shelly.RPCFrameError{Code: -109, Message: "overpower"}},
+	}}
+	rec := NewRecorder(stub, golden)
+
+	_, _, err := (&shelly.WifiGetConfigRequest{}).Do(ctx, rec, nil)
+	require.NoError(t, err)
+	_, err = rec.Call(ctx, &shelly.RPCFrame{
+		Method: "Shelly.SetAuth",
+		Params: json.RawMessage(`{"user":"admin","realm":"shellypro4pm-abc","ha1":"0123abcd"}`),
+	}, nil)
+	require.NoError(t, err)
+	_, _, err = (&shelly.SwitchSetRequest{ID: 0, On: true}).Do(ctx, rec, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCFailedPrecondition)
+	require.NoError(t, rec.Disconnect(ctx))
+	assert.True(t, stub.disconnected)
+
+	b, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "hunter2")
+	assert.NotContains(t, string(b), "0123abcd")
+
+	p, err := Load(golden)
+	require.NoError(t, err)
+	// Params are matched regardless of key order.
+	resp, err := p.Call(ctx, &shelly.RPCFrame{
+		Method: "Shelly.SetAuth",
+		Params: json.RawMessage(`{"ha1":"different","realm":"shellypro4pm-abc","user":"admin"}`),
+	}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, resp.Error)
+
+	cfg, _, err := (&shelly.WifiGetConfigRequest{}).Do(ctx, p, nil)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.STA)
+	assert.Equal(t, "home", cfg.STA.SSID.Or(""))
+
+	_, _, err = (&shelly.SwitchSetRequest{ID: 0, On: true}).Do(ctx, p, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCFailedPrecondition)
+	assert.Empty(t, p.Unused())
+
+	_, _, err = (&shelly.SwitchSetRequest{ID: 1, On: true}).Do(ctx, p, nil)
+	assert.ErrorIs(t, err, ErrNoInteraction)
+}
+
+func TestPlayerOrdering(t *testing.T) {
+	ctx := context.Background()
+	p, err := Load(filepath.Join("testdata", "switch_session.json"))
+	require.NoError(t, err)
+
+	status, _, err := (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, p, nil)
+	require.NoError(t, err)
+	assert.False(t, *status.Output)
+
+	set, _, err := (&shelly.SwitchSetRequest{ID: 0, On: true}).Do(ctx, p, nil)
+	require.NoError(t, err)
+	assert.False(t, set.WasOn)
+
+	// Matching interactions are served in recorded order, then the last is repeated.
+	for i := 0; i < 2; i++ {
+		status, _, err = (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, p, nil)
+		require.NoError(t, err)
+		assert.True(t, *status.Output)
+	}
+
+	_, _, err = (&shelly.SwitchGetStatusRequest{ID: 3}).Do(ctx, p, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnknownComponentID)
+}
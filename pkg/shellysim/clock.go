@@ -0,0 +1,121 @@
+package shellysim
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides time to a Device, so tests can control timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc calls f once d has elapsed. f is never called from within AfterFunc, as the
+	// caller may hold locks f needs.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call scheduled by a Clock.
+type Timer interface {
+	// Stop prevents the call, returning false if it has already been made or stopped.
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// VirtualClock is a Clock which only moves when advanced, so tests of timers and motion run
+// instantly and deterministically. Timers fire from Advance, in the goroutine calling it.
+type VirtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int
+	timers []*virtualTimer
+}
+
+type virtualTimer struct {
+	c    *VirtualClock
+	when time.Time
+	seq  int
+	f    func()
+}
+
+// NewVirtualClock returns a clock stopped at start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now implements Clock.
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc implements Clock. f is called by the Advance which moves the clock past its
+// deadline.
+func (c *VirtualClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &virtualTimer{c: c, when: c.now.Add(d), seq: c.seq, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, calling the functions of timers which expire in order
+// of their deadlines. The clock reads each timer's deadline while its function runs, and timers
+// scheduled by those functions fire too if they expire within d.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			if c.timers[i].when.Equal(c.timers[j].when) {
+				return c.timers[i].seq < c.timers[j].seq
+			}
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+		if len(c.timers) == 0 || c.timers[0].when.After(end) {
+			break
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// Pending returns the number of timers which haven't fired or been stopped.
+func (c *VirtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Stop implements Timer.
+func (t *virtualTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	for i, pending := range t.c.timers {
+		if pending == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,557 @@
+package shellysim
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// componentTypes maps the method namespace of each emulated component type to its key prefix.
+// Every type supports GetStatus, GetConfig and SetConfig.
+var componentTypes = map[string]string{
+	"Sys":         "sys",
+	"Wifi":        "wifi",
+	"Eth":         "eth",
+	"BLE":         "ble",
+	"Input":       "input",
+	"Switch":      "switch",
+	"Cover":       "cover",
+	"Light":       "light",
+	"Temperature": "temperature",
+	"Humidity":    "humidity",
+	"DevicePower": "devicepower",
+	"Script":      "script",
+
+	"Boolean":      "boolean",
+	"Number":       "number",
+	"Text":         "text",
+	"Enum":         "enum",
+	"Group":        "group",
+	"Button":       "button",
+	"BTHomeDevice": "bthomedevice",
+	"BTHomeSensor": "bthomesensor",
+}
+
+// restartFields are the config fields of each component type (or "*" for any) whose change
+// requires a restart, as reported by SetConfig and sys.restart_required.
+var restartFields = map[string]string{
+	"ble": "*",
+	"sys": "rpc_udp",
+}
+
+// unauthenticatedMethods may be called without credentials when authentication is enabled.
+var unauthenticatedMethods = map[string]bool{
+	"Shelly.GetDeviceInfo": true,
+}
+
+// methods are the handlers for methods which aren't the generic component GetStatus, GetConfig
+// and SetConfig. Handlers are called with d.mu held.
+var methods map[string]func(d *Device, params json.RawMessage) (any, error)
+
+func init() {
+	// Assigned in init as Shelly.ListMethods refers back to the table.
+	methods = map[string]func(d *Device, params json.RawMessage) (any, error){
+		"Shelly.GetDeviceInfo": (*Device).shellyGetDeviceInfo,
+		"Shelly.GetStatus":     (*Device).shellyGetStatus,
+		"Shelly.GetConfig":     (*Device).shellyGetConfig,
+		"Shelly.GetComponents": (*Device).shellyGetComponents,
+		"Shelly.ListMethods":   (*Device).shellyListMethods,
+		"Shelly.SetAuth":       (*Device).shellySetAuth,
+		"Shelly.Reboot":        (*Device).shellyReboot,
+		"Shelly.SetProfile":    (*Device).shellySetProfile,
+
+		"Switch.Set":    (*Device).switchSet,
+		"Switch.Toggle": (*Device).switchToggle,
+		"Light.Set":     (*Device).lightSet,
+		"Light.Toggle":  (*Device).lightToggle,
+
+		"Cover.Open":         (*Device).coverOpen,
+		"Cover.Close":        (*Device).coverClose,
+		"Cover.Stop":         (*Device).coverStop,
+		"Cover.GoToPosition": (*Device).coverGoToPosition,
+		"Cover.Calibrate":    (*Device).coverCalibrate,
+
+		"Script.Create":  (*Device).scriptCreate,
+		"Script.PutCode": (*Device).scriptPutCode,
+		"Script.GetCode": (*Device).scriptGetCode,
+		"Script.Start":   (*Device).scriptStart,
+		"Script.Stop":    (*Device).scriptStop,
+		"Script.List":    (*Device).scriptList,
+		"Script.Delete":  (*Device).scriptDelete,
+
+		"KVS.Set":     (*Device).kvsSet,
+		"KVS.Get":     (*Device).kvsGet,
+		"KVS.GetMany": (*Device).kvsGetMany,
+		"KVS.List":    (*Device).kvsList,
+		"KVS.Delete":  (*Device).kvsDelete,
+
+		"Schedule.Create":    (*Device).scheduleCreate,
+		"Schedule.Update":    (*Device).scheduleUpdate,
+		"Schedule.List":      (*Device).scheduleList,
+		"Schedule.Delete":    (*Device).scheduleDelete,
+		"Schedule.DeleteAll": (*Device).scheduleDeleteAll,
+
+		"Webhook.Create":    (*Device).webhookCreate,
+		"Webhook.Update":    (*Device).webhookUpdate,
+		"Webhook.List":      (*Device).webhookList,
+		"Webhook.Delete":    (*Device).webhookDelete,
+		"Webhook.DeleteAll": (*Device).webhookDeleteAll,
+
+		"Virtual.Add":         (*Device).virtualAdd,
+		"Virtual.Delete":      (*Device).virtualDelete,
+		"BTHome.AddDevice":    (*Device).bthomeAddDevice,
+		"BTHome.DeleteDevice": (*Device).bthomeDeleteDevice,
+		"BTHome.AddSensor":    (*Device).bthomeAddSensor,
+		"BTHome.DeleteSensor": (*Device).bthomeDeleteSensor,
+	}
+}
+
+// addComponents creates the components described by specs with factory default config.
+func (d *Device) addComponents(specs shelly.DeviceSpecs) {
+	profile := any(nil)
+	if specs.IsMultiProfile() {
+		profile = "switch"
+		if specs.Covers > 0 && specs.Switches == 0 {
+			profile = "cover"
+		}
+	}
+	d.components["sys"] = &component{
+		config: map[string]any{
+			"device": map[string]any{
+				"name":         nil,
+				"eco_mode":     false,
+				"mac":          d.mac,
+				"fw_id":        defaultFirmwareID,
+				"profile":      profile,
+				"discoverable": true,
+			},
+			"location": map[string]any{"tz": nil, "lat": nil, "lon": nil},
+			"debug": map[string]any{
+				"mqtt":       map[string]any{"enable": false},
+				"websocket":  map[string]any{"enable": false},
+				"udp":        map[string]any{"addr": nil},
+				"level":      2,
+				"file_level": nil,
+			},
+			"ui_data": map[string]any{},
+			"rpc_udp": map[string]any{"dst_addr": nil, "listen_port": nil},
+			"sntp":    map[string]any{"server": "time.google.com"},
+			"cfg_rev": d.cfgRev,
+		},
+		status: map[string]any{
+			"mac":               d.mac,
+			"restart_required":  false,
+			"time":              nil,
+			"unixtime":          nil,
+			"uptime":            0,
+			"ram_size":          245016,
+			"ram_free":          150000,
+			"fs_size":           524288,
+			"fs_free":           188416,
+			"cfg_rev":           d.cfgRev,
+			"kvs_rev":           d.kvsRev,
+			"schedule_rev":      d.scheduleRev,
+			"webhook_rev":       d.webhookRev,
+			"available_updates": map[string]any{},
+		},
+	}
+	if specs.Wifi {
+		d.components["wifi"] = &component{
+			config: map[string]any{
+				"ap": map[string]any{
+					"ssid": d.id, "is_open": true, "enable": false,
+					"range_extender": map[string]any{"enable": false},
+				},
+				"sta":  map[string]any{"ssid": nil, "is_open": true, "enable": false, "ipv4mode": "dhcp"},
+				"sta1": map[string]any{"ssid": nil, "is_open": true, "enable": false, "ipv4mode": "dhcp"},
+				"roam": map[string]any{"rssi_thr": -80, "interval": 60},
+			},
+			status: map[string]any{"sta_ip": nil, "status": "disconnected", "ssid": nil, "rssi": 0},
+		}
+	}
+	if specs.Ethernet {
+		d.components["eth"] = &component{
+			config: map[string]any{"enable": true, "ipv4mode": "dhcp"},
+			status: map[string]any{"ip": "127.0.0.1"},
+		}
+	}
+	if specs.BluetoothLowEnergy {
+		d.components["ble"] = &component{
+			config: map[string]any{"enable": true, "rpc": map[string]any{"enable": true}},
+			status: map[string]any{},
+		}
+	}
+	for i := 0; i < specs.Inputs; i++ {
+		d.components[fmt.Sprintf("input:%d", i)] = &component{
+			config: map[string]any{
+				"id": i, "name": nil, "type": "switch", "enable": true, "invert": false,
+				"factory_reset": true,
+			},
+			status: map[string]any{"id": i, "state": false},
+		}
+	}
+	for i := 0; i < specs.Switches; i++ {
+		c := &component{
+			config: map[string]any{
+				"id": i, "name": nil, "in_mode": "follow", "initial_state": "match_input",
+				"auto_on": false, "auto_on_delay": 60.0, "auto_off": false, "auto_off_delay": 60.0,
+			},
+			status: map[string]any{
+				"id": i, "source": "init", "output": false,
+				"temperature": map[string]any{"tC": 40.0, "tF": 104.0},
+			},
+		}
+		if specs.SwitchEnergy {
+			c.config["power_limit"] = 4480.0
+			c.config["voltage_limit"] = 280.0
+			c.config["current_limit"] = 16.0
+			c.status["apower"] = 0.0
+			c.status["voltage"] = 230.0
+			c.status["current"] = 0.0
+			c.status["pf"] = 0.0
+			c.status["freq"] = 50.0
+			c.status["aenergy"] = map[string]any{"total": 0.0, "by_minute": []any{0.0, 0.0, 0.0}}
+		}
+		d.components[fmt.Sprintf("switch:%d", i)] = c
+	}
+	for i := 0; i < specs.Covers; i++ {
+		key := fmt.Sprintf("cover:%d", i)
+		d.components[key] = newCover(i)
+		d.covers[key] = &coverMotion{}
+	}
+	for i := 0; i < specs.Lights; i++ {
+		d.components[fmt.Sprintf("light:%d", i)] = &component{
+			config: map[string]any{
+				"id": i, "name": nil, "initial_state": "restore_last",
+				"auto_on": false, "auto_on_delay": 60.0, "auto_off": false, "auto_off_delay": 60.0,
+				"transition_duration": 3.0, "min_brightness_on_toggle": 3.0,
+				"night_mode": map[string]any{"enable": false, "brightness": 50.0, "active_between": []any{}},
+			},
+			status: map[string]any{
+				"id": i, "source": "init", "output": false, "brightness": 50.0,
+				"temperature": map[string]any{"tC": 40.0, "tF": 104.0},
+			},
+		}
+	}
+	if specs.Temperature {
+		d.components["temperature:0"] = &component{
+			config: map[string]any{"id": 0, "name": nil, "report_thr_C": 1.0, "offset_C": 0.0},
+			status: map[string]any{"id": 0, "tC": 21.0, "tF": 69.8},
+		}
+	}
+	if specs.Humidity {
+		d.components["humidity:0"] = &component{
+			config: map[string]any{"id": 0, "name": nil, "report_thr": 5.0, "offset": 0.0},
+			status: map[string]any{"id": 0, "rh": 45.0},
+		}
+	}
+	if specs.DevicePower {
+		d.components["devicepower:0"] = &component{
+			config: map[string]any{"id": 0},
+			status: map[string]any{
+				"id":       0,
+				"battery":  map[string]any{"V": 6.0, "percent": 100},
+				"external": map[string]any{"present": false},
+			},
+		}
+	}
+}
+
+// hasComponentType returns true if the device has a component of kind. d.mu must be held.
+func (d *Device) hasComponentType(kind string) bool {
+	if _, ok := d.components[kind]; ok {
+		return true
+	}
+	for k := range d.components {
+		if strings.HasPrefix(k, kind+":") {
+			return true
+		}
+	}
+	return kind == "script" && d.maxScripts > 0
+}
+
+// keys returns the sorted component keys. d.mu must be held.
+func (d *Device) keys() []string {
+	keys := make([]string, 0, len(d.components))
+	for k := range d.components {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookup finds the component of kind addressed by params. Singletons (ex. sys) have no id.
+// d.mu must be held.
+func (d *Device) lookup(kind string, params json.RawMessage) (string, *component, error) {
+	if c, ok := d.components[kind]; ok {
+		return kind, c, nil
+	}
+	var p struct {
+		ID *int `json:"id"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return "", nil, err
+	}
+	if p.ID == nil {
+		return "", nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'id'!")
+	}
+	key := fmt.Sprintf("%s:%d", kind, *p.ID)
+	c, ok := d.components[key]
+	if !ok {
+		return "", nil, rpcError(shelly.ErrRPCUnknownComponentID,
+			fmt.Sprintf("Argument 'id', value %d not found!", *p.ID))
+	}
+	return key, c, nil
+}
+
+func (d *Device) getStatus(kind string, params json.RawMessage) (any, error) {
+	_, c, err := d.lookup(kind, params)
+	if err != nil {
+		return nil, err
+	}
+	d.refreshStatus()
+	return deepCopy(c.status), nil
+}
+
+func (d *Device) getConfig(kind string, params json.RawMessage) (any, error) {
+	_, c, err := d.lookup(kind, params)
+	if err != nil {
+		return nil, err
+	}
+	return deepCopy(c.config), nil
+}
+
+func (d *Device) setConfig(kind string, params json.RawMessage) (any, error) {
+	key, c, err := d.lookup(kind, params)
+	if err != nil {
+		return nil, err
+	}
+	var p struct {
+		Config map[string]any `json:"config"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Config == nil {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'config'!")
+	}
+	delete(p.Config, "id")
+	delete(p.Config, "cfg_rev")
+	merge(c.config, p.Config)
+	// Write-only fields, ex. passwords, are accepted but never reported.
+	shelly.StripWriteOnlyConfig(key, c.config)
+	if key == "sys" {
+		if dev, ok := c.config["device"].(map[string]any); ok {
+			// The MAC and firmware are read-only.
+			dev["mac"], dev["fw_id"] = d.mac, defaultFirmwareID
+		}
+	}
+	field, restart := restartFields[kind]
+	if restart && field != "*" {
+		_, restart = p.Config[field]
+	}
+	if restart {
+		d.restart = true
+		d.components["sys"].status["restart_required"] = true
+	}
+	d.configChanged()
+	return map[string]any{"restart_required": restart}, nil
+}
+
+// configChanged bumps cfg_rev and notifies subscribers. d.mu must be held.
+func (d *Device) configChanged() {
+	d.cfgRev++
+	sys := d.components["sys"]
+	sys.config["cfg_rev"] = d.cfgRev
+	sys.status["cfg_rev"] = d.cfgRev
+	d.notifyStatus("sys", map[string]any{"cfg_rev": d.cfgRev})
+	d.notifyEvent("sys", "config_changed", map[string]any{"cfg_rev": d.cfgRev, "restart_required": d.restart})
+}
+
+// refreshStatus updates the time-dependent fields of the status. d.mu must be held.
+func (d *Device) refreshStatus() {
+	d.refreshSys()
+	d.refreshCovers()
+}
+
+// refreshSys updates the time-dependent fields of the sys status. d.mu must be held.
+func (d *Device) refreshSys() {
+	now := d.clock.Now()
+	s := d.components["sys"].status
+	s["uptime"] = int(now.Sub(d.bootTime).Seconds())
+	s["unixtime"] = now.Unix()
+	s["time"] = now.Format("15:04")
+}
+
+func (d *Device) shellyGetDeviceInfo(params json.RawMessage) (any, error) {
+	info := map[string]any{
+		"name":        d.components["sys"].config["device"].(map[string]any)["name"],
+		"id":          d.id,
+		"mac":         d.mac,
+		"slot":        0,
+		"model":       d.model,
+		"gen":         2,
+		"fw_id":       defaultFirmwareID,
+		"ver":         DefaultFirmwareVersion,
+		"app":         d.app,
+		"auth_en":     d.ha1 != "",
+		"auth_domain": nil,
+	}
+	if d.ha1 != "" {
+		info["auth_domain"] = d.id
+	}
+	if profile := d.components["sys"].config["device"].(map[string]any)["profile"]; profile != nil {
+		info["profile"] = profile
+	}
+	return info, nil
+}
+
+func (d *Device) shellyGetStatus(params json.RawMessage) (any, error) {
+	d.refreshStatus()
+	out := make(map[string]any, len(d.components))
+	for k, c := range d.components {
+		out[k] = deepCopy(c.status)
+	}
+	return out, nil
+}
+
+func (d *Device) shellyGetConfig(params json.RawMessage) (any, error) {
+	out := make(map[string]any, len(d.components))
+	for k, c := range d.components {
+		out[k] = deepCopy(c.config)
+	}
+	return out, nil
+}
+
+func (d *Device) shellyGetComponents(params json.RawMessage) (any, error) {
+	var p struct {
+		Offset      int      `json:"offset"`
+		Include     []string `json:"include"`
+		DynamicOnly bool     `json:"dynamic_only"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	d.refreshStatus()
+	keys := d.keys()
+	if p.DynamicOnly {
+		var dynamic []string
+		for _, k := range keys {
+			if isDynamic(k) {
+				dynamic = append(dynamic, k)
+			}
+		}
+		keys = dynamic
+	}
+	comps := []any{}
+	for i, k := range keys {
+		if i < p.Offset {
+			continue
+		}
+		entry := map[string]any{"key": k}
+		for _, inc := range p.Include {
+			switch inc {
+			case "status":
+				entry["status"] = deepCopy(d.components[k].status)
+			case "config":
+				entry["config"] = deepCopy(d.components[k].config)
+			}
+		}
+		comps = append(comps, entry)
+	}
+	return map[string]any{
+		"components": comps,
+		"cfg_rev":    d.cfgRev,
+		"offset":     p.Offset,
+		"total":      len(keys),
+	}, nil
+}
+
+func (d *Device) shellyListMethods(params json.RawMessage) (any, error) {
+	return map[string]any{"methods": d.listMethods()}, nil
+}
+
+func (d *Device) shellySetAuth(params json.RawMessage) (any, error) {
+	var p shelly.ShellySetAuthRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.User != shelly.DefaultAuthenticationUsername {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Argument 'user' must be 'admin'!")
+	}
+	if p.Realm != d.id {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Argument 'realm' must be the device id!")
+	}
+	if p.HA1 == nil {
+		d.ha1 = ""
+	} else {
+		d.ha1 = *p.HA1
+	}
+	return nil, nil
+}
+
+// shellyReboot applies pending changes which require a restart. The device stays connected.
+func (d *Device) shellyReboot(params json.RawMessage) (any, error) {
+	d.restart = false
+	d.components["sys"].status["restart_required"] = false
+	d.bootTime = d.clock.Now()
+	return nil, nil
+}
+
+// shellySetProfile changes sys.device.profile. Unlike the device, the components aren't
+// rebuilt for the new profile.
+func (d *Device) shellySetProfile(params json.RawMessage) (any, error) {
+	var p shelly.ShellySetProfileRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	dev := d.components["sys"].config["device"].(map[string]any)
+	was, ok := dev["profile"].(string)
+	if !ok {
+		return nil, rpcError(shelly.ErrRPCNoHandler, "No handler for Shelly.SetProfile")
+	}
+	if p.Profile != "switch" && p.Profile != "cover" {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments,
+			fmt.Sprintf("Argument 'profile', value %q not supported!", p.Profile))
+	}
+	if p.Profile != was {
+		dev["profile"] = p.Profile
+		d.configChanged()
+	}
+	return map[string]any{"profile_was": was}, nil
+}
+
+// merge copies the fields of patch into dst, recursing into objects present in both.
+func merge(dst, patch map[string]any) {
+	for k, v := range patch {
+		pv, pok := v.(map[string]any)
+		dv, dok := dst[k].(map[string]any)
+		if pok && dok {
+			merge(dv, pv)
+			continue
+		}
+		dst[k] = deepCopy(v)
+	}
+}
+
+// deepCopy copies the maps and slices of a decoded JSON value.
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = deepCopy(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = deepCopy(e)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,499 @@
+package shellysim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+const (
+	// DefaultCoverTravelTime is how long emulated covers take to move between fully closed and
+	// fully open.
+	DefaultCoverTravelTime = 30 * time.Second
+
+	// coverMotorPower is the power drawn by a moving cover motor, in watts.
+	coverMotorPower = 120.0
+
+	coverOpen  = "open"
+	coverClose = "close"
+)
+
+// WithCoverTravelTime sets how long covers physically take to move from fully closed to fully
+// open, and back.
+func WithCoverTravelTime(open, close time.Duration) Option {
+	return func(d *Device) {
+		d.coverOpenTime, d.coverCloseTime = open, close
+	}
+}
+
+// NewCoverDevice builds a device with a single cover and two inputs, like a Shelly Plus 2PM in
+// cover profile. Combine with WithClock(NewVirtualClock(...)) to test automations instantly.
+func NewCoverDevice(opts ...Option) *Device {
+	specs, _ := shelly.AppToDeviceSpecs("Plus2PM", "cover")
+	return New(specs, append([]Option{WithModel("Plus2PM", "SNSW-102P16EU")}, opts...)...)
+}
+
+// coverMotion is the physical state of a cover. The device only learns the position through
+// calibration, so it's tracked separately from the component status.
+type coverMotion struct {
+	// pos is the position, from 0 (closed) to 100 (open), when the current movement started or
+	// the cover stopped.
+	pos float64
+
+	// direction is coverOpen or coverClose while moving, and empty when stopped.
+	direction string
+
+	// startedAt is when the current movement started.
+	startedAt time.Time
+
+	// calibrating is true while Cover.Calibrate runs; the cover closes fully, then opens fully.
+	calibrating bool
+
+	// obstacle, if set, is a position the cover can't move past.
+	obstacle *float64
+
+	// safety is true while the safety switch is engaged.
+	safety bool
+
+	// paused is the movement interrupted by the safety switch with the "pause" action.
+	paused *coverResume
+}
+
+// coverResume is a movement to continue when the safety switch is released.
+type coverResume struct {
+	direction string
+	target    *float64
+}
+
+// newCover builds a cover component with factory default config. Covers start uncalibrated,
+// so positioning is unavailable until Cover.Calibrate.
+func newCover(id int) *component {
+	return &component{
+		config: map[string]any{
+			"id": id, "name": nil, "in_mode": "dual", "initial_state": "stopped",
+			"power_limit": 2800.0, "voltage_limit": 280.0, "current_limit": 10.0,
+			"motor":          map[string]any{"idle_power_thr": 2.0, "idle_confirm_period": 0.25},
+			"max_time_open":  60.0,
+			"max_time_close": 60.0,
+			"swap_inputs":    false, "invert_directions": false,
+			"obstruction_detection": map[string]any{
+				"enable": false, "direction": "both", "action": "stop", "power_thr": 1000.0, "holdoff": 1.0,
+			},
+			"safety_switch": map[string]any{
+				"enable": false, "direction": "both", "action": "stop", "allowed_move": nil,
+			},
+		},
+		status: map[string]any{
+			"id": id, "source": "init", "state": "stopped", "apower": 0.0, "voltage": 230.0,
+			"current": 0.0, "pf": 0.0, "freq": 50.0,
+			"aenergy":        map[string]any{"total": 0.0, "by_minute": []any{0.0, 0.0, 0.0}},
+			"pos_control":    false,
+			"last_direction": nil,
+			"temperature":    map[string]any{"tC": 40.0, "tF": 104.0},
+		},
+	}
+}
+
+// SetCoverObstacle places an obstacle (ex. an object on a window sill) at pos, which the cover
+// can't move past. Obstruction detection, if enabled, stops the cover when it reaches the
+// obstacle; otherwise the motor stalls until the move times out. A nil pos removes the
+// obstacle. The obstacle applies to movements started after the call.
+func (d *Device) SetCoverObstacle(id int, pos *float64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	m, ok := d.covers[fmt.Sprintf("cover:%d", id)]
+	if !ok {
+		return fmt.Errorf("unknown cover %d", id)
+	}
+	if pos != nil {
+		p := *pos
+		pos = &p
+	}
+	m.obstacle = pos
+	return nil
+}
+
+// SetCoverSafetySwitch engages or releases the safety switch of a cover. When enabled in the
+// cover's config, engaging it while moving in the configured direction stops, reverses or
+// pauses the cover according to safety_switch.action, and blocks further movement in that
+// direction except as permitted by safety_switch.allowed_move.
+func (d *Device) SetCoverSafetySwitch(id int, engaged bool) error {
+	d.mu.Lock()
+	key := fmt.Sprintf("cover:%d", id)
+	m, ok := d.covers[key]
+	if !ok {
+		d.mu.Unlock()
+		return fmt.Errorf("unknown cover %d", id)
+	}
+	c := d.components[key]
+	m.safety = engaged
+	cfg, _ := c.config["safety_switch"].(map[string]any)
+	if boolField(cfg, "enable") {
+		if engaged && m.direction != "" && directionMatches(cfg["direction"], m.direction) {
+			direction, target := m.direction, d.coverTarget(c)
+			d.finishCoverMove(key, c, m, "safety_switch")
+			switch cfg["action"] {
+			case "reverse":
+				d.startCoverMove(key, c, m, opposite(direction), nil, nil, "safety_switch")
+			case "pause":
+				m.paused = &coverResume{direction: direction, target: target}
+			}
+		} else if !engaged && m.paused != nil {
+			resume := m.paused
+			m.paused = nil
+			d.startCoverMove(key, c, m, resume.direction, resume.target, nil, "safety_switch")
+		}
+	}
+	d.unlockAndFlush()
+	return nil
+}
+
+// lookupCover finds the cover addressed by params. d.mu must be held.
+func (d *Device) lookupCover(params json.RawMessage) (string, *component, *coverMotion, error) {
+	key, c, err := d.lookup("cover", params)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return key, c, d.covers[key], nil
+}
+
+func (d *Device) coverOpen(params json.RawMessage) (any, error) {
+	return d.coverMove(params, coverOpen)
+}
+
+func (d *Device) coverClose(params json.RawMessage) (any, error) {
+	return d.coverMove(params, coverClose)
+}
+
+func (d *Device) coverMove(params json.RawMessage, direction string) (any, error) {
+	key, c, m, err := d.lookupCover(params)
+	if err != nil {
+		return nil, err
+	}
+	var p shelly.CoverOpenRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Duration != nil && *p.Duration <= 0 {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Argument 'duration' must be positive!")
+	}
+	if err := d.checkSafetySwitch(c, m, direction); err != nil {
+		return nil, err
+	}
+	d.startCoverMove(key, c, m, direction, nil, p.Duration, sourceRPC)
+	return nil, nil
+}
+
+func (d *Device) coverStop(params json.RawMessage) (any, error) {
+	key, c, m, err := d.lookupCover(params)
+	if err != nil {
+		return nil, err
+	}
+	m.paused = nil
+	if m.direction != "" {
+		d.finishCoverMove(key, c, m, sourceRPC)
+	}
+	return nil, nil
+}
+
+func (d *Device) coverGoToPosition(params json.RawMessage) (any, error) {
+	key, c, m, err := d.lookupCover(params)
+	if err != nil {
+		return nil, err
+	}
+	var p shelly.CoverGoToPositionRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if !boolField(c.status, "pos_control") {
+		return nil, rpcError(shelly.ErrRPCFailedPrecondition, "Cover is not calibrated!")
+	}
+	current := m.position(d)
+	var target float64
+	switch {
+	case p.Pos != nil && p.Rel != nil:
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Only one of 'pos' or 'rel' is allowed!")
+	case p.Pos != nil:
+		target = *p.Pos
+	case p.Rel != nil:
+		target = current + *p.Rel
+	default:
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "One of 'pos' or 'rel' is required!")
+	}
+	if target < 0 || target > 100 {
+		if p.Pos != nil {
+			return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Argument 'pos' out of range!")
+		}
+		target = min(max(target, 0), 100)
+	}
+	direction := coverOpen
+	if target < current {
+		direction = coverClose
+	}
+	if err := d.checkSafetySwitch(c, m, direction); err != nil {
+		return nil, err
+	}
+	d.startCoverMove(key, c, m, direction, &target, nil, sourceRPC)
+	return nil, nil
+}
+
+func (d *Device) coverCalibrate(params json.RawMessage) (any, error) {
+	key, c, m, err := d.lookupCover(params)
+	if err != nil {
+		return nil, err
+	}
+	if m.safety {
+		if cfg, _ := c.config["safety_switch"].(map[string]any); boolField(cfg, "enable") {
+			return nil, rpcError(shelly.ErrRPCFailedPrecondition, "Safety switch is engaged!")
+		}
+	}
+	if m.direction != "" {
+		m.settle(d)
+	}
+	d.stopTimer(key)
+	m.direction, m.calibrating, m.startedAt, m.paused = coverClose, true, d.clock.Now(), nil
+	c.status["pos_control"] = false
+	delete(c.status, "current_pos")
+	delta := map[string]any{
+		"state": "calibrating", "source": sourceRPC, "apower": coverMotorPower,
+		"pos_control": false, "current_pos": nil,
+	}
+	merge(c.status, delta)
+	d.notifyStatus(key, delta)
+	d.after(key, m.calibrationTime(d), func() {
+		d.finishCoverMove(key, c, m, sourceRPC)
+	})
+	return nil, nil
+}
+
+// checkSafetySwitch fails if the engaged safety switch blocks moving in direction. d.mu must be
+// held.
+func (d *Device) checkSafetySwitch(c *component, m *coverMotion, direction string) error {
+	cfg, _ := c.config["safety_switch"].(map[string]any)
+	if !m.safety || !boolField(cfg, "enable") || !directionMatches(cfg["direction"], direction) {
+		return nil
+	}
+	last, _ := c.status["last_direction"].(string)
+	if cfg["allowed_move"] == "reverse" && last != "" && direction == opposite(last) {
+		return nil
+	}
+	return rpcError(shelly.ErrRPCFailedPrecondition, "Safety switch is engaged!")
+}
+
+// startCoverMove starts the motor in direction, stopping at target if given. The move times out
+// after duration seconds if given, or the configured max_time_open or max_time_close. d.mu must
+// be held.
+func (d *Device) startCoverMove(
+	key string,
+	c *component,
+	m *coverMotion,
+	direction string,
+	target *float64,
+	duration *float64,
+	source string,
+) {
+	if m.direction != "" {
+		m.settle(d)
+	}
+	d.stopTimer(key)
+	m.calibrating = false
+
+	timeout := numField(c.config, "max_time_"+direction)
+	if duration != nil {
+		timeout = *duration
+	}
+	travel := d.coverTravelTime(direction)
+	secondsTo := func(pos float64) float64 {
+		return math.Abs(pos-m.pos) / 100 * travel.Seconds()
+	}
+
+	// The motor stops at whichever comes first: the target, the end stop, detection of an
+	// obstacle, or the timeout. Without obstruction detection the motor stalls at an obstacle.
+	reason, after := "timeout", timeout
+	end := 0.0
+	if direction == coverOpen {
+		end = 100
+	}
+	var blockedAt *float64
+	if m.obstacle != nil && ((direction == coverOpen && *m.obstacle > m.pos) ||
+		(direction == coverClose && *m.obstacle <= m.pos)) {
+		blockedAt = m.obstacle
+	}
+	switch {
+	case target != nil && (blockedAt == nil || secondsTo(*target) < secondsTo(*blockedAt)):
+		if t := secondsTo(*target); t < after {
+			reason, after = "target", t
+		}
+	case blockedAt == nil:
+		if t := secondsTo(end); t < after {
+			reason, after = "end", t
+		}
+	default:
+		od, _ := c.config["obstruction_detection"].(map[string]any)
+		if boolField(od, "enable") && directionMatches(od["direction"], direction) {
+			if t := max(secondsTo(*blockedAt), numField(od, "holdoff")); t < after {
+				reason, after = "obstruction", t
+			}
+		}
+	}
+
+	m.direction, m.startedAt = direction, d.clock.Now()
+	if after <= 0 {
+		// Already there; the motor stops as soon as it starts.
+		d.finishCoverMove(key, c, m, source)
+		return
+	}
+	state := "opening"
+	if direction == coverClose {
+		state = "closing"
+	}
+	delta := map[string]any{
+		"state":           state,
+		"source":          source,
+		"apower":          coverMotorPower,
+		"last_direction":  direction,
+		"move_timeout":    timeout,
+		"move_started_at": d.timestamp(),
+	}
+	event := map[string]any{}
+	if target != nil && boolField(c.status, "pos_control") {
+		delta["target_pos"] = *target
+		event["target_pos"] = *target
+	}
+	merge(c.status, delta)
+	d.notifyStatus(key, delta)
+	d.notifyEvent(key, state, event)
+
+	d.after(key, time.Duration(after*float64(time.Second)), func() {
+		if reason == "obstruction" {
+			od, _ := c.config["obstruction_detection"].(map[string]any)
+			d.finishCoverMove(key, c, m, "obstruction")
+			if od["action"] == "reverse" {
+				d.startCoverMove(key, c, m, opposite(direction), nil, nil, "obstruction")
+			}
+			return
+		}
+		d.finishCoverMove(key, c, m, source)
+	})
+}
+
+// finishCoverMove stops the motor and reports where the cover stopped. d.mu must be held.
+func (d *Device) finishCoverMove(key string, c *component, m *coverMotion, source string) {
+	d.stopTimer(key)
+	calibrated := m.calibrating
+	m.settle(d)
+	if calibrated {
+		c.status["pos_control"] = m.pos == 100
+	}
+
+	state := "stopped"
+	switch m.pos {
+	case 100:
+		state = "open"
+	case 0:
+		state = "closed"
+	}
+	delta := map[string]any{
+		"state":           state,
+		"source":          source,
+		"apower":          0.0,
+		"target_pos":      nil,
+		"move_timeout":    nil,
+		"move_started_at": nil,
+	}
+	event := map[string]any{}
+	if boolField(c.status, "pos_control") {
+		delta["current_pos"] = math.Round(m.pos)
+		event["current_pos"] = delta["current_pos"]
+		if calibrated {
+			delta["pos_control"] = true
+		}
+	}
+	merge(c.status, delta)
+	for _, k := range []string{"target_pos", "move_timeout", "move_started_at"} {
+		delete(c.status, k)
+	}
+	d.notifyStatus(key, delta)
+	d.notifyEvent(key, state, event)
+}
+
+// refreshCovers updates the reported position of moving covers. d.mu must be held.
+func (d *Device) refreshCovers() {
+	for key, m := range d.covers {
+		c := d.components[key]
+		if m.direction != "" && !m.calibrating && boolField(c.status, "pos_control") {
+			c.status["current_pos"] = math.Round(m.position(d))
+		}
+	}
+}
+
+// coverTarget returns the target of the current movement, if any. d.mu must be held.
+func (d *Device) coverTarget(c *component) *float64 {
+	if t, ok := c.status["target_pos"].(float64); ok {
+		return &t
+	}
+	return nil
+}
+
+func (d *Device) coverTravelTime(direction string) time.Duration {
+	if direction == coverClose {
+		return d.coverCloseTime
+	}
+	return d.coverOpenTime
+}
+
+// position returns the physical position of the cover now.
+func (m *coverMotion) position(d *Device) float64 {
+	if m.direction == "" {
+		return m.pos
+	}
+	elapsed := d.clock.Now().Sub(m.startedAt)
+	if m.calibrating {
+		// Close fully, then open fully.
+		closing := time.Duration(m.pos / 100 * float64(d.coverCloseTime))
+		if elapsed < closing {
+			return m.pos - float64(elapsed)/float64(d.coverCloseTime)*100
+		}
+		return min(float64(elapsed-closing)/float64(d.coverOpenTime)*100, 100)
+	}
+	moved := float64(elapsed) / float64(d.coverTravelTime(m.direction)) * 100
+	if m.direction == coverOpen {
+		pos := min(m.pos+moved, 100)
+		if m.obstacle != nil && *m.obstacle > m.pos {
+			pos = min(pos, *m.obstacle)
+		}
+		return pos
+	}
+	pos := max(m.pos-moved, 0)
+	if m.obstacle != nil && *m.obstacle <= m.pos {
+		pos = max(pos, *m.obstacle)
+	}
+	return pos
+}
+
+// calibrationTime is how long calibration takes from the current position.
+func (m *coverMotion) calibrationTime(d *Device) time.Duration {
+	return time.Duration(m.pos/100*float64(d.coverCloseTime)) + d.coverOpenTime
+}
+
+// settle records the current position and stops the motion.
+func (m *coverMotion) settle(d *Device) {
+	m.pos = m.position(d)
+	m.direction, m.calibrating = "", false
+}
+
+// directionMatches returns true if the configured direction ("open", "close" or "both")
+// applies to movement in direction.
+func directionMatches(configured any, direction string) bool {
+	return configured == "both" || configured == direction
+}
+
+func opposite(direction string) string {
+	if direction == coverOpen {
+		return coverClose
+	}
+	return coverOpen
+}
@@ -0,0 +1,171 @@
+package shellysim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+func coverStatus(t *testing.T, d *Device) *shelly.CoverStatus {
+	status, _, err := (&shelly.CoverGetStatusRequest{ID: 0}).Do(context.Background(), d, nil)
+	require.NoError(t, err)
+	return status
+}
+
+func TestCoverMotion(t *testing.T) {
+	ctx := context.Background()
+	clock := NewVirtualClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	d := NewCoverDevice(WithClock(clock))
+
+	events := d.Subscribe(ctx, "NotifyEvent")
+
+	// Uncalibrated covers open until the end stop, without reporting a position.
+	_, _, err := (&shelly.CoverOpenRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	status := coverStatus(t, d)
+	assert.Equal(t, "opening", *status.State)
+	assert.Equal(t, 60.0, *status.MoveTimeout)
+	clock.Advance(29 * time.Second)
+	assert.Equal(t, "opening", *coverStatus(t, d).State)
+	clock.Advance(time.Second)
+	status = coverStatus(t, d)
+	assert.Equal(t, "open", *status.State)
+	assert.False(t, *status.PosControl)
+	assert.Nil(t, status.CurrentPos)
+	assert.Nil(t, status.MoveTimeout)
+	pos := 50.0
+	_, _, err = (&shelly.CoverGoToPositionRequest{ID: 0, Pos: &pos}).Do(ctx, d, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCFailedPrecondition)
+
+	// Calibration closes then opens fully.
+	_, _, err = (&shelly.CoverCalibrateRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "calibrating", *coverStatus(t, d).State)
+	clock.Advance(time.Minute)
+	status = coverStatus(t, d)
+	assert.Equal(t, "open", *status.State)
+	assert.True(t, *status.PosControl)
+	assert.Equal(t, 100.0, *status.CurrentPos)
+
+	for len(events) > 0 {
+		<-events
+	}
+	pos = 40
+	_, _, err = (&shelly.CoverGoToPositionRequest{ID: 0, Pos: &pos}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string((<-events).Params), `"event":"closing"`)
+	status = coverStatus(t, d)
+	assert.Equal(t, "closing", *status.State)
+	assert.Equal(t, 40.0, *status.TargetPos)
+	clock.Advance(9 * time.Second)
+	assert.Equal(t, 70.0, *coverStatus(t, d).CurrentPos)
+	clock.Advance(9 * time.Second)
+	status = coverStatus(t, d)
+	assert.Equal(t, "stopped", *status.State)
+	assert.Equal(t, 40.0, *status.CurrentPos)
+	assert.Nil(t, status.TargetPos)
+	assert.Contains(t, string((<-events).Params), `"current_pos":40`)
+
+	// The configured max_time_close stops the motor early.
+	maxTime := 6.0
+	_, _, err = (&shelly.CoverSetConfigRequest{
+		ID: 0, Config: shelly.CoverConfig{MaxTimeClose: &maxTime},
+	}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	_, _, err = (&shelly.CoverCloseRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	clock.Advance(10 * time.Second)
+	status = coverStatus(t, d)
+	assert.Equal(t, "stopped", *status.State)
+	assert.Equal(t, 20.0, *status.CurrentPos)
+	assert.Equal(t, "close", *status.LastDirection)
+	assert.Zero(t, clock.Pending())
+}
+
+func TestCoverObstructionDetection(t *testing.T) {
+	ctx := context.Background()
+	clock := NewVirtualClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	d := NewCoverDevice(WithClock(clock), WithCoverTravelTime(20*time.Second, 20*time.Second))
+	_, _, err := (&shelly.CoverCalibrateRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	clock.Advance(time.Minute)
+
+	obstacle := 30.0
+	require.NoError(t, d.SetCoverObstacle(0, &obstacle))
+
+	// Without detection the motor stalls at the obstacle until the move times out.
+	_, _, err = (&shelly.CoverCloseRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, "closing", *coverStatus(t, d).State)
+	assert.Equal(t, 30.0, *coverStatus(t, d).CurrentPos)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, "stopped", *coverStatus(t, d).State)
+
+	enable, direction, action := true, "close", "reverse"
+	_, _, err = (&shelly.CoverSetConfigRequest{ID: 0, Config: shelly.CoverConfig{
+		ObstructionDetection: &shelly.CoverObstructionDetectionConfig{
+			Enable: &enable, Direction: &direction, Action: &action,
+		},
+	}}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	pos := 80.0
+	_, _, err = (&shelly.CoverGoToPositionRequest{ID: 0, Pos: &pos}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	clock.Advance(15 * time.Second)
+	assert.Equal(t, 80.0, *coverStatus(t, d).CurrentPos)
+
+	// Closing from 80 reaches the obstacle after 10s, then reverses to fully open.
+	_, _, err = (&shelly.CoverCloseRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	clock.Advance(10 * time.Second)
+	status := coverStatus(t, d)
+	assert.Equal(t, "opening", *status.State)
+	assert.Equal(t, "obstruction", *status.Source)
+	clock.Advance(14 * time.Second)
+	assert.Equal(t, "open", *coverStatus(t, d).State)
+	assert.Equal(t, 100.0, *coverStatus(t, d).CurrentPos)
+}
+
+func TestCoverSafetySwitch(t *testing.T) {
+	ctx := context.Background()
+	clock := NewVirtualClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	d := NewCoverDevice(WithClock(clock))
+	_, _, err := (&shelly.CoverCalibrateRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	clock.Advance(time.Minute)
+
+	enable, direction, action := true, "close", "pause"
+	_, _, err = (&shelly.CoverSetConfigRequest{ID: 0, Config: shelly.CoverConfig{
+		SafetySwitch: &shelly.CoverSafetySwitchConfig{
+			Enable: &enable, Direction: &direction, Action: &action,
+		},
+	}}).Do(ctx, d, nil)
+	require.NoError(t, err)
+
+	_, _, err = (&shelly.CoverCloseRequest{ID: 0}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	clock.Advance(15 * time.Second)
+	require.NoError(t, d.SetCoverSafetySwitch(0, true))
+	status := coverStatus(t, d)
+	assert.Equal(t, "stopped", *status.State)
+	assert.Equal(t, 50.0, *status.CurrentPos)
+
+	_, _, err = (&shelly.CoverCloseRequest{ID: 0}).Do(ctx, d, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCFailedPrecondition)
+	clock.Advance(time.Minute)
+	assert.Equal(t, 50.0, *coverStatus(t, d).CurrentPos)
+
+	// Releasing the switch resumes the paused movement.
+	require.NoError(t, d.SetCoverSafetySwitch(0, false))
+	assert.Equal(t, "closing", *coverStatus(t, d).State)
+	clock.Advance(15 * time.Second)
+	status = coverStatus(t, d)
+	assert.Equal(t, "closed", *status.State)
+	assert.Equal(t, 0.0, *status.CurrentPos)
+}
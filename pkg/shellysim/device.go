@@ -0,0 +1,458 @@
+// Package shellysim emulates a Gen2 Shelly device for tests. A Device is built from
+// shelly.DeviceSpecs and keeps the state of its components, answering RPC calls in-process (as
+// a shelly.Caller) or over HTTP and WebSocket /rpc endpoints via its http.Handler.
+//
+// The emulation follows device semantics where they're observable by clients: outputs honor
+// toggle_after and auto on/off timers, SetConfig bumps cfg_rev, Shelly.SetAuth enables digest
+// authentication, scripts, KVS entries, schedules, webhooks and dynamic components (virtual
+// components and BTHome devices) are stored, changes which require a restart are reported until
+// Shelly.Reboot, and NotifyStatus and NotifyEvent notifications are sent as state changes.
+package shellysim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+const (
+	// DefaultID is the device id used unless WithID is given.
+	DefaultID = "shellysim-b8d61a000001"
+
+	// DefaultFirmwareVersion is the firmware version reported by Shelly.GetDeviceInfo.
+	DefaultFirmwareVersion = "1.4.4"
+
+	defaultFirmwareID = "20241011-114455/1.4.4-g6d2a586"
+)
+
+var (
+	// ErrDisconnected is returned by calls made after Disconnect.
+	ErrDisconnected = errors.New("shellysim device is disconnected")
+)
+
+// Option configures a Device.
+type Option func(*Device)
+
+// WithID sets the device id, which is also the digest auth realm and the src of frames.
+func WithID(id string) Option {
+	return func(d *Device) {
+		d.id = id
+	}
+}
+
+// WithModel sets the app (ex. "Pro4PM") and model (ex. "SPSW-104PE16EU") reported by
+// Shelly.GetDeviceInfo.
+func WithModel(app, model string) Option {
+	return func(d *Device) {
+		d.app, d.model = app, model
+	}
+}
+
+// WithClock sets the clock used for timers and timestamps.
+func WithClock(c Clock) Option {
+	return func(d *Device) {
+		d.clock = c
+	}
+}
+
+// WithPassword enables authentication, as if Shelly.SetAuth had been called.
+func WithPassword(password string) Option {
+	return func(d *Device) {
+		d.password = password
+	}
+}
+
+// Device is an emulated Gen2 device. It implements shelly.Caller, answering calls directly, and
+// http.Handler, serving the /rpc endpoints.
+type Device struct {
+	id       string
+	mac      string
+	app      string
+	model    string
+	clock    Clock
+	bootTime time.Time
+
+	coverOpenTime  time.Duration
+	coverCloseTime time.Duration
+	password       string
+
+	mu          sync.Mutex
+	components  map[string]*component
+	covers      map[string]*coverMotion
+	cfgRev      int
+	kvsRev      int
+	scheduleRev int
+	webhookRev  int
+	restart     bool
+	ha1         string
+	nonce       int64
+	scripts     map[int]*script
+	kvs         map[string]*kvsEntry
+	schedules   map[int]map[string]any
+	webhooks    map[int]map[string]any
+	maxScripts  int
+	nextID      map[string]int
+	timers      map[string]Timer
+	sinks       map[int]func(*shelly.RPCFrame)
+	nextSink    int
+	subscribers shelly.NotificationHub
+	pending     []*shelly.RPCFrame
+	closed      bool
+}
+
+// component is the state of a component instance, as returned by its GetConfig and GetStatus
+// methods.
+type component struct {
+	config map[string]any
+	status map[string]any
+}
+
+// New builds a device with the components described by specs.
+func New(specs shelly.DeviceSpecs, opts ...Option) *Device {
+	d := &Device{
+		id:    DefaultID,
+		app:   "Sim",
+		model: "SNSW-SIM",
+		clock: realClock{},
+
+		coverOpenTime:  DefaultCoverTravelTime,
+		coverCloseTime: DefaultCoverTravelTime,
+		components:     make(map[string]*component),
+		covers:         make(map[string]*coverMotion),
+		cfgRev:         1,
+		scripts:        make(map[int]*script),
+		kvs:            make(map[string]*kvsEntry),
+		schedules:      make(map[int]map[string]any),
+		webhooks:       make(map[int]map[string]any),
+		nextID:         make(map[string]int),
+		timers:         make(map[string]Timer),
+		sinks:          make(map[int]func(*shelly.RPCFrame)),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if _, mac, ok := strings.Cut(d.id, "-"); ok {
+		d.mac = strings.ToUpper(mac)
+	}
+	if d.password != "" {
+		d.ha1 = authHA1(d.id, d.password)
+	}
+	d.maxScripts = specs.Scripts
+	d.bootTime = d.clock.Now()
+	d.nonce = d.bootTime.Unix()
+	d.addComponents(specs)
+	return d
+}
+
+// ID returns the device id.
+func (d *Device) ID() string {
+	return d.id
+}
+
+// Call implements shelly.Caller. If authentication is enabled, getCreds must return the device
+// password.
+func (d *Device) Call(
+	ctx context.Context,
+	f *shelly.RPCFrame,
+	getCreds shelly.GetCredsCallback,
+) (*shelly.RPCFrame, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	authorized := func(ha1 string) bool {
+		if getCreds == nil {
+			return false
+		}
+		user, pass, err := getCreds()
+		if err != nil {
+			return false
+		}
+		if user == "" {
+			user = shelly.DefaultAuthenticationUsername
+		}
+		return user == shelly.DefaultAuthenticationUsername && authHA1(d.id, pass) == ha1
+	}
+	result, err := d.dispatch(f.Method, f.Params, authorized)
+	if errors.Is(err, ErrDisconnected) {
+		return nil, err
+	}
+	resp := &shelly.RPCFrame{ID: f.ID, Src: d.id, Dst: f.Src}
+	var rpcErr *shelly.BadStatusWithMessageError
+	if errors.As(err, &rpcErr) {
+		resp.Error = &shelly.RPCFrameError{Code: int(rpcErr.Status), Message: rpcErr.Msg}
+	} else if err != nil {
+		return nil, err
+	} else {
+		resp.Result = result
+	}
+	return resp, nil
+}
+
+// Subscribe implements shelly.Subscriber, returning the notifications (ex. NotifyStatus) sent by
+// the device.
+func (d *Device) Subscribe(ctx context.Context, method string) <-chan *shelly.RPCFrame {
+	return d.subscribers.Subscribe(ctx, method)
+}
+
+// Disconnect stops pending timers; later calls fail with ErrDisconnected.
+func (d *Device) Disconnect(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	for k, t := range d.timers {
+		t.Stop()
+		delete(d.timers, k)
+	}
+	return nil
+}
+
+// IsConnected is false once the device is disconnected.
+func (d *Device) IsConnected() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.closed
+}
+
+// Status returns a copy of the status of the component with key (ex. "switch:0").
+func (d *Device) Status(key string) (map[string]any, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.components[key]
+	if !ok {
+		return nil, false
+	}
+	return deepCopy(c.status).(map[string]any), true
+}
+
+// Config returns a copy of the config of the component with key (ex. "switch:0").
+func (d *Device) Config(key string) (map[string]any, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.components[key]
+	if !ok {
+		return nil, false
+	}
+	return deepCopy(c.config).(map[string]any), true
+}
+
+// UpdateStatus merges patch into the status of the component with key and sends NotifyStatus,
+// emulating a change observed by the device (ex. an input changing state or a new sensor
+// reading).
+func (d *Device) UpdateStatus(key string, patch map[string]any) error {
+	d.mu.Lock()
+	c, ok := d.components[key]
+	if !ok {
+		d.mu.Unlock()
+		return fmt.Errorf("unknown component %q", key)
+	}
+	patch = deepCopy(patch).(map[string]any)
+	merge(c.status, patch)
+	d.notifyStatus(key, patch)
+	d.unlockAndFlush()
+	return nil
+}
+
+// EmitEvent sends a NotifyEvent for the component with key (ex. "input:0", "single_push").
+// Fields in data are added to the event.
+func (d *Device) EmitEvent(key, event string, data map[string]any) {
+	d.mu.Lock()
+	d.notifyEvent(key, event, data)
+	d.unlockAndFlush()
+}
+
+// subscribe registers f to receive notifications until the returned func is called.
+func (d *Device) subscribe(f func(*shelly.RPCFrame)) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := d.nextSink
+	d.nextSink++
+	d.sinks[id] = f
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.sinks, id)
+	}
+}
+
+// dispatch calls the method. authorized reports whether credentials match the ha1; it is only
+// consulted when authentication is enabled.
+func (d *Device) dispatch(
+	method string,
+	params json.RawMessage,
+	authorized func(ha1 string) bool,
+) (json.RawMessage, error) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil, ErrDisconnected
+	}
+	if d.ha1 != "" && !unauthenticatedMethods[method] && !authorized(d.ha1) {
+		challenge, _ := json.Marshal(d.challenge())
+		d.mu.Unlock()
+		return nil, rpcError(shelly.ErrRPCUnauthorized, string(challenge))
+	}
+	result, err := d.call(method, params)
+	d.unlockAndFlush()
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s result: %w", method, err)
+	}
+	return b, nil
+}
+
+// call finds and invokes the handler for method. d.mu must be held.
+func (d *Device) call(method string, params json.RawMessage) (any, error) {
+	if len(params) == 0 || string(params) == "null" {
+		params = json.RawMessage("{}")
+	}
+	if h, ok := methods[method]; ok {
+		return h(d, params)
+	}
+	typ, op, _ := strings.Cut(method, ".")
+	if kind, ok := componentTypes[typ]; ok && d.hasComponentType(kind) {
+		switch op {
+		case "GetStatus":
+			return d.getStatus(kind, params)
+		case "GetConfig":
+			return d.getConfig(kind, params)
+		case "SetConfig":
+			return d.setConfig(kind, params)
+		}
+	}
+	return nil, rpcError(shelly.ErrRPCNoHandler, "No handler for "+method)
+}
+
+// listMethods returns the methods supported by the device.
+func (d *Device) listMethods() []string {
+	var out []string
+	for m := range methods {
+		typ, _, _ := strings.Cut(m, ".")
+		if kind, ok := componentTypes[typ]; !ok || d.hasComponentType(kind) {
+			out = append(out, m)
+		}
+	}
+	for typ, kind := range componentTypes {
+		if d.hasComponentType(kind) {
+			out = append(out, typ+".GetStatus", typ+".GetConfig", typ+".SetConfig")
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// notifyStatus queues a NotifyStatus with the changed fields of a component. d.mu must be held.
+func (d *Device) notifyStatus(key string, delta map[string]any) {
+	delta = deepCopy(delta).(map[string]any)
+	if c, ok := d.components[key]; ok {
+		if id, ok := c.status["id"]; ok {
+			// Like the device, identify the instance in each delta.
+			delta["id"] = id
+		}
+	}
+	params := map[string]any{"ts": d.timestamp(), key: delta}
+	d.queue("NotifyStatus", params)
+}
+
+// notifyEvent queues a NotifyEvent. d.mu must be held.
+func (d *Device) notifyEvent(key, event string, data map[string]any) {
+	ev := map[string]any{"component": key, "event": event, "ts": d.timestamp()}
+	if _, id, ok := strings.Cut(key, ":"); ok {
+		var n int
+		fmt.Sscan(id, &n)
+		ev["id"] = n
+	}
+	for k, v := range data {
+		ev[k] = v
+	}
+	d.queue("NotifyEvent", map[string]any{"ts": ev["ts"], "events": []any{ev}})
+}
+
+func (d *Device) queue(method string, params any) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	d.pending = append(d.pending, &shelly.RPCFrame{
+		JSONRPC: shelly.JSONRPCVersion,
+		Src:     d.id,
+		Method:  method,
+		Params:  b,
+	})
+}
+
+// unlockAndFlush releases d.mu and delivers queued notifications. Delivery happens without the
+// lock so receivers may call the device.
+func (d *Device) unlockAndFlush() {
+	pending := d.pending
+	d.pending = nil
+	sinks := make([]func(*shelly.RPCFrame), 0, len(d.sinks))
+	ids := make([]int, 0, len(d.sinks))
+	for id := range d.sinks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		sinks = append(sinks, d.sinks[id])
+	}
+	d.mu.Unlock()
+
+	for _, f := range pending {
+		for _, sink := range sinks {
+			sink(f)
+		}
+		d.subscribers.Publish(f)
+	}
+}
+
+// after schedules f under d.mu after delay, replacing any timer with the same name.
+// d.mu must be held.
+func (d *Device) after(name string, delay time.Duration, f func()) {
+	d.stopTimer(name)
+	var t Timer
+	t = d.clock.AfterFunc(delay, func() {
+		d.mu.Lock()
+		if d.timers[name] != t || d.closed {
+			d.mu.Unlock()
+			return
+		}
+		delete(d.timers, name)
+		f()
+		d.unlockAndFlush()
+	})
+	d.timers[name] = t
+}
+
+// stopTimer cancels the named timer. d.mu must be held.
+func (d *Device) stopTimer(name string) {
+	if t, ok := d.timers[name]; ok {
+		t.Stop()
+		delete(d.timers, name)
+	}
+}
+
+func (d *Device) timestamp() float64 {
+	return float64(d.clock.Now().UnixMilli()) / 1000
+}
+
+// rpcError builds the error returned for a failed call.
+func rpcError(code shelly.ShellyErrorCode, msg string) error {
+	return &shelly.BadStatusWithMessageError{Status: code, Msg: msg}
+}
+
+// decodeParams decodes params into v, failing like a device when they're malformed.
+func decodeParams(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Invalid params: "+err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package shellysim
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+func pro4PM(t *testing.T, opts ...Option) *Device {
+	specs, err := shelly.AppToDeviceSpecs("Pro4PM", "")
+	require.NoError(t, err)
+	return New(specs, append([]Option{WithModel("Pro4PM", "SPSW-104PE16EU")}, opts...)...)
+}
+
+func TestSwitchToggleAfter(t *testing.T) {
+	ctx := context.Background()
+	d := pro4PM(t)
+	defer d.Disconnect(ctx)
+
+	notifications := d.Subscribe(ctx, "NotifyStatus")
+
+	toggleAfter := 0.05
+	resp, _, err := (&shelly.SwitchSetRequest{ID: 1, On: true, ToggleAfter: &toggleAfter}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.False(t, resp.WasOn)
+
+	status, _, err := (&shelly.SwitchGetStatusRequest{ID: 1}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.True(t, *status.Output)
+	require.NotNil(t, status.TimerDuration)
+	assert.Equal(t, toggleAfter, *status.TimerDuration)
+	assert.Contains(t, string((<-notifications).Params), `"output":true`)
+
+	// The output flips back when the timer fires.
+	select {
+	case n := <-notifications:
+		assert.Contains(t, string(n.Params), `"output":false`)
+		assert.Contains(t, string(n.Params), `"source":"timer"`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("toggle_after timer did not fire")
+	}
+	status, _, err = (&shelly.SwitchGetStatusRequest{ID: 1}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.False(t, *status.Output)
+	assert.Nil(t, status.TimerDuration)
+
+	toggled, _, err := (&shelly.SwitchToggleRequest{ID: 1}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.False(t, toggled.WasOn)
+
+	_, _, err = (&shelly.SwitchGetStatusRequest{ID: 4}).Do(ctx, d, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnknownComponentID)
+	_, _, err = (&shelly.LightGetStatusRequest{ID: 0}).Do(ctx, d, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCNoHandler)
+}
+
+func TestSetConfigBumpsCfgRev(t *testing.T) {
+	ctx := context.Background()
+	d := pro4PM(t)
+
+	events := d.Subscribe(ctx, "NotifyEvent")
+
+	before, _, err := (&shelly.SysGetStatusRequest{}).Do(ctx, d, nil)
+	require.NoError(t, err)
+
+	_, _, err = (&shelly.SwitchSetConfigRequest{ID: 2, Config: shelly.SwitchConfig{Name: shelly.Some("Porch")}}).Do(ctx, d, nil)
+	require.NoError(t, err)
+
+	cfg, _, err := (&shelly.SwitchGetConfigRequest{ID: 2}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.ID)
+	assert.Equal(t, "Porch", cfg.Name.Or(""))
+
+	after, _, err := (&shelly.SysGetStatusRequest{}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Equal(t, before.CfgRev+1, after.CfgRev)
+	assert.Contains(t, string((<-events).Params), `"event":"config_changed"`)
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	d := pro4PM(t)
+
+	name := "lights"
+	created, _, err := (&shelly.ScriptCreateRequest{Name: &name}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	_, _, err = (&shelly.ScriptPutCodeRequest{ID: created.ID, Code: "let a = 1;"}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	put, _, err := (&shelly.ScriptPutCodeRequest{ID: created.ID, Code: " a++;", Append: true}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Equal(t, len("let a = 1; a++;"), put.Len)
+	started, _, err := (&shelly.ScriptStartRequest{ID: created.ID}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.False(t, started.WasRunning)
+	list, _, err := (&shelly.ScriptListRequest{}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	require.Len(t, list.Scripts, 1)
+	assert.Equal(t, "lights", list.Scripts[0].Name)
+	assert.True(t, list.Scripts[0].Running)
+	_, _, err = (&shelly.ScriptDeleteRequest{ID: created.ID}).Do(ctx, d, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCFailedPrecondition)
+
+	call := func(method, params string) (json.RawMessage, error) {
+		resp, err := d.Call(ctx, &shelly.RPCFrame{Method: method, Params: json.RawMessage(params)}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, shelly.ShellyErrorCode(resp.Error.Code)
+		}
+		return resp.Result, nil
+	}
+	set, err := call("KVS.Set", `{"key":"scene/evening","value":{"brightness":40}}`)
+	require.NoError(t, err)
+	var setResp struct {
+		Etag string `json:"etag"`
+		Rev  int    `json:"rev"`
+	}
+	require.NoError(t, json.Unmarshal(set, &setResp))
+	assert.Equal(t, 1, setResp.Rev)
+	_, err = call("KVS.Set", `{"key":"scene/morning","value":80}`)
+	require.NoError(t, err)
+	_, err = call("KVS.Set", `{"key":"other","value":"x"}`)
+	require.NoError(t, err)
+
+	got, err := call("KVS.Get", `{"key":"scene/evening"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"etag":"`+setResp.Etag+`","value":{"brightness":40}}`, string(got))
+	many, err := call("KVS.GetMany", `{"match":"scene/*"}`)
+	require.NoError(t, err)
+	assert.Contains(t, string(many), `"total":2`)
+	_, err = call("KVS.Delete", `{"key":"scene/evening","etag":"stale"}`)
+	assert.ErrorIs(t, err, shelly.ErrRPCFailedPrecondition)
+	_, err = call("KVS.Delete", `{"key":"scene/evening"}`)
+	require.NoError(t, err)
+	_, err = call("KVS.Get", `{"key":"scene/evening"}`)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnknownComponentID)
+
+	on := true
+	timespec := "0 0 7 * * MON-FRI"
+	sched, _, err := (&shelly.ScheduleCreateRequest{
+		TimeSpec: &timespec,
+		Calls:    []shelly.ScheduleCall{{Method: "Switch.Set"}},
+		Enable:   &on,
+	}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	require.NotNil(t, sched.ID)
+	jobs, err := call("Schedule.List", `{}`)
+	require.NoError(t, err)
+	assert.Contains(t, string(jobs), timespec)
+
+	sys, _, err := (&shelly.SysGetStatusRequest{}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, sys.KVRev)
+	assert.Equal(t, 1, *sys.ScheduleRev)
+}
+
+func TestDynamicComponentsAndWebhooks(t *testing.T) {
+	ctx := context.Background()
+	d := pro4PM(t)
+
+	added, _, err := (&shelly.VirtualAddRequest{
+		Type:   "boolean",
+		Config: map[string]any{"name": "away"},
+	}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 200, added.ID)
+	_, _, err = (&shelly.VirtualAddRequest{Type: "boolean", ID: shelly.IntPtr(200)}).Do(ctx, d, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCInvalidOrMissingArguments)
+	_, _, err = (&shelly.BTHomeAddDeviceRequest{
+		ID:     shelly.IntPtr(201),
+		Config: shelly.BTHomeDeviceConfig{Addr: "3c:2e:f5:71:d5:2a"},
+	}).Do(ctx, d, nil)
+	require.NoError(t, err)
+
+	comps, _, err := (&shelly.ShellyGetComponentsRequest{
+		Include:     []string{"config"},
+		DynamicOnly: shelly.BoolPtr(true),
+	}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	require.Len(t, comps.Components, 2)
+	assert.Equal(t, "boolean:200", comps.Components[0].Key)
+	assert.Equal(t, "away", comps.Components[0].Config["name"])
+	assert.Equal(t, "bthomedevice:201", comps.Components[1].Key)
+
+	cfg, err := d.Call(ctx, &shelly.RPCFrame{Method: "Boolean.GetConfig", Params: json.RawMessage(`{"id":200}`)}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(cfg.Result), `"name":"away"`)
+	_, _, err = (&shelly.VirtualDeleteRequest{Key: "boolean:200"}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	_, ok := d.Config("boolean:200")
+	assert.False(t, ok)
+
+	hook, _, err := (&shelly.WebhookCreateRequest{
+		CID:   shelly.IntPtr(0),
+		Event: shelly.StrPtr("switch.on"),
+		URLs:  []string{"http://example.com/on"},
+	}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	require.NotNil(t, hook.ID)
+	hooks, _, err := (&shelly.WebhookListRequest{}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	require.Len(t, hooks.Hooks, 1)
+	assert.Equal(t, "switch.on", *hooks.Hooks[0].Event)
+	assert.True(t, *hooks.Hooks[0].Enable)
+	_, _, err = (&shelly.WebhookDeleteAllRequest{}).Do(ctx, d, nil)
+	require.NoError(t, err)
+
+	sys, _, err := (&shelly.SysGetStatusRequest{}).Do(ctx, d, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *sys.WebhookRev)
+}
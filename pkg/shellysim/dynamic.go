@@ -0,0 +1,192 @@
+package shellysim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// virtualTypes are the default config and status of each type of virtual component.
+var virtualTypes = map[string]struct{ config, status map[string]any }{
+	"boolean": {
+		config: map[string]any{"name": nil, "persisted": false, "default_value": false},
+		status: map[string]any{"value": false},
+	},
+	"number": {
+		config: map[string]any{
+			"name": nil, "persisted": false, "default_value": 0.0, "min": 0.0, "max": 100.0,
+		},
+		status: map[string]any{"value": 0.0},
+	},
+	"text": {
+		config: map[string]any{"name": nil, "persisted": false, "default_value": "", "max_len": 255},
+		status: map[string]any{"value": ""},
+	},
+	"enum": {
+		config: map[string]any{"name": nil, "persisted": false, "default_value": nil, "options": []any{}},
+		status: map[string]any{"value": nil},
+	},
+	"group": {
+		config: map[string]any{"name": nil},
+		status: map[string]any{"value": []any{}},
+	},
+	"button": {
+		config: map[string]any{"name": nil},
+		status: map[string]any{},
+	},
+}
+
+// isDynamic is true if the component with key was created at runtime, ex. by Virtual.Add or
+// BTHome.AddDevice.
+func isDynamic(key string) bool {
+	_, id, ok := strings.Cut(key, ":")
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(id)
+	return err == nil && n >= shelly.MinVirtualID
+}
+
+// addDynamic creates a component of kind with config merged over the defaults, using the
+// first free id if id is nil. d.mu must be held.
+func (d *Device) addDynamic(kind string, id *int, config, defaults, status map[string]any) (string, error) {
+	if id == nil {
+		for i := shelly.MinVirtualID; i <= shelly.MaxVirtualID; i++ {
+			if _, ok := d.components[fmt.Sprintf("%s:%d", kind, i)]; !ok {
+				id = &i
+				break
+			}
+		}
+		if id == nil {
+			return "", rpcError(shelly.ErrRPCResourcesExhausted, "Too many components!")
+		}
+	}
+	if *id < shelly.MinVirtualID || *id > shelly.MaxVirtualID {
+		return "", rpcError(shelly.ErrRPCInvalidOrMissingArguments,
+			fmt.Sprintf("Argument 'id', value %d out of range!", *id))
+	}
+	key := fmt.Sprintf("%s:%d", kind, *id)
+	if _, ok := d.components[key]; ok {
+		return "", rpcError(shelly.ErrRPCInvalidOrMissingArguments,
+			fmt.Sprintf("Argument 'id', value %d already in use!", *id))
+	}
+	c := &component{
+		config: deepCopy(defaults).(map[string]any),
+		status: deepCopy(status).(map[string]any),
+	}
+	merge(c.config, config)
+	c.config["id"], c.status["id"] = *id, *id
+	d.components[key] = c
+	d.configChanged()
+	return key, nil
+}
+
+// deleteDynamic removes the dynamic component with key. d.mu must be held.
+func (d *Device) deleteDynamic(key string) error {
+	if _, ok := d.components[key]; !ok || !isDynamic(key) {
+		return rpcError(shelly.ErrRPCUnknownComponentID, fmt.Sprintf("Component %q not found!", key))
+	}
+	delete(d.components, key)
+	d.configChanged()
+	return nil
+}
+
+// dynamicParams are the params of the Virtual and BTHome methods.
+type dynamicParams struct {
+	Type   string         `json:"type"`
+	Key    string         `json:"key"`
+	ID     *int           `json:"id"`
+	Config map[string]any `json:"config"`
+}
+
+func (d *Device) virtualAdd(params json.RawMessage) (any, error) {
+	var p dynamicParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	typ, ok := virtualTypes[p.Type]
+	if !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments,
+			fmt.Sprintf("Argument 'type', value %q not supported!", p.Type))
+	}
+	key, err := d.addDynamic(p.Type, p.ID, p.Config, typ.config, typ.status)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"id": d.components[key].config["id"]}, nil
+}
+
+func (d *Device) virtualDelete(params json.RawMessage) (any, error) {
+	var p dynamicParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	kind, _, _ := strings.Cut(p.Key, ":")
+	if _, ok := virtualTypes[kind]; !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments,
+			fmt.Sprintf("Argument 'key', value %q is not a virtual component!", p.Key))
+	}
+	return nil, d.deleteDynamic(p.Key)
+}
+
+func (d *Device) bthomeAddDevice(params json.RawMessage) (any, error) {
+	var p dynamicParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.Config["addr"].(string); !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'config.addr'!")
+	}
+	key, err := d.addDynamic("bthomedevice", p.ID, p.Config,
+		map[string]any{"name": nil, "key": nil, "meta": map[string]any{}},
+		map[string]any{"rssi": nil, "battery": nil, "packet_id": nil, "last_updated_ts": 0})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"key": key}, nil
+}
+
+func (d *Device) bthomeDeleteDevice(params json.RawMessage) (any, error) {
+	var p dynamicParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ID == nil {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'id'!")
+	}
+	return nil, d.deleteDynamic(fmt.Sprintf("bthomedevice:%d", *p.ID))
+}
+
+func (d *Device) bthomeAddSensor(params json.RawMessage) (any, error) {
+	var p dynamicParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.Config["addr"].(string); !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'config.addr'!")
+	}
+	if _, ok := p.Config["obj_id"].(float64); !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'config.obj_id'!")
+	}
+	key, err := d.addDynamic("bthomesensor", p.ID, p.Config,
+		map[string]any{"name": nil, "idx": 0, "meta": map[string]any{}},
+		map[string]any{"value": nil, "last_updated_ts": 0})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"key": key}, nil
+}
+
+func (d *Device) bthomeDeleteSensor(params json.RawMessage) (any, error) {
+	var p dynamicParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ID == nil {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'id'!")
+	}
+	return nil, d.deleteDynamic(fmt.Sprintf("bthomesensor:%d", *p.ID))
+}
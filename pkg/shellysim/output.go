@@ -0,0 +1,136 @@
+package shellysim
+
+import (
+	"encoding/json"
+	"time"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// sourceRPC is reported as the source of changes made by calls.
+const sourceRPC = "rpc"
+
+// sourceTimer is reported as the source of changes made by toggle_after and auto on/off timers.
+const sourceTimer = "timer"
+
+func (d *Device) switchSet(params json.RawMessage) (any, error) {
+	key, c, err := d.lookup("switch", params)
+	if err != nil {
+		return nil, err
+	}
+	var p shelly.SwitchSetRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	wasOn := boolField(c.status, "output")
+	d.setOutput(key, c, p.On, p.ToggleAfter, sourceRPC)
+	return &shelly.SwitchActionResponse{WasOn: wasOn}, nil
+}
+
+func (d *Device) switchToggle(params json.RawMessage) (any, error) {
+	key, c, err := d.lookup("switch", params)
+	if err != nil {
+		return nil, err
+	}
+	wasOn := boolField(c.status, "output")
+	d.setOutput(key, c, !wasOn, nil, sourceRPC)
+	return &shelly.SwitchActionResponse{WasOn: wasOn}, nil
+}
+
+func (d *Device) lightSet(params json.RawMessage) (any, error) {
+	key, c, err := d.lookup("light", params)
+	if err != nil {
+		return nil, err
+	}
+	var p shelly.LightSetRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.On == nil && p.Brightness == nil {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "One of 'on' or 'brightness' is required!")
+	}
+	if p.Brightness != nil {
+		if *p.Brightness < 0 || *p.Brightness > 100 {
+			return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Argument 'brightness' out of range!")
+		}
+		if numField(c.status, "brightness") != *p.Brightness {
+			c.status["brightness"] = *p.Brightness
+			c.status["source"] = sourceRPC
+			d.notifyStatus(key, map[string]any{"brightness": *p.Brightness, "source": sourceRPC})
+		}
+	}
+	on := boolField(c.status, "output")
+	if p.On != nil {
+		on = *p.On
+	}
+	if p.On != nil || p.ToggleAfter != nil {
+		d.setOutput(key, c, on, p.ToggleAfter, sourceRPC)
+	}
+	return nil, nil
+}
+
+func (d *Device) lightToggle(params json.RawMessage) (any, error) {
+	key, c, err := d.lookup("light", params)
+	if err != nil {
+		return nil, err
+	}
+	d.setOutput(key, c, !boolField(c.status, "output"), nil, sourceRPC)
+	return nil, nil
+}
+
+// setOutput turns the output of a switch or light on or off. The output flips back after
+// toggleAfter seconds or, if nil, the configured auto_off or auto_on delay. d.mu must be held.
+func (d *Device) setOutput(key string, c *component, on bool, toggleAfter *float64, source string) {
+	d.stopTimer(key)
+	delta := map[string]any{}
+	if boolField(c.status, "output") != on {
+		c.status["output"] = on
+		c.status["source"] = source
+		delta["output"], delta["source"] = on, source
+	}
+	if _, ok := c.status["timer_started_at"]; ok {
+		delete(c.status, "timer_started_at")
+		delete(c.status, "timer_duration")
+		delta["timer_started_at"], delta["timer_duration"] = nil, nil
+	}
+
+	var flipAfter float64
+	switch {
+	case toggleAfter != nil:
+		flipAfter = *toggleAfter
+	case on && boolField(c.config, "auto_off"):
+		flipAfter = numField(c.config, "auto_off_delay")
+	case !on && boolField(c.config, "auto_on"):
+		flipAfter = numField(c.config, "auto_on_delay")
+	}
+	if flipAfter > 0 {
+		c.status["timer_started_at"] = d.timestamp()
+		c.status["timer_duration"] = flipAfter
+		delta["timer_started_at"], delta["timer_duration"] = c.status["timer_started_at"], flipAfter
+		d.after(key, time.Duration(flipAfter*float64(time.Second)), func() {
+			d.setOutput(key, c, !on, nil, sourceTimer)
+		})
+	}
+	if len(delta) > 0 {
+		d.notifyStatus(key, delta)
+	}
+}
+
+// boolField reads a boolean from decoded JSON, defaulting to false.
+func boolField(m map[string]any, k string) bool {
+	b, _ := m[k].(bool)
+	return b
+}
+
+// numField reads a number from decoded JSON or a component default, defaulting to 0.
+func numField(m map[string]any, k string) float64 {
+	switch v := m[k].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
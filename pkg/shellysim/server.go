@@ -0,0 +1,184 @@
+package shellysim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// ServeHTTP implements http.Handler, serving the channels of a device:
+//   - POST /rpc with a JSON-RPC request frame.
+//   - GET /rpc/<method>?<params>, with params encoded as by shelly.EncodeQuery. Digest
+//     authentication via the Authorization header is not emulated, so these calls fail once
+//     authentication is enabled.
+//   - WebSocket /rpc, which also carries notifications once the client has sent a request with
+//     a src.
+func (d *Device) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/rpc" && websocket.IsWebSocketUpgrade(r):
+		d.serveWebSocket(w, r)
+	case r.URL.Path == "/rpc" && r.Method == http.MethodPost:
+		d.servePost(w, r)
+	case strings.HasPrefix(r.URL.Path, shelly.RPCPathPrefix) && r.Method == http.MethodGet:
+		d.serveQuery(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *Device) servePost(w http.ResponseWriter, r *http.Request) {
+	var req shelly.RPCFrame
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := d.dispatch(req.Method, req.Params, func(ha1 string) bool {
+		return d.verifyAuth(req.Auth, ha1)
+	})
+	if d.writeHTTPError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d.response(&req, result, err))
+}
+
+func (d *Device) serveQuery(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, shelly.RPCPathPrefix)
+	q := &queryRequest{method: method}
+	if err := shelly.DecodeQuery(r.URL.String(), q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := d.dispatch(method, q.params, func(string) bool { return false })
+	if d.writeHTTPError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	var rpcErr *shelly.BadStatusWithMessageError
+	if errors.As(err, &rpcErr) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&shelly.RPCFrameError{Code: int(rpcErr.Status), Message: rpcErr.Msg})
+		return
+	}
+	if len(result) == 0 {
+		result = json.RawMessage("null")
+	}
+	w.Write(result)
+}
+
+// writeHTTPError answers errors which HTTP carries outside of the response frame, returning
+// true if it did.
+func (d *Device) writeHTTPError(w http.ResponseWriter, err error) bool {
+	var rpcErr *shelly.BadStatusWithMessageError
+	switch {
+	case errors.Is(err, ErrDisconnected):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.As(err, &rpcErr) && rpcErr.Status == shelly.ErrRPCUnauthorized:
+		c := d.challenge()
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Digest qop="auth", realm=%q, nonce="%x", algorithm=%s`, c.Realm, c.Nonce, c.Algorithm))
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		return false
+	}
+	return true
+}
+
+// response builds the response frame for a request.
+func (d *Device) response(req *shelly.RPCFrame, result json.RawMessage, err error) *shelly.RPCFrame {
+	resp := &shelly.RPCFrame{ID: req.ID, Src: d.id, Dst: req.Src}
+	var rpcErr *shelly.BadStatusWithMessageError
+	switch {
+	case errors.As(err, &rpcErr):
+		resp.Error = &shelly.RPCFrameError{Code: int(rpcErr.Status), Message: rpcErr.Msg}
+	case err != nil:
+		resp.Error = &shelly.RPCFrameError{Code: int(shelly.ErrRPCUnavailable), Message: err.Error()}
+	default:
+		resp.Result = result
+		if len(resp.Result) == 0 {
+			resp.Result = json.RawMessage("null")
+		}
+	}
+	return resp
+}
+
+func (d *Device) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var (
+		writeMu sync.Mutex
+		dst     string
+	)
+	write := func(f *shelly.RPCFrame) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.WriteJSON(f)
+	}
+	unsubscribe := d.subscribe(func(f *shelly.RPCFrame) {
+		writeMu.Lock()
+		to := dst
+		writeMu.Unlock()
+		if to == "" {
+			// Devices only notify peers which have identified themselves.
+			return
+		}
+		n := *f
+		n.Dst = to
+		write(&n)
+	})
+	defer unsubscribe()
+
+	for {
+		var req shelly.RPCFrame
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if req.Src != "" {
+			writeMu.Lock()
+			dst = req.Src
+			writeMu.Unlock()
+		}
+		result, err := d.dispatch(req.Method, req.Params, func(ha1 string) bool {
+			return d.verifyAuth(req.Auth, ha1)
+		})
+		if errors.Is(err, ErrDisconnected) {
+			return
+		}
+		write(d.response(&req, result, err))
+	}
+}
+
+// queryRequest adapts an HTTP GET call for shelly.DecodeQuery, keeping the decoded params.
+type queryRequest struct {
+	method string
+	params json.RawMessage
+}
+
+func (q *queryRequest) Method() string {
+	return q.method
+}
+
+func (q *queryRequest) Idempotent() bool {
+	return false
+}
+
+func (q *queryRequest) NewResponse() any {
+	return &json.RawMessage{}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *queryRequest) UnmarshalJSON(b []byte) error {
+	q.params = append(json.RawMessage(nil), b...)
+	return nil
+}
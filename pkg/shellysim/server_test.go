@@ -0,0 +1,118 @@
+package shellysim
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+func TestServerHTTPAuth(t *testing.T) {
+	ctx := context.Background()
+	d := pro4PM(t)
+	ts := httptest.NewServer(d)
+	defer ts.Close()
+	c := shelly.NewHTTPClient(ts.URL + "/rpc")
+
+	info, _, err := (&shelly.ShellyGetDeviceInfoRequest{}).Do(ctx, c, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultID, info.ID)
+	assert.Equal(t, "Pro4PM", info.App)
+	assert.False(t, info.AuthEn)
+
+	ha1 := authHA1(DefaultID, "hunter2")
+	_, _, err = (&shelly.ShellySetAuthRequest{
+		User: shelly.DefaultAuthenticationUsername, Realm: DefaultID, HA1: &ha1,
+	}).Do(ctx, c, nil)
+	require.NoError(t, err)
+
+	_, _, err = (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, c, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnauthorized)
+
+	wrong := func() (string, string, error) { return shelly.DefaultAuthenticationUsername, "nope", nil }
+	_, _, err = (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, shelly.NewHTTPClient(ts.URL+"/rpc"), wrong)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnauthorized)
+
+	creds := func() (string, string, error) { return shelly.DefaultAuthenticationUsername, "hunter2", nil }
+	resp, _, err := (&shelly.SwitchSetRequest{ID: 0, On: true}).Do(ctx, c, creds)
+	require.NoError(t, err)
+	assert.False(t, resp.WasOn)
+
+	// Device info remains available without credentials.
+	info, _, err = (&shelly.ShellyGetDeviceInfoRequest{}).Do(ctx, c, nil)
+	require.NoError(t, err)
+	assert.True(t, info.AuthEn)
+}
+
+func TestServerQuery(t *testing.T) {
+	d := pro4PM(t)
+	ts := httptest.NewServer(d)
+	defer ts.Close()
+
+	u, err := shelly.RequestURL(ts.URL, &shelly.SwitchSetRequest{ID: 3, On: true})
+	require.NoError(t, err)
+	resp, err := http.Get(u)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"was_on":false}`, string(body))
+
+	status, ok := d.Status("switch:3")
+	require.True(t, ok)
+	assert.Equal(t, true, status["output"])
+
+	resp, err = http.Get(ts.URL + "/rpc/Switch.GetStatus?id=9")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestServerWebSocketNotifications(t *testing.T) {
+	ctx := context.Background()
+	d := pro4PM(t, WithPassword("hunter2"))
+	ts := httptest.NewServer(d)
+	defer ts.Close()
+
+	c, err := shelly.NewWebSocketClient(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/rpc")
+	require.NoError(t, err)
+	defer c.Disconnect(ctx)
+	creds := func() (string, string, error) { return shelly.DefaultAuthenticationUsername, "hunter2", nil }
+
+	statuses := c.SubscribeStatus(ctx)
+	events := c.SubscribeEvent(ctx)
+	err = c.Announce(ctx)
+	require.NoError(t, err)
+
+	_, _, err = (&shelly.SwitchSetRequest{ID: 2, On: true}).Do(ctx, c, creds)
+	require.NoError(t, err)
+	select {
+	case n := <-statuses:
+		require.Len(t, n.Value.Switches, 1)
+		assert.Equal(t, 2, n.Value.Switches[0].ID)
+		assert.True(t, *n.Value.Switches[0].Output)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no NotifyStatus received")
+	}
+
+	d.EmitEvent("input:0", "single_push", nil)
+	select {
+	case n := <-events:
+		require.Len(t, n.Value.Events, 1)
+		assert.Equal(t, "input:0", n.Value.Events[0].Component)
+		assert.Equal(t, "single_push", n.Value.Events[0].Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no NotifyEvent received")
+	}
+
+	_, _, err = (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, c, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnauthorized)
+}
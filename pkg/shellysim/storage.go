@@ -0,0 +1,464 @@
+package shellysim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// script holds the code of a script component; its config and status are in d.components.
+type script struct {
+	code string
+}
+
+// kvsEntry is a value stored in the KVS.
+type kvsEntry struct {
+	value any
+	etag  string
+}
+
+func (d *Device) scriptCreate(params json.RawMessage) (any, error) {
+	var p shelly.ScriptCreateRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(d.scripts) >= d.maxScripts {
+		return nil, rpcError(shelly.ErrRPCResourcesExhausted, "Too many scripts!")
+	}
+	d.nextID["script"]++
+	id := d.nextID["script"]
+	var name any
+	if p.Name != nil {
+		name = *p.Name
+	}
+	d.scripts[id] = &script{}
+	d.components[fmt.Sprintf("script:%d", id)] = &component{
+		config: map[string]any{"id": id, "name": name, "enable": false},
+		status: map[string]any{"id": id, "running": false},
+	}
+	d.configChanged()
+	return &shelly.ScriptCreateResponse{ID: id}, nil
+}
+
+// lookupScript finds the script addressed by params. d.mu must be held.
+func (d *Device) lookupScript(params json.RawMessage) (string, *component, *script, error) {
+	key, c, err := d.lookup("script", params)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return key, c, d.scripts[int(numField(c.config, "id"))], nil
+}
+
+func (d *Device) scriptPutCode(params json.RawMessage) (any, error) {
+	_, _, s, err := d.lookupScript(params)
+	if err != nil {
+		return nil, err
+	}
+	var p shelly.ScriptPutCodeRequest
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Append {
+		s.code += p.Code
+	} else {
+		s.code = p.Code
+	}
+	return &shelly.ScriptPutCodeResponse{Len: len(s.code)}, nil
+}
+
+func (d *Device) scriptGetCode(params json.RawMessage) (any, error) {
+	_, _, s, err := d.lookupScript(params)
+	if err != nil {
+		return nil, err
+	}
+	var p struct {
+		Offset int  `json:"offset"`
+		Len    *int `json:"len"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Offset < 0 || p.Offset > len(s.code) {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Argument 'offset' out of range!")
+	}
+	end := len(s.code)
+	if p.Len != nil && p.Offset+*p.Len < end {
+		end = p.Offset + *p.Len
+	}
+	return map[string]any{"data": s.code[p.Offset:end], "left": len(s.code) - end}, nil
+}
+
+func (d *Device) scriptStart(params json.RawMessage) (any, error) {
+	key, c, _, err := d.lookupScript(params)
+	if err != nil {
+		return nil, err
+	}
+	return &shelly.ScriptStartResponse{WasRunning: d.setScriptRunning(key, c, true)}, nil
+}
+
+func (d *Device) scriptStop(params json.RawMessage) (any, error) {
+	key, c, _, err := d.lookupScript(params)
+	if err != nil {
+		return nil, err
+	}
+	return &shelly.ScriptStopResponse{WasRunning: d.setScriptRunning(key, c, false)}, nil
+}
+
+// setScriptRunning starts or stops a script, returning whether it was running. Scripts aren't
+// executed. d.mu must be held.
+func (d *Device) setScriptRunning(key string, c *component, running bool) bool {
+	was := boolField(c.status, "running")
+	if was != running {
+		c.status["running"] = running
+		d.notifyStatus(key, map[string]any{"running": running})
+	}
+	return was
+}
+
+func (d *Device) scriptList(params json.RawMessage) (any, error) {
+	resp := &shelly.ScriptListResponse{Scripts: []shelly.ScriptListScript{}}
+	for _, k := range d.keys() {
+		if !strings.HasPrefix(k, "script:") {
+			continue
+		}
+		c := d.components[k]
+		name, _ := c.config["name"].(string)
+		resp.Scripts = append(resp.Scripts, shelly.ScriptListScript{
+			ID:      int(numField(c.config, "id")),
+			Name:    name,
+			Enable:  boolField(c.config, "enable"),
+			Running: boolField(c.status, "running"),
+		})
+	}
+	sort.Slice(resp.Scripts, func(i, j int) bool { return resp.Scripts[i].ID < resp.Scripts[j].ID })
+	return resp, nil
+}
+
+func (d *Device) scriptDelete(params json.RawMessage) (any, error) {
+	key, c, _, err := d.lookupScript(params)
+	if err != nil {
+		return nil, err
+	}
+	if boolField(c.status, "running") {
+		return nil, rpcError(shelly.ErrRPCFailedPrecondition, "Script is running!")
+	}
+	delete(d.scripts, int(numField(c.config, "id")))
+	delete(d.components, key)
+	d.configChanged()
+	return nil, nil
+}
+
+// kvsChanged bumps kvs_rev and notifies subscribers. d.mu must be held.
+func (d *Device) kvsChanged() {
+	d.kvsRev++
+	d.components["sys"].status["kvs_rev"] = d.kvsRev
+	d.notifyStatus("sys", map[string]any{"kvs_rev": d.kvsRev})
+}
+
+// kvsParams are the params of the KVS methods.
+type kvsParams struct {
+	Key    string  `json:"key"`
+	Value  any     `json:"value"`
+	Etag   *string `json:"etag"`
+	Match  *string `json:"match"`
+	Offset int     `json:"offset"`
+}
+
+// lookupKVS finds the entry addressed by params, checking the etag if given. d.mu must be held.
+func (d *Device) lookupKVS(params json.RawMessage) (*kvsParams, *kvsEntry, error) {
+	var p kvsParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, nil, err
+	}
+	if p.Key == "" {
+		return nil, nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'key'!")
+	}
+	e, ok := d.kvs[p.Key]
+	if !ok {
+		return &p, nil, rpcError(shelly.ErrRPCUnknownComponentID,
+			fmt.Sprintf("Argument 'key', value %q not found!", p.Key))
+	}
+	if p.Etag != nil && *p.Etag != e.etag {
+		return &p, e, rpcError(shelly.ErrRPCFailedPrecondition, "Etag mismatch!")
+	}
+	return &p, e, nil
+}
+
+func (d *Device) kvsSet(params json.RawMessage) (any, error) {
+	p, _, err := d.lookupKVS(params)
+	if p == nil || (err != nil && p.Etag != nil) {
+		// Setting a missing key is allowed, unless an etag was given.
+		return nil, err
+	}
+	if p.Value == nil {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'value'!")
+	}
+	b, _ := json.Marshal(p.Value)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s", p.Key, d.kvsRev+1, b)))
+	e := &kvsEntry{value: p.Value, etag: hex.EncodeToString(sum[:12])}
+	d.kvs[p.Key] = e
+	d.kvsChanged()
+	return map[string]any{"etag": e.etag, "rev": d.kvsRev}, nil
+}
+
+func (d *Device) kvsGet(params json.RawMessage) (any, error) {
+	_, e, err := d.lookupKVS(params)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"etag": e.etag, "value": deepCopy(e.value)}, nil
+}
+
+func (d *Device) kvsDelete(params json.RawMessage) (any, error) {
+	p, _, err := d.lookupKVS(params)
+	if err != nil {
+		return nil, err
+	}
+	delete(d.kvs, p.Key)
+	d.kvsChanged()
+	return map[string]any{"rev": d.kvsRev}, nil
+}
+
+// kvsMatch returns the sorted keys matching the pattern in params. d.mu must be held.
+func (d *Device) kvsMatch(params json.RawMessage) (*kvsParams, []string, error) {
+	var p kvsParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, nil, err
+	}
+	pattern := "*"
+	if p.Match != nil {
+		pattern = *p.Match
+	}
+	var keys []string
+	for k := range d.kvs {
+		if matchKey(pattern, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return &p, keys, nil
+}
+
+func (d *Device) kvsGetMany(params json.RawMessage) (any, error) {
+	p, keys, err := d.kvsMatch(params)
+	if err != nil {
+		return nil, err
+	}
+	items := []any{}
+	for i, k := range keys {
+		if i >= p.Offset {
+			items = append(items, map[string]any{
+				"key": k, "etag": d.kvs[k].etag, "value": deepCopy(d.kvs[k].value),
+			})
+		}
+	}
+	return map[string]any{"items": items, "offset": p.Offset, "total": len(keys)}, nil
+}
+
+func (d *Device) kvsList(params json.RawMessage) (any, error) {
+	_, keys, err := d.kvsMatch(params)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]any, len(keys))
+	for _, k := range keys {
+		out[k] = map[string]any{"etag": d.kvs[k].etag}
+	}
+	return map[string]any{"keys": out, "rev": d.kvsRev}, nil
+}
+
+// matchKey matches a KVS key against a pattern where * matches any sequence of characters.
+func matchKey(pattern, key string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(key, parts[0]) {
+		return false
+	}
+	key = key[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(key, part)
+		}
+		idx := strings.Index(key, part)
+		if idx < 0 {
+			return false
+		}
+		key = key[idx+len(part):]
+	}
+	return key == ""
+}
+
+// scheduleChanged bumps schedule_rev and notifies subscribers. d.mu must be held.
+func (d *Device) scheduleChanged() {
+	d.scheduleRev++
+	d.components["sys"].status["schedule_rev"] = d.scheduleRev
+	d.notifyStatus("sys", map[string]any{"schedule_rev": d.scheduleRev})
+}
+
+func (d *Device) scheduleCreate(params json.RawMessage) (any, error) {
+	var job map[string]any
+	if err := decodeParams(params, &job); err != nil {
+		return nil, err
+	}
+	if _, ok := job["timespec"].(string); !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'timespec'!")
+	}
+	if _, ok := job["calls"].([]any); !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'calls'!")
+	}
+	if _, ok := job["enable"]; !ok {
+		job["enable"] = true
+	}
+	d.nextID["schedule"]++
+	id := d.nextID["schedule"]
+	job["id"] = id
+	d.schedules[id] = job
+	d.scheduleChanged()
+	return map[string]any{"id": id, "rev": d.scheduleRev}, nil
+}
+
+// lookupJob finds the schedule or webhook in jobs addressed by params.
+func lookupJob(jobs map[int]map[string]any, params json.RawMessage) (int, error) {
+	var p struct {
+		ID *int `json:"id"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return 0, err
+	}
+	if p.ID == nil {
+		return 0, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'id'!")
+	}
+	if _, ok := jobs[*p.ID]; !ok {
+		return 0, rpcError(shelly.ErrRPCUnknownComponentID,
+			fmt.Sprintf("Argument 'id', value %d not found!", *p.ID))
+	}
+	return *p.ID, nil
+}
+
+func (d *Device) scheduleUpdate(params json.RawMessage) (any, error) {
+	id, err := lookupJob(d.schedules, params)
+	if err != nil {
+		return nil, err
+	}
+	var patch map[string]any
+	if err := decodeParams(params, &patch); err != nil {
+		return nil, err
+	}
+	delete(patch, "id")
+	for k, v := range patch {
+		d.schedules[id][k] = v
+	}
+	d.scheduleChanged()
+	return map[string]any{"rev": d.scheduleRev}, nil
+}
+
+func (d *Device) scheduleList(params json.RawMessage) (any, error) {
+	return map[string]any{"jobs": listJobs(d.schedules), "rev": d.scheduleRev}, nil
+}
+
+// listJobs returns copies of the schedules or webhooks in jobs, ordered by id.
+func listJobs(jobs map[int]map[string]any) []any {
+	ids := make([]int, 0, len(jobs))
+	for id := range jobs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	out := make([]any, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, deepCopy(jobs[id]))
+	}
+	return out
+}
+
+func (d *Device) scheduleDelete(params json.RawMessage) (any, error) {
+	id, err := lookupJob(d.schedules, params)
+	if err != nil {
+		return nil, err
+	}
+	delete(d.schedules, id)
+	d.scheduleChanged()
+	return map[string]any{"rev": d.scheduleRev}, nil
+}
+
+func (d *Device) scheduleDeleteAll(params json.RawMessage) (any, error) {
+	for id := range d.schedules {
+		delete(d.schedules, id)
+	}
+	d.scheduleChanged()
+	return map[string]any{"rev": d.scheduleRev}, nil
+}
+
+// webhookChanged bumps webhook_rev and notifies subscribers. d.mu must be held.
+func (d *Device) webhookChanged() {
+	d.webhookRev++
+	d.components["sys"].status["webhook_rev"] = d.webhookRev
+	d.notifyStatus("sys", map[string]any{"webhook_rev": d.webhookRev})
+}
+
+func (d *Device) webhookCreate(params json.RawMessage) (any, error) {
+	var hook map[string]any
+	if err := decodeParams(params, &hook); err != nil {
+		return nil, err
+	}
+	if _, ok := hook["event"].(string); !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'event'!")
+	}
+	if _, ok := hook["cid"].(float64); !ok {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'cid'!")
+	}
+	if urls, ok := hook["urls"].([]any); !ok || len(urls) == 0 {
+		return nil, rpcError(shelly.ErrRPCInvalidOrMissingArguments, "Missing required argument 'urls'!")
+	}
+	if _, ok := hook["enable"]; !ok {
+		hook["enable"] = true
+	}
+	d.nextID["webhook"]++
+	id := d.nextID["webhook"]
+	hook["id"] = id
+	d.webhooks[id] = hook
+	d.webhookChanged()
+	return map[string]any{"id": id, "rev": d.webhookRev}, nil
+}
+
+func (d *Device) webhookUpdate(params json.RawMessage) (any, error) {
+	id, err := lookupJob(d.webhooks, params)
+	if err != nil {
+		return nil, err
+	}
+	var patch map[string]any
+	if err := decodeParams(params, &patch); err != nil {
+		return nil, err
+	}
+	delete(patch, "id")
+	for k, v := range patch {
+		d.webhooks[id][k] = v
+	}
+	d.webhookChanged()
+	return map[string]any{"rev": d.webhookRev}, nil
+}
+
+func (d *Device) webhookList(params json.RawMessage) (any, error) {
+	return map[string]any{"hooks": listJobs(d.webhooks), "rev": d.webhookRev}, nil
+}
+
+func (d *Device) webhookDelete(params json.RawMessage) (any, error) {
+	id, err := lookupJob(d.webhooks, params)
+	if err != nil {
+		return nil, err
+	}
+	delete(d.webhooks, id)
+	d.webhookChanged()
+	return map[string]any{"rev": d.webhookRev}, nil
+}
+
+func (d *Device) webhookDeleteAll(params json.RawMessage) (any, error) {
+	for id := range d.webhooks {
+		delete(d.webhooks, id)
+	}
+	d.webhookChanged()
+	return map[string]any{"rev": d.webhookRev}, nil
+}
@@ -0,0 +1,225 @@
+package shellytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// Expectation is a call expected by a FakeRPC. Its methods return the expectation so they can
+// be chained.
+type Expectation struct {
+	f       *FakeRPC
+	method  string
+	matcher Matcher
+	after   []*Expectation
+
+	// min and max are the allowed number of calls; max is negative if unlimited.
+	min, max int
+	calls    int
+
+	response *shelly.RPCFrame
+	err      error
+	respond  func(params json.RawMessage) (any, error)
+}
+
+// WithParams restricts the expectation to calls whose params are accepted by m.
+func (e *Expectation) WithParams(m Matcher) *Expectation {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	e.matcher = m
+	return e
+}
+
+// Return answers matching calls with resp, which is encoded as JSON unless it's a
+// json.RawMessage.
+func (e *Expectation) Return(resp any) *Expectation {
+	b, err := marshalResponse(resp)
+	if err != nil {
+		e.f.t.Fatalf("shellytest: encoding response for %s: %v", e.method, err)
+	}
+	return e.set(&shelly.RPCFrame{Result: b}, nil, nil)
+}
+
+// ReturnError answers matching calls with the device error code and message, which shelly.Do
+// returns as a *shelly.BadStatusWithMessageError.
+func (e *Expectation) ReturnError(code shelly.ShellyErrorCode, msg string) *Expectation {
+	return e.set(errorResponse(code, msg), nil, nil)
+}
+
+// Fail makes matching calls fail with err, as if the channel failed.
+func (e *Expectation) Fail(err error) *Expectation {
+	return e.set(nil, err, nil)
+}
+
+// ReturnFunc answers matching calls with the result of f. Errors which wrap a
+// shelly.ShellyErrorCode are returned as device errors; others fail the call.
+func (e *Expectation) ReturnFunc(f func(params json.RawMessage) (any, error)) *Expectation {
+	return e.set(nil, nil, f)
+}
+
+func (e *Expectation) set(
+	resp *shelly.RPCFrame,
+	err error,
+	respond func(params json.RawMessage) (any, error),
+) *Expectation {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	e.response, e.err, e.respond = resp, err, respond
+	return e
+}
+
+// Times requires exactly n matching calls.
+func (e *Expectation) Times(n int) *Expectation {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	e.min, e.max = n, n
+	return e
+}
+
+// AnyTimes allows any number of matching calls, including none.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	e.min, e.max = 0, -1
+	return e
+}
+
+// After requires each of prereqs to be satisfied before the expectation is called.
+func (e *Expectation) After(prereqs ...*Expectation) *Expectation {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	e.after = append(e.after, prereqs...)
+	return e
+}
+
+// String describes the expectation in failure messages.
+func (e *Expectation) String() string {
+	if e.matcher == nil {
+		return e.method
+	}
+	return e.method + " (with params)"
+}
+
+func (e *Expectation) timesString() string {
+	switch {
+	case e.max < 0:
+		return "at least " + strconv.Itoa(e.min)
+	case e.min == e.max:
+		return strconv.Itoa(e.min)
+	}
+	return fmt.Sprintf("%d to %d", e.min, e.max)
+}
+
+// Matcher accepts or rejects the encoded params of a call, describing the mismatch if rejected.
+type Matcher func(params json.RawMessage) error
+
+// Any matches all params.
+func Any() Matcher {
+	return func(json.RawMessage) error { return nil }
+}
+
+// Equals matches params with the same JSON encoding as v, ignoring key order.
+func Equals(v any) Matcher {
+	want, err := normalize(v)
+	return func(params json.RawMessage) error {
+		if err != nil {
+			return fmt.Errorf("encoding expected params: %w", err)
+		}
+		got, err := normalize(params)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(want, got) {
+			return fmt.Errorf("params %s, want %s", params, mustMarshal(want))
+		}
+		return nil
+	}
+}
+
+// Subset matches params which contain every field in the JSON encoding of v with the same
+// value. Nested objects are compared the same way, so unset fields of v are ignored when it's a
+// request with omitempty fields.
+func Subset(v any) Matcher {
+	want, err := normalize(v)
+	return func(params json.RawMessage) error {
+		if err != nil {
+			return fmt.Errorf("encoding expected params: %w", err)
+		}
+		got, err := normalize(params)
+		if err != nil {
+			return err
+		}
+		if !contains(got, want) {
+			return fmt.Errorf("params %s, want a superset of %s", params, mustMarshal(want))
+		}
+		return nil
+	}
+}
+
+// Func decodes params into a T, ex. *shelly.SwitchSetRequest, and matches them if f returns true.
+func Func[T any](f func(T) bool) Matcher {
+	return func(params json.RawMessage) error {
+		var v T
+		if err := json.Unmarshal(params, &v); err != nil {
+			return fmt.Errorf("decoding params as %T: %w", v, err)
+		}
+		if !f(v) {
+			return fmt.Errorf("params %s rejected by matcher", params)
+		}
+		return nil
+	}
+}
+
+// contains reports whether got has every field of want with an equal value.
+func contains(got, want any) bool {
+	wantObj, ok := want.(map[string]any)
+	if !ok {
+		return reflect.DeepEqual(got, want)
+	}
+	gotObj, ok := got.(map[string]any)
+	if !ok {
+		return false
+	}
+	for k, w := range wantObj {
+		g, ok := gotObj[k]
+		if !ok || !contains(g, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize decodes the JSON encoding of v into generic values, treating null as {}.
+func normalize(v any) (any, error) {
+	b, ok := v.(json.RawMessage)
+	if !ok {
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("decoding params: %w", err)
+	}
+	if out == nil {
+		out = map[string]any{}
+	}
+	return out, nil
+}
+
+func mustMarshal(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func marshalResponse(resp any) (json.RawMessage, error) {
+	if b, ok := resp.(json.RawMessage); ok {
+		return b, nil
+	}
+	return json.Marshal(resp)
+}
@@ -0,0 +1,268 @@
+// Package shellytest provides a scriptable fake shelly.Caller for unit tests of code which
+// calls Shelly devices.
+//
+// Tests register the calls they expect, with param matchers and canned responses or device
+// errors. Calls are checked against the expectations as they arrive, and expected call counts
+// are verified when the test finishes:
+//
+//	f := shellytest.New(t)
+//	f.ExpectRequest(&shelly.SwitchSetRequest{ID: 0, On: true}).
+//		Return(&shelly.SwitchActionResponse{WasOn: false})
+//	f.Expect("Switch.GetStatus").ReturnError(shelly.ErrRPCUnknownComponentID, "bad id")
+package shellytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// DefaultSrc is the source of notifications injected with Notify.
+const DefaultSrc = "shellytest-fake"
+
+var (
+	// ErrUnexpectedCall is returned by FakeRPC for calls which match no expectation.
+	ErrUnexpectedCall = errors.New("unexpected call")
+
+	// ErrDisconnected is returned by FakeRPC for calls made after Disconnect.
+	ErrDisconnected = errors.New("fake rpc channel disconnected")
+)
+
+// Call is a call received by a FakeRPC.
+type Call struct {
+	// Method is the name of the method called.
+	Method string
+
+	// Params are the encoded params of the call.
+	Params json.RawMessage
+}
+
+// FakeRPC implements shelly.Caller, answering calls from registered expectations. Calls which
+// don't match an expectation, or arrive before the expectations they're ordered after, are
+// reported as test errors.
+type FakeRPC struct {
+	t testing.TB
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []*Call
+	disconnected bool
+
+	notifications shelly.NotificationHub
+}
+
+// New returns a FakeRPC which reports failures to t, and verifies its expectations when the
+// test finishes.
+func New(t testing.TB) *FakeRPC {
+	f := &FakeRPC{t: t}
+	t.Cleanup(f.AssertExpectations)
+	return f
+}
+
+// Expect registers an expectation for a call to method. By default it matches any params,
+// must be called exactly once, and returns an empty response.
+func (f *FakeRPC) Expect(method string) *Expectation {
+	e := &Expectation{
+		f:        f,
+		method:   method,
+		min:      1,
+		max:      1,
+		response: &shelly.RPCFrame{Result: json.RawMessage("{}")},
+	}
+	f.mu.Lock()
+	f.expectations = append(f.expectations, e)
+	f.mu.Unlock()
+	return e
+}
+
+// ExpectRequest registers an expectation for a call with the method and params of req.
+func (f *FakeRPC) ExpectRequest(req shelly.RPCRequestBody) *Expectation {
+	return f.Expect(req.Method()).WithParams(Equals(req))
+}
+
+// InOrder requires each expectation to be satisfied before the next is called.
+func (f *FakeRPC) InOrder(expectations ...*Expectation) {
+	for i := 1; i < len(expectations); i++ {
+		expectations[i].After(expectations[i-1])
+	}
+}
+
+// Calls returns the calls received so far, in the order they were made.
+func (f *FakeRPC) Calls() []*Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*Call(nil), f.calls...)
+}
+
+// CallCount returns the number of calls received for method.
+func (f *FakeRPC) CallCount(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int
+	for _, c := range f.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// AssertExpectations reports an error for each expectation which hasn't been called its
+// minimum number of times. It's called automatically when the test finishes.
+func (f *FakeRPC) AssertExpectations() {
+	f.t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.expectations {
+		if e.calls < e.min {
+			f.t.Errorf("shellytest: %s called %d times, expected %s", e, e.calls, e.timesString())
+		}
+	}
+}
+
+// Notify delivers the notification n, ex. a *shelly.NotifyStatus or *shelly.NotifyEvent, to
+// subscribers of n.Method(). It's a no-op if there are none.
+func (f *FakeRPC) Notify(n interface{ Method() string }) error {
+	params, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("marshalling notification: %w", err)
+	}
+	f.NotifyRaw(n.Method(), params)
+	return nil
+}
+
+// NotifyRaw delivers a notification with the given method and encoded params to its
+// subscribers.
+func (f *FakeRPC) NotifyRaw(method string, params json.RawMessage) {
+	f.notifications.Publish(&shelly.RPCFrame{Src: DefaultSrc, Method: method, Params: params})
+}
+
+// Subscribe implements shelly.Subscriber. Subscribers receive notifications injected with
+// Notify.
+func (f *FakeRPC) Subscribe(ctx context.Context, method string) <-chan *shelly.RPCFrame {
+	return f.notifications.Subscribe(ctx, method)
+}
+
+// Call implements shelly.Caller.
+func (f *FakeRPC) Call(
+	ctx context.Context,
+	req *shelly.RPCFrame,
+	getCreds shelly.GetCredsCallback,
+) (*shelly.RPCFrame, error) {
+	f.t.Helper()
+	params := req.Params
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	f.mu.Lock()
+	if f.disconnected {
+		f.mu.Unlock()
+		return nil, ErrDisconnected
+	}
+	f.calls = append(f.calls, &Call{Method: req.Method, Params: params})
+	e, mismatches := f.match(req.Method, params)
+	if e == nil {
+		f.mu.Unlock()
+		msg := fmt.Sprintf("shellytest: unexpected call to %s with params %s", req.Method, params)
+		if len(mismatches) > 0 {
+			msg += "\n\t" + strings.Join(mismatches, "\n\t")
+		}
+		f.t.Errorf("%s", msg)
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedCall, req.Method)
+	}
+	var unsatisfied []string
+	for _, prereq := range e.after {
+		if prereq.calls < prereq.min {
+			unsatisfied = append(unsatisfied, prereq.String())
+		}
+	}
+	e.calls++
+	respond := e.respond
+	resp, err := e.response, e.err
+	f.mu.Unlock()
+
+	if len(unsatisfied) > 0 {
+		f.t.Errorf("shellytest: %s called before %s", e, strings.Join(unsatisfied, ", "))
+	}
+	if respond != nil {
+		return respondWith(respond(params))
+	}
+	if resp != nil {
+		// Copy the response so callers can't alter the canned one.
+		r := *resp
+		if r.Error != nil {
+			e := *r.Error
+			r.Error = &e
+		}
+		resp = &r
+	}
+	return resp, err
+}
+
+// match returns the first expectation which accepts the call and hasn't been exhausted, or
+// descriptions of why expectations for the method were rejected. f.mu must be held.
+func (f *FakeRPC) match(method string, params json.RawMessage) (*Expectation, []string) {
+	var mismatches []string
+	for _, e := range f.expectations {
+		if e.method != method {
+			continue
+		}
+		if e.max >= 0 && e.calls >= e.max {
+			mismatches = append(mismatches, fmt.Sprintf("%s: already called %d times", e, e.calls))
+			continue
+		}
+		if e.matcher != nil {
+			if err := e.matcher(params); err != nil {
+				mismatches = append(mismatches, fmt.Sprintf("%s: %v", e, err))
+				continue
+			}
+		}
+		return e, nil
+	}
+	return nil, mismatches
+}
+
+// respondWith converts the result of a ReturnFunc callback to a response.
+func respondWith(result any, err error) (*shelly.RPCFrame, error) {
+	var badStatus *shelly.BadStatusWithMessageError
+	var code shelly.ShellyErrorCode
+	switch {
+	case errors.As(err, &badStatus):
+		return errorResponse(badStatus.Status, badStatus.Msg), nil
+	case errors.As(err, &code):
+		return errorResponse(code, code.Error()), nil
+	case err != nil:
+		return nil, err
+	}
+	b, err := marshalResponse(result)
+	if err != nil {
+		return nil, err
+	}
+	return &shelly.RPCFrame{Result: b}, nil
+}
+
+// errorResponse returns a response frame with the device error code and msg.
+func errorResponse(code shelly.ShellyErrorCode, msg string) *shelly.RPCFrame {
+	return &shelly.RPCFrame{Error: &shelly.RPCFrameError{Code: int(code), Message: msg}}
+}
+
+// Disconnect makes calls made afterwards fail with ErrDisconnected.
+func (f *FakeRPC) Disconnect(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disconnected = true
+	return nil
+}
+
+// IsConnected is false once Disconnect is called.
+func (f *FakeRPC) IsConnected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.disconnected
+}
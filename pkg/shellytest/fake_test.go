@@ -0,0 +1,138 @@
+package shellytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelly "github.com/jcodybaker/go-shelly"
+)
+
+// recordingT captures failures so tests can assert on them.
+type recordingT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func (r *recordingT) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingT) finish() {
+	for _, f := range r.cleanups {
+		f()
+	}
+}
+
+func TestFakeRPC(t *testing.T) {
+	ctx := context.Background()
+	f := New(t)
+
+	f.ExpectRequest(&shelly.SwitchSetRequest{ID: 1, On: true}).
+		Return(&shelly.SwitchActionResponse{WasOn: true})
+	f.Expect("Switch.GetStatus").
+		WithParams(Func(func(req *shelly.SwitchGetStatusRequest) bool { return req.ID == 9 })).
+		ReturnError(shelly.ErrRPCUnknownComponentID, "component not found")
+	f.Expect("Switch.Toggle").WithParams(Subset(map[string]any{"id": 2})).Times(2)
+	f.Expect("Sys.GetStatus").Fail(errors.New("connection reset")).AnyTimes()
+
+	resp, _, err := (&shelly.SwitchSetRequest{ID: 1, On: true}).Do(ctx, f, nil)
+	require.NoError(t, err)
+	assert.True(t, resp.WasOn)
+
+	_, _, err = (&shelly.SwitchGetStatusRequest{ID: 9}).Do(ctx, f, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCUnknownComponentID)
+
+	for i := 0; i < 2; i++ {
+		_, _, err = (&shelly.SwitchToggleRequest{ID: 2}).Do(ctx, f, nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, f.CallCount("Switch.Toggle"))
+	assert.Len(t, f.Calls(), 4)
+}
+
+func TestFakeRPCReturnFunc(t *testing.T) {
+	ctx := context.Background()
+	f := New(t)
+	var on bool
+	f.Expect("Switch.Toggle").AnyTimes().ReturnFunc(func(params json.RawMessage) (any, error) {
+		was := on
+		on = !on
+		return &shelly.SwitchActionResponse{WasOn: was}, nil
+	})
+	f.Expect("Switch.Set").ReturnFunc(func(json.RawMessage) (any, error) {
+		return nil, shelly.ErrRPCFailedPrecondition
+	})
+
+	for _, want := range []bool{false, true, false} {
+		resp, _, err := (&shelly.SwitchToggleRequest{ID: 0}).Do(ctx, f, nil)
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.WasOn)
+	}
+	_, _, err := (&shelly.SwitchSetRequest{ID: 0, On: true}).Do(ctx, f, nil)
+	assert.ErrorIs(t, err, shelly.ErrRPCFailedPrecondition)
+}
+
+func TestFakeRPCFailures(t *testing.T) {
+	ctx := context.Background()
+	rt := &recordingT{}
+	f := New(rt)
+
+	set := f.ExpectRequest(&shelly.SwitchSetRequest{ID: 0, On: true})
+	get := f.Expect("Switch.GetStatus")
+	f.InOrder(set, get)
+	f.Expect("Sys.Reboot")
+
+	// Out of order calls are reported, but still answered.
+	_, _, err := (&shelly.SwitchGetStatusRequest{ID: 0}).Do(ctx, f, nil)
+	require.NoError(t, err)
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "Switch.GetStatus called before Switch.Set")
+
+	_, _, err = (&shelly.SwitchSetRequest{ID: 0, On: false}).Do(ctx, f, nil)
+	assert.ErrorIs(t, err, ErrUnexpectedCall)
+	require.Len(t, rt.errors, 2)
+	assert.Contains(t, rt.errors[1], "unexpected call to Switch.Set")
+	assert.Contains(t, rt.errors[1], `want {"id":0,"on":true}`)
+
+	rt.finish()
+	require.Len(t, rt.errors, 4)
+	assert.Contains(t, rt.errors[2], "Switch.Set (with params) called 0 times, expected 1")
+	assert.Contains(t, rt.errors[3], "Sys.Reboot called 0 times, expected 1")
+}
+
+func TestFakeRPCNotify(t *testing.T) {
+	f := New(t)
+	got := f.Subscribe(context.Background(), "NotifyEvent")
+
+	require.NoError(t, f.Notify(&shelly.NotifyEvent{
+		Events: []shelly.Event{{Component: "input:0", ID: 0, Event: "single_push"}},
+	}))
+	fr := <-got
+	assert.Equal(t, DefaultSrc, fr.Src)
+	var n shelly.NotifyEvent
+	require.NoError(t, json.Unmarshal(fr.Params, &n))
+	require.Len(t, n.Events, 1)
+	assert.Equal(t, "single_push", n.Events[0].Event)
+
+	require.NoError(t, f.Disconnect(context.Background()))
+	assert.False(t, f.IsConnected())
+	_, _, err := (&shelly.SysGetStatusRequest{}).Do(context.Background(), f, nil)
+	assert.ErrorIs(t, err, ErrDisconnected)
+}
@@ -0,0 +1,131 @@
+package shelly
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// https://shelly-api-docs.shelly.cloud/gen2/General/RPCProtocol#http-get-requests
+
+const (
+	// RPCPathPrefix is the path under which devices serve RPC methods via HTTP GET.
+	RPCPathPrefix = "/rpc/"
+)
+
+var (
+	// ErrNotRPCQuery is returned when decoding a URL whose path is not /rpc/<method>.
+	ErrNotRPCQuery = errors.New("url is not an rpc query")
+
+	// ErrQueryMethodMismatch is returned when a URL names a different method than the request
+	// it's being decoded into.
+	ErrQueryMethodMismatch = errors.New("rpc query method does not match request")
+)
+
+// EncodeQuery returns the path and query string (ex. `/rpc/Switch.Set?id=0&on=true`) which
+// makes the request via HTTP GET. Each parameter is JSON encoded, so strings are quoted and
+// objects and arrays are passed as JSON documents. Parameters encoded as null (ex. a null
+// Optional) are sent as `null`, while omitted parameters are left out of the query.
+func EncodeQuery(req RPCRequestBody) (string, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("marshalling shelly rpc request: %w", err)
+	}
+	var params map[string]json.RawMessage
+	if !bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		if err := json.Unmarshal(b, &params); err != nil {
+			return "", fmt.Errorf("rpc request params must be an object: %w", err)
+		}
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var q strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			q.WriteByte('&')
+		}
+		q.WriteString(url.QueryEscape(k))
+		q.WriteByte('=')
+		q.WriteString(url.QueryEscape(string(params[k])))
+	}
+	path := RPCPathPrefix + req.Method()
+	if q.Len() == 0 {
+		return path, nil
+	}
+	return path + "?" + q.String(), nil
+}
+
+// RequestURL returns the full URL (ex. `http://192.168.1.20/rpc/Switch.Set?id=0&on=true`) which
+// makes the request against the device at baseURL via HTTP GET. This is the form used by
+// webhook and schedule URL actions.
+func RequestURL(baseURL string, req RPCRequestBody) (string, error) {
+	q, err := EncodeQuery(req)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(baseURL, "/") + q, nil
+}
+
+// QueryMethod returns the method named by an RPC query URL.
+func QueryMethod(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing rpc query: %w", err)
+	}
+	return queryMethod(u)
+}
+
+func queryMethod(u *url.URL) (string, error) {
+	i := strings.Index(u.Path, RPCPathPrefix)
+	if i < 0 || len(u.Path) == i+len(RPCPathPrefix) {
+		return "", fmt.Errorf("%w: %q", ErrNotRPCQuery, u.Path)
+	}
+	return u.Path[i+len(RPCPathPrefix):], nil
+}
+
+// DecodeQuery parses an RPC query URL, as produced by EncodeQuery or RequestURL, into req. As on
+// the device, values which are not valid JSON are treated as strings. Parameters given as
+// `null` decode to null Optionals, or to nil pointers for other fields.
+func DecodeQuery(rawURL string, req RPCRequestBody) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parsing rpc query: %w", err)
+	}
+	method, err := queryMethod(u)
+	if err != nil {
+		return err
+	}
+	if method != req.Method() {
+		return fmt.Errorf("%w: %q != %q", ErrQueryMethodMismatch, method, req.Method())
+	}
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return fmt.Errorf("parsing rpc query: %w", err)
+	}
+	params := make(map[string]json.RawMessage, len(values))
+	for k, v := range values {
+		raw := []byte(v[len(v)-1])
+		if !json.Valid(raw) {
+			if raw, err = json.Marshal(string(raw)); err != nil {
+				return err
+			}
+		}
+		params[k] = raw
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, req); err != nil {
+		return fmt.Errorf("decoding rpc query params: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package shelly
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeQuery(t *testing.T) {
+	q, err := EncodeQuery(&SwitchSetRequest{ID: 0, On: true})
+	require.NoError(t, err)
+	assert.Equal(t, "/rpc/Switch.Set?id=0&on=true", q)
+
+	q, err = EncodeQuery(&ScriptPutCodeRequest{ID: 1, Code: "let a = 1;"})
+	require.NoError(t, err)
+	assert.Equal(t, "/rpc/Script.PutCode?code=%22let+a+%3D+1%3B%22&id=1", q)
+
+	// A null Optional is sent as null while unset fields are omitted.
+	u, err := RequestURL("http://192.168.1.20/", &MQTTSetConfigRequest{
+		Config: MQTTConfig{Enable: BoolPtr(true), TopicPrefix: Null[string]()},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://192.168.1.20/rpc/MQTT.SetConfig?config=%7B%22enabled%22%3Atrue%2C%22topic_prefix%22%3Anull%7D", u)
+
+	q, err = EncodeQuery(&ShellyGetStatusRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "/rpc/Shelly.GetStatus", q)
+}
+
+func TestDecodeQuery(t *testing.T) {
+	u, err := RequestURL("http://192.168.1.20", &MQTTSetConfigRequest{
+		Config: MQTTConfig{Enable: BoolPtr(true), TopicPrefix: Some("home/plug")},
+	})
+	require.NoError(t, err)
+	method, err := QueryMethod(u)
+	require.NoError(t, err)
+	assert.Equal(t, "MQTT.SetConfig", method)
+
+	var req MQTTSetConfigRequest
+	require.NoError(t, DecodeQuery(u, &req))
+	assert.True(t, *req.Config.Enable)
+	assert.Equal(t, "home/plug", req.Config.TopicPrefix.Or(""))
+
+	// Unquoted strings are accepted, as they are by devices.
+	var put ScriptPutCodeRequest
+	require.NoError(t, DecodeQuery("/rpc/Script.PutCode?id=2&code=print", &put))
+	assert.Equal(t, ScriptPutCodeRequest{ID: 2, Code: "print"}, put)
+
+	assert.ErrorIs(t, DecodeQuery("/rpc/Switch.Set?id=0", &put), ErrQueryMethodMismatch)
+	assert.ErrorIs(t, DecodeQuery("/status", &put), ErrNotRPCQuery)
+}
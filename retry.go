@@ -0,0 +1,138 @@
+package shelly
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy describes how failed calls are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is made, including the first.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier scales the delay after each attempt.
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to this fraction in either direction (ex. 0.2 for
+	// +/-20%) so clients don't retry in lockstep.
+	Jitter float64
+
+	// ShouldRetry decides whether an error is retryable. IsRetryable is used if nil.
+	ShouldRetry func(error) bool
+}
+
+// DefaultRetryPolicy makes up to 3 attempts, backing off from 100ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff returns the delay before the given retry (1 for the first retry).
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(math.Max(p.Multiplier, 1), float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+type retryNonIdempotentKey struct{}
+
+// WithRetryNonIdempotent returns a context which allows calls to be retried even if the request
+// is not idempotent.
+func WithRetryNonIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryNonIdempotentKey{}, true)
+}
+
+type idempotentRequestKey struct{}
+
+// mayRepeat returns true if the request being made with ctx may be sent more than once: it's
+// idempotent, or ctx allows retrying non-idempotent requests.
+func mayRepeat(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentRequestKey{}).(bool)
+	optIn, _ := ctx.Value(retryNonIdempotentKey{}).(bool)
+	return idempotent || optIn
+}
+
+// do makes the call, repeating it while it fails with a retryable error. Requests which aren't
+// idempotent are only retried if the context opts in.
+func (p RetryPolicy) do(
+	ctx context.Context,
+	req RPCRequestBody,
+	call func() (*RPCFrame, error),
+) (*RPCFrame, error) {
+	attempts := p.MaxAttempts
+	optIn, _ := ctx.Value(retryNonIdempotentKey{}).(bool)
+	if req == nil || (!req.Idempotent() && !optIn) || attempts < 1 {
+		attempts = 1
+	}
+	shouldRetry := p.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = IsRetryable
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := call()
+		failure := ResponseError(resp, err)
+		if failure == nil || attempt >= attempts || !shouldRetry(failure) || ctx.Err() != nil {
+			return resp, err
+		}
+		t := time.NewTimer(p.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return resp, err
+		case <-t.C:
+		}
+	}
+}
+
+// RetryClient implements Caller, retrying failed calls according to a RetryPolicy. Only calls
+// made through Do (or other callers of CallRequest) can be retried, as idempotency is determined
+// from the request.
+type RetryClient struct {
+	c      Caller
+	policy RetryPolicy
+}
+
+// NewRetryClient wraps c, retrying calls according to policy.
+func NewRetryClient(c Caller, policy RetryPolicy) *RetryClient {
+	return &RetryClient{c: c, policy: policy}
+}
+
+// Call implements Caller. Calls made without a request are not retried.
+func (r *RetryClient) Call(ctx context.Context, f *RPCFrame, getCreds GetCredsCallback) (*RPCFrame, error) {
+	return r.c.Call(ctx, f, getCreds)
+}
+
+// CallRequest implements RequestCaller.
+func (r *RetryClient) CallRequest(
+	ctx context.Context,
+	req RPCRequestBody,
+	f *RPCFrame,
+	getCreds GetCredsCallback,
+) (*RPCFrame, error) {
+	return r.policy.do(ctx, req, func() (*RPCFrame, error) {
+		return callRequest(ctx, r.c, req, f, getCreds)
+	})
+}
+
+// Subscribe implements Subscriber, returning the notifications received by the wrapped caller.
+func (r *RetryClient) Subscribe(ctx context.Context, method string) <-chan *RPCFrame {
+	return subscribe(ctx, r.c, method)
+}
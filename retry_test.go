@@ -0,0 +1,152 @@
+package shelly
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedRPC responds to successive calls with the listed statuses, then succeeds.
+type scriptedRPC struct {
+	statuses []ShellyErrorCode
+	calls    int
+}
+
+func (s *scriptedRPC) Call(context.Context, *RPCFrame, GetCredsCallback) (*RPCFrame, error) {
+	s.calls++
+	if s.calls <= len(s.statuses) {
+		return &RPCFrame{Error: &RPCFrameError{Code: int(s.statuses[s.calls-1]), Message: "failed"}}, nil
+	}
+	return &RPCFrame{Result: []byte(`{"was_on":true}`)}, nil
+}
+
+func TestRetryClient(t *testing.T) {
+	ctx := context.Background()
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	for _, tc := range []struct {
+		name      string
+		ctx       context.Context
+		req       RPCRequestBody
+		statuses  []ShellyErrorCode
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "retryable",
+			req:       &SwitchSetRequest{ID: 0, On: true},
+			statuses:  []ShellyErrorCode{ErrRPCDeadlineExceeded, ErrRPCResourcesExhausted},
+			wantCalls: 3,
+		},
+		{
+			name:      "exhausted",
+			req:       &SwitchSetRequest{ID: 0, On: true},
+			statuses:  []ShellyErrorCode{ErrRPCUnavailable, ErrRPCUnavailable, ErrRPCUnavailable},
+			wantErr:   ErrRPCUnavailable,
+			wantCalls: 3,
+		},
+		{
+			name:      "fatal",
+			req:       &SwitchSetRequest{ID: 0, On: true},
+			statuses:  []ShellyErrorCode{ErrRPCFailedPrecondition},
+			wantErr:   ErrRPCFailedPrecondition,
+			wantCalls: 1,
+		},
+		{
+			name:      "non-idempotent",
+			req:       &SwitchToggleRequest{ID: 0},
+			statuses:  []ShellyErrorCode{ErrRPCDeadlineExceeded},
+			wantErr:   ErrRPCDeadlineExceeded,
+			wantCalls: 1,
+		},
+		{
+			name:      "absolute move",
+			req:       &CoverGoToPositionRequest{ID: 0, Pos: Float64Ptr(50)},
+			statuses:  []ShellyErrorCode{ErrRPCUnavailable},
+			wantCalls: 2,
+		},
+		{
+			name:      "relative move",
+			req:       &CoverGoToPositionRequest{ID: 0, Rel: Float64Ptr(10)},
+			statuses:  []ShellyErrorCode{ErrRPCUnavailable},
+			wantErr:   ErrRPCUnavailable,
+			wantCalls: 1,
+		},
+		{
+			name:      "open",
+			req:       &CoverOpenRequest{ID: 0},
+			statuses:  []ShellyErrorCode{ErrRPCUnavailable},
+			wantCalls: 2,
+		},
+		{
+			name:      "timed open",
+			req:       &CoverOpenRequest{ID: 0, Duration: Float64Ptr(2)},
+			statuses:  []ShellyErrorCode{ErrRPCUnavailable},
+			wantErr:   ErrRPCUnavailable,
+			wantCalls: 1,
+		},
+		{
+			name:      "timed close",
+			req:       &CoverCloseRequest{ID: 0, Duration: Float64Ptr(2)},
+			statuses:  []ShellyErrorCode{ErrRPCUnavailable},
+			wantErr:   ErrRPCUnavailable,
+			wantCalls: 1,
+		},
+		{
+			name:      "delete by id",
+			req:       &WebhookDeleteRequest{ID: 1},
+			statuses:  []ShellyErrorCode{ErrRPCUnavailable},
+			wantErr:   ErrRPCUnavailable,
+			wantCalls: 1,
+		},
+		{
+			name:      "non-idempotent opt-in",
+			ctx:       WithRetryNonIdempotent(ctx),
+			req:       &SwitchToggleRequest{ID: 0},
+			statuses:  []ShellyErrorCode{ErrRPCDeadlineExceeded},
+			wantCalls: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &scriptedRPC{statuses: tc.statuses}
+			callCtx := tc.ctx
+			if callCtx == nil {
+				callCtx = ctx
+			}
+			_, err := Do(callCtx, NewRetryClient(s, policy), nil, tc.req, &SwitchActionResponse{})
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantCalls, s.calls)
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	assert.Equal(t, 100*time.Millisecond, p.Backoff(1))
+	assert.Equal(t, 400*time.Millisecond, p.Backoff(3))
+	assert.Equal(t, time.Second, p.Backoff(10))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(2)
+		assert.True(t, d >= 100*time.Millisecond && d <= 300*time.Millisecond, d)
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	assert.True(t, IsRetryable(fmt.Errorf("wrapped: %w", &BadStatusWithMessageError{Status: ErrRPCUnavailable})))
+	assert.True(t, IsRetryable(ErrConnectionLost))
+	assert.False(t, IsRetryable(ErrRPCInvalidOrMissingArguments))
+	assert.False(t, IsRetryable(nil))
+	assert.True(t, IsAuthError(&BadStatusWithMessageError{Status: ErrRPCUnauthorized}))
+	assert.False(t, IsAuthError(ErrRPCNoHandler))
+}
@@ -4,6 +4,20 @@ package shelly
 type RPCRequestBody interface {
 	Method() string
 	NewResponse() any
+
+	// Idempotent returns true if repeating the request has the same effect as making it once,
+	// and thus it's safe to retry (ex. Switch.Set, but not Switch.Toggle). Deleting by id or
+	// key (ex. Schedule.Delete) is not idempotent: a repeat fails once the item is gone, and
+	// may delete a new item which reused the id. Deleting all items (ex. Schedule.DeleteAll) is.
+	Idempotent() bool
+}
+
+// TypedRequest describes requests whose response decodes into a Resp.
+type TypedRequest[Resp any] interface {
+	RPCRequestBody
+
+	// NewTypedResponse returns an empty response to decode the result into.
+	NewTypedResponse() *Resp
 }
 
 type RPCEmptyResponse struct{}
@@ -3,9 +3,6 @@ package shelly
 import (
 	"context"
 	"encoding/json"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 // Schedule describes a series of RPCs to be repeated on a schedule.
@@ -56,6 +53,10 @@ func (r *ScheduleCreateRequest) Method() string {
 	return "Schedule.Create"
 }
 
+func (r *ScheduleCreateRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ScheduleCreateRequest) NewTypedResponse() *ScheduleCreateResponse {
 	return &ScheduleCreateResponse{}
 }
@@ -66,16 +67,31 @@ func (r *ScheduleCreateRequest) NewResponse() any {
 
 func (r *ScheduleCreateRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScheduleCreateResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *ScheduleCreateRequest) Validate() error {
+	v := newValidation(r)
+	if r.ID != nil {
+		v.addf("id", "must not be set when creating a schedule")
+	}
+	if r.TimeSpec == nil {
+		v.addf("timespec", "is required")
+	} else {
+		v.timeSpec("timespec", *r.TimeSpec)
+	}
+	if len(r.Calls) == 0 {
+		v.addf("calls", "must have at least 1 call")
+	}
+	v.scheduleCalls("calls", r.Calls)
+	return v.err()
 }
 
 // ScheduleCreateResponse is the RPC response to the ScheduleCreateRequest.
@@ -101,6 +117,10 @@ func (r *ScheduleUpdateRequest) Method() string {
 	return "Schedule.Update"
 }
 
+func (r *ScheduleUpdateRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScheduleUpdateRequest) NewTypedResponse() *ScheduleUpdateResponse {
 	return &ScheduleUpdateResponse{}
 }
@@ -111,16 +131,66 @@ func (r *ScheduleUpdateRequest) NewResponse() any {
 
 func (r *ScheduleUpdateRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScheduleUpdateResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *ScheduleUpdateRequest) Validate() error {
+	v := newValidation(r)
+	if r.ID == nil {
+		v.addf("id", "is required")
+	}
+	if r.TimeSpec != nil {
+		v.timeSpec("timespec", *r.TimeSpec)
+	}
+	v.scheduleCalls("calls", r.Calls)
+	return v.err()
+}
+
+// ScheduleListRequest lists the existing schedules.
+type ScheduleListRequest struct{}
+
+func (r *ScheduleListRequest) Method() string {
+	return "Schedule.List"
+}
+
+func (r *ScheduleListRequest) Idempotent() bool {
+	return true
+}
+
+func (r *ScheduleListRequest) NewTypedResponse() *ScheduleListResponse {
+	return &ScheduleListResponse{}
+}
+
+func (r *ScheduleListRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *ScheduleListRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*ScheduleListResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+// ScheduleListResponse is the RPC response to the ScheduleListRequest.
+type ScheduleListResponse struct {
+	// Jobs are the existing schedules.
+	Jobs []Schedule `json:"jobs"`
+
+	// Rev is the current revision number of the schedule instances.
+	Rev *int `json:"rev,omitempty"`
 }
 
 // ScheduleDeleteRequest deletes an existing schedule.
@@ -133,6 +203,10 @@ func (r *ScheduleDeleteRequest) Method() string {
 	return "Schedule.Delete"
 }
 
+func (r *ScheduleDeleteRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ScheduleDeleteRequest) NewTypedResponse() *ScheduleUpdateResponse {
 	return &ScheduleUpdateResponse{}
 }
@@ -143,16 +217,14 @@ func (r *ScheduleDeleteRequest) NewResponse() any {
 
 func (r *ScheduleDeleteRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScheduleUpdateResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // ScheduleDeleteAllRequest deletes all existing schedules.
@@ -162,6 +234,10 @@ func (r *ScheduleDeleteAllRequest) Method() string {
 	return "Schedule.DeleteAll"
 }
 
+func (r *ScheduleDeleteAllRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScheduleDeleteAllRequest) NewTypedResponse() *ScheduleUpdateResponse {
 	return &ScheduleUpdateResponse{}
 }
@@ -172,14 +248,12 @@ func (r *ScheduleDeleteAllRequest) NewResponse() any {
 
 func (r *ScheduleDeleteAllRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScheduleUpdateResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
@@ -5,9 +5,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type ScriptConfig struct {
@@ -44,18 +41,20 @@ func (r *ScriptGetConfigRequest) Method() string {
 	return "Script.GetConfig"
 }
 
+func (r *ScriptGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScriptGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptGetConfigRequest) NewTypedResponse() *ScriptConfig {
@@ -78,18 +77,20 @@ func (r *ScriptSetConfigRequest) Method() string {
 	return "Script.SetConfig"
 }
 
+func (r *ScriptSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScriptSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptSetConfigRequest) NewTypedResponse() *SetConfigResponse {
@@ -109,18 +110,20 @@ func (r *ScriptGetStatusRequest) Method() string {
 	return "Script.GetStatus"
 }
 
+func (r *ScriptGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScriptGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptGetStatusRequest) NewTypedResponse() *ScriptStatus {
@@ -144,18 +147,20 @@ func (r *ScriptCreateRequest) Method() string {
 	return "Script.Create"
 }
 
+func (r *ScriptCreateRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ScriptCreateRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptCreateResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptCreateRequest) NewTypedResponse() *ScriptCreateResponse {
@@ -185,18 +190,20 @@ func (r *ScriptPutCodeRequest) Method() string {
 	return "Script.PutCode"
 }
 
+func (r *ScriptPutCodeRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ScriptPutCodeRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptPutCodeResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptPutCodeRequest) NewTypedResponse() *ScriptPutCodeResponse {
@@ -211,8 +218,8 @@ func (r *ScriptPutCodeRequest) NewResponse() any {
 // Script.PutCode method, line-by-line to accomodate limits on payload size.
 func ScriptPutCode(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 	data io.Reader,
 ) error {
 	s := bufio.NewScanner(data)
@@ -230,6 +237,54 @@ func ScriptPutCode(
 	return nil
 }
 
+type ScriptGetCodeResponse struct {
+	// Data is the requested part of the code.
+	Data string `json:"data"`
+
+	// Left is the number of bytes of code after Data.
+	Left int `json:"left"`
+}
+
+type ScriptGetCodeRequest struct {
+	// ID of the script component instance.
+	ID int `json:"id"`
+
+	// Offset is the byte offset to read from, default 0.
+	Offset int `json:"offset,omitempty"`
+
+	// Len is the number of bytes to read, default (nil) is the rest of the code, up to a
+	// device-specific limit.
+	Len *int `json:"len,omitempty"`
+}
+
+func (r *ScriptGetCodeRequest) Method() string {
+	return "Script.GetCode"
+}
+
+func (r *ScriptGetCodeRequest) Idempotent() bool {
+	return true
+}
+
+func (r *ScriptGetCodeRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*ScriptGetCodeResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *ScriptGetCodeRequest) NewTypedResponse() *ScriptGetCodeResponse {
+	return &ScriptGetCodeResponse{}
+}
+
+func (r *ScriptGetCodeRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
 type ScriptEvalResponse struct {
 	Result string `json:"result"`
 }
@@ -247,18 +302,20 @@ func (r *ScriptEvalRequest) Method() string {
 	return "Script.Eval"
 }
 
+func (r *ScriptEvalRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ScriptEvalRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptEvalResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptEvalRequest) NewTypedResponse() *ScriptEvalResponse {
@@ -282,18 +339,20 @@ func (r *ScriptStartRequest) Method() string {
 	return "Script.Start"
 }
 
+func (r *ScriptStartRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScriptStartRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptStartResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptStartRequest) NewTypedResponse() *ScriptStartResponse {
@@ -317,18 +376,20 @@ func (r *ScriptStopRequest) Method() string {
 	return "Script.Stop"
 }
 
+func (r *ScriptStopRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScriptStopRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptStopResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptStopRequest) NewTypedResponse() *ScriptStopResponse {
@@ -364,18 +425,20 @@ func (r *ScriptListRequest) Method() string {
 	return "Script.List"
 }
 
+func (r *ScriptListRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ScriptListRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ScriptListResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptListRequest) NewTypedResponse() *ScriptListResponse {
@@ -395,18 +458,20 @@ func (r *ScriptDeleteRequest) Method() string {
 	return "Script.Delete"
 }
 
+func (r *ScriptDeleteRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ScriptDeleteRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ScriptDeleteRequest) NewTypedResponse() *RPCEmptyResponse {
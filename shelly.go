@@ -7,9 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 const (
@@ -23,6 +20,10 @@ func (r *ShellyGetStatusRequest) Method() string {
 	return "Shelly.GetStatus"
 }
 
+func (r *ShellyGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyGetStatusRequest) NewTypedResponse() *ShellyGetStatusResponse {
 	return &ShellyGetStatusResponse{}
 }
@@ -33,16 +34,14 @@ func (r *ShellyGetStatusRequest) NewResponse() any {
 
 func (r *ShellyGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyGetStatusResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyGetStatusResponse struct {
@@ -230,6 +229,10 @@ func (r *ShellyGetDeviceInfoRequest) Method() string {
 	return "Shelly.GetDeviceInfo"
 }
 
+func (r *ShellyGetDeviceInfoRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyGetDeviceInfoRequest) NewTypedResponse() *ShellyGetDeviceInfoResponse {
 	return &ShellyGetDeviceInfoResponse{}
 }
@@ -240,16 +243,14 @@ func (r *ShellyGetDeviceInfoRequest) NewResponse() any {
 
 func (r *ShellyGetDeviceInfoRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyGetDeviceInfoResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyGetDeviceInfoResponse struct {
@@ -303,6 +304,10 @@ func (r *ShellyCheckForUpdateRequest) Method() string {
 	return "Shelly.CheckForUpdate"
 }
 
+func (r *ShellyCheckForUpdateRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyCheckForUpdateRequest) NewTypedResponse() *ShellyCheckForUpdateResponse {
 	return &ShellyCheckForUpdateResponse{}
 }
@@ -313,16 +318,14 @@ func (r *ShellyCheckForUpdateRequest) NewResponse() any {
 
 func (r *ShellyCheckForUpdateRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyCheckForUpdateResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyCheckForUpdateResponse struct {
@@ -354,6 +357,10 @@ func (r *ShellyUpdateRequest) Method() string {
 	return "Shelly.Update"
 }
 
+func (r *ShellyUpdateRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ShellyUpdateRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -362,12 +369,28 @@ func (r *ShellyUpdateRequest) NewResponse() any {
 	return r.NewTypedResponse()
 }
 
+func (r *ShellyUpdateRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*RPCEmptyResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
 type ShellyFactoryResetRequest struct{}
 
 func (r *ShellyFactoryResetRequest) Method() string {
 	return "Shelly.FactoryReset"
 }
 
+func (r *ShellyFactoryResetRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ShellyFactoryResetRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -378,16 +401,14 @@ func (r *ShellyFactoryResetRequest) NewResponse() any {
 
 func (r *ShellyFactoryResetRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyResetWiFiConfigRequest struct{}
@@ -396,6 +417,10 @@ func (r *ShellyResetWiFiConfigRequest) Method() string {
 	return "Shelly.ResetWiFiConfig"
 }
 
+func (r *ShellyResetWiFiConfigRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ShellyResetWiFiConfigRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -406,16 +431,14 @@ func (r *ShellyResetWiFiConfigRequest) NewResponse() any {
 
 func (r *ShellyResetWiFiConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyRebootRequest struct {
@@ -428,6 +451,10 @@ func (r *ShellyRebootRequest) Method() string {
 	return "Shelly.Reboot"
 }
 
+func (r *ShellyRebootRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ShellyRebootRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -438,16 +465,14 @@ func (r *ShellyRebootRequest) NewResponse() any {
 
 func (r *ShellyRebootRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellySetAuthRequest struct {
@@ -465,6 +490,10 @@ func (r *ShellySetAuthRequest) Method() string {
 	return "Shelly.SetAuth"
 }
 
+func (r *ShellySetAuthRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellySetAuthRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -475,16 +504,14 @@ func (r *ShellySetAuthRequest) NewResponse() any {
 
 func (r *ShellySetAuthRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func NewShellySetAuthRequest(deviceID, password string) *ShellySetAuthRequest {
@@ -504,7 +531,7 @@ func NewShellySetAuthRequest(deviceID, password string) *ShellySetAuthRequest {
 // BuildShellyAuthRequest builds the request, fetching the deviceID for realm.
 func BuildShellyAuthRequest(
 	ctx context.Context,
-	c mgrpc.MgRPC,
+	c Caller,
 	password string,
 ) (*ShellySetAuthRequest, error) {
 	resp, _, err := (&ShellyGetDeviceInfoRequest{}).Do(ctx, c, nil)
@@ -527,6 +554,10 @@ func (r *ShellyPutUserCARequest) Method() string {
 	return "Shelly.PutUserCA"
 }
 
+func (r *ShellyPutUserCARequest) Idempotent() bool {
+	return false
+}
+
 func (r *ShellyPutUserCARequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -537,24 +568,22 @@ func (r *ShellyPutUserCARequest) NewResponse() any {
 
 func (r *ShellyPutUserCARequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // ShellyPutUserCA is a helper method which uploads the provided data to the Shelly.PutUserCA method,
 // line-by-line to accomodate limits on payload size.
 func ShellyPutUserCA(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 	data io.Reader,
 ) error {
 	s := bufio.NewScanner(data)
@@ -584,6 +613,10 @@ func (r *ShellyPutTLSClientCertRequest) Method() string {
 	return "Shelly.PutTLSClientCert"
 }
 
+func (r *ShellyPutTLSClientCertRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ShellyPutTLSClientCertRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -594,24 +627,22 @@ func (r *ShellyPutTLSClientCertRequest) NewResponse() any {
 
 func (r *ShellyPutTLSClientCertRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // ShellyPutTLSClientCert is a helper method which uploads the provided data to the
 // Shelly.PutTLSClientCert method, line-by-line to accomodate limits on payload size.
 func ShellyPutTLSClientCert(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 	data io.Reader,
 ) error {
 	s := bufio.NewScanner(data)
@@ -641,6 +672,10 @@ func (r *ShellyPutTLSClientKeyRequest) Method() string {
 	return "Shelly.PutTLSClientKey"
 }
 
+func (r *ShellyPutTLSClientKeyRequest) Idempotent() bool {
+	return false
+}
+
 func (r *ShellyPutTLSClientKeyRequest) NewTypedResponse() *RPCEmptyResponse {
 	return &RPCEmptyResponse{}
 }
@@ -651,24 +686,22 @@ func (r *ShellyPutTLSClientKeyRequest) NewResponse() any {
 
 func (r *ShellyPutTLSClientKeyRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*RPCEmptyResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // ShellyPutTLSClientKey is a helper method which uploads the provided data to the
 // Shelly.PutTLSClientKey method, line-by-line to accomodate limits on payload size.
 func ShellyPutTLSClientKey(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 	data io.Reader,
 ) error {
 	s := bufio.NewScanner(data)
@@ -857,6 +890,10 @@ func (r *ShellyGetConfigRequest) Method() string {
 	return "Shelly.GetConfig"
 }
 
+func (r *ShellyGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyGetConfigRequest) NewTypedResponse() *ShellyGetConfigResponse {
 	return &ShellyGetConfigResponse{}
 }
@@ -867,16 +904,14 @@ func (r *ShellyGetConfigRequest) NewResponse() any {
 
 func (r *ShellyGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyGetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyListMethodsResponse struct {
@@ -890,6 +925,10 @@ func (r *ShellyListMethodsRequest) Method() string {
 	return "Shelly.ListMethods"
 }
 
+func (r *ShellyListMethodsRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyListMethodsRequest) NewTypedResponse() *ShellyListMethodsResponse {
 	return &ShellyListMethodsResponse{}
 }
@@ -900,16 +939,14 @@ func (r *ShellyListMethodsRequest) NewResponse() any {
 
 func (r *ShellyListMethodsRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyListMethodsResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyListProfilesComponent struct {
@@ -930,6 +967,10 @@ func (r *ShellyListProfilesRequest) Method() string {
 	return "Shelly.ListProfiles"
 }
 
+func (r *ShellyListProfilesRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyListProfilesRequest) NewTypedResponse() *ShellyListProfilesResponse {
 	return &ShellyListProfilesResponse{}
 }
@@ -940,16 +981,14 @@ func (r *ShellyListProfilesRequest) NewResponse() any {
 
 func (r *ShellyListProfilesRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyListProfilesResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellySetProfileResponse struct {
@@ -967,6 +1006,10 @@ func (r *ShellySetProfileRequest) Method() string {
 	return "Shelly.SetProfile"
 }
 
+func (r *ShellySetProfileRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellySetProfileRequest) NewTypedResponse() *ShellySetProfileResponse {
 	return &ShellySetProfileResponse{}
 }
@@ -977,16 +1020,14 @@ func (r *ShellySetProfileRequest) NewResponse() any {
 
 func (r *ShellySetProfileRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellySetProfileResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyListTimezonesResponse struct {
@@ -1000,6 +1041,10 @@ func (r *ShellyListTimezonesRequest) Method() string {
 	return "Shelly.ListTimezones"
 }
 
+func (r *ShellyListTimezonesRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyListTimezonesRequest) NewTypedResponse() *ShellyListTimezonesResponse {
 	return &ShellyListTimezonesResponse{}
 }
@@ -1010,16 +1055,14 @@ func (r *ShellyListTimezonesRequest) NewResponse() any {
 
 func (r *ShellyListTimezonesRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyListTimezonesResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyDetectLocationResponse struct {
@@ -1039,6 +1082,10 @@ func (r *ShellyDetectLocationRequest) Method() string {
 	return "Shelly.DetectLocation"
 }
 
+func (r *ShellyDetectLocationRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyDetectLocationRequest) NewTypedResponse() *ShellyDetectLocationResponse {
 	return &ShellyDetectLocationResponse{}
 }
@@ -1049,16 +1096,14 @@ func (r *ShellyDetectLocationRequest) NewResponse() any {
 
 func (r *ShellyDetectLocationRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyDetectLocationResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type ShellyComponent struct {
@@ -1105,6 +1150,10 @@ func (r *ShellyGetComponentsRequest) Method() string {
 	return "Shelly.GetComponents"
 }
 
+func (r *ShellyGetComponentsRequest) Idempotent() bool {
+	return true
+}
+
 func (r *ShellyGetComponentsRequest) NewTypedResponse() *ShellyGetComponentsResponse {
 	return &ShellyGetComponentsResponse{}
 }
@@ -1115,38 +1164,46 @@ func (r *ShellyGetComponentsRequest) NewResponse() any {
 
 func (r *ShellyGetComponentsRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyGetComponentsResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *ShellyGetComponentsRequest) DoAll(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*ShellyGetComponentsResponse,
 	error,
 ) {
-	total := 1
 	composed := r.NewTypedResponse()
-	for have := 0; have < total; {
+	page := *r
+	start := 0
+	if r.Offset != nil {
+		start = *r.Offset
+	}
+	total := start + 1
+	for have := start; have < total; {
+		page.Offset = IntPtr(have)
 		resp := r.NewTypedResponse()
-		_, err := Do(ctx, c, credsCallback, r, resp)
+		_, err := Do(ctx, c, credsCallback, &page, resp)
 		if err != nil {
 			return nil, err
 		}
+		if len(resp.Components) == 0 {
+			break
+		}
 		composed.Components = append(composed.Components, resp.Components...)
 		total = resp.Total
 		have += len(resp.Components)
 		composed.CfgRev = resp.CfgRev
 	}
+	composed.Offset, composed.Total = start, total
 	return composed, nil
 }
@@ -0,0 +1,289 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StateChange describes a single field which changed in a DeviceState.
+type StateChange struct {
+	// Component is the component key, ex. switch:0.
+	Component string
+
+	// Path is the dotted path of the field within the device status, ex. switch:0.aenergy.total.
+	Path string
+
+	// Old is the previous value, or nil if the field was added.
+	Old any
+
+	// New is the current value, or nil if the field was removed.
+	New any
+}
+
+// DeviceState mirrors the status of a device. It's seeded from Shelly.GetStatus and then kept
+// current by applying NotifyStatus deltas, which only contain the fields which changed. The
+// mirror is resynchronized when a NotifyFullStatus is received or a gap in the configuration
+// revision shows changes were missed. DeviceState is safe for concurrent use.
+type DeviceState struct {
+	c             Caller
+	credsCallback GetCredsCallback
+
+	mu     sync.RWMutex
+	tree   map[string]any
+	cfgRev int
+	subs   map[chan StateChange]struct{}
+}
+
+// NewDeviceState builds a DeviceState which resynchronizes using the provided channel, and
+// receives notifications from it if it's a Subscriber (ex. a WebSocket Client).
+func NewDeviceState(c Caller, credsCallback GetCredsCallback) *DeviceState {
+	return &DeviceState{
+		c:             c,
+		credsCallback: credsCallback,
+		tree:          make(map[string]any),
+		subs:          make(map[chan StateChange]struct{}),
+	}
+}
+
+// Run seeds the state and applies notifications received by the channel until ctx is done or
+// a resynchronization fails. Channels which don't receive notifications are only synced once.
+func (s *DeviceState) Run(ctx context.Context) error {
+	frames := subscribe(ctx, s.c, "")
+	if err := s.Sync(ctx); err != nil {
+		return err
+	}
+	for f := range frames {
+		if err := s.Apply(ctx, f); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// Sync replaces the state with the result of Shelly.GetStatus.
+func (s *DeviceState) Sync(ctx context.Context) error {
+	var raw json.RawMessage
+	if _, err := Do(ctx, s.c, s.credsCallback, &ShellyGetStatusRequest{}, &raw); err != nil {
+		return err
+	}
+	return s.replace(raw)
+}
+
+// Apply updates the state from a notification frame. NotifyStatus deltas are merged,
+// NotifyFullStatus replaces the state, and a configuration revision gap triggers a Sync. Other
+// frames are ignored.
+func (s *DeviceState) Apply(ctx context.Context, f *RPCFrame) error {
+	var cfgRev int
+	switch f.Method {
+	case (&NotifyStatus{}).Method():
+		var delta map[string]any
+		if err := json.Unmarshal(f.Params, &delta); err != nil {
+			return fmt.Errorf("decoding status notification: %w", err)
+		}
+		delete(delta, "ts")
+		s.mu.Lock()
+		changes := mergeState(s.tree, delta, "")
+		s.mu.Unlock()
+		s.emit(changes)
+		cfgRev = treeCfgRev(delta)
+	case NotifyFullStatusMethod:
+		return s.replace(f.Params)
+	case (&NotifyEvent{}).Method():
+		var ne NotifyEvent
+		if err := json.Unmarshal(f.Params, &ne); err != nil {
+			return fmt.Errorf("decoding event notification: %w", err)
+		}
+		for _, e := range ne.Events {
+			if cc, ok := e.Data.(*SysConfigChangedEvent); ok && cc.CfgRev > cfgRev {
+				cfgRev = cc.CfgRev
+			}
+		}
+	default:
+		return nil
+	}
+	if cfgRev == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	gap := s.cfgRev != 0 && cfgRev > s.cfgRev+1
+	if cfgRev > s.cfgRev {
+		s.cfgRev = cfgRev
+	}
+	s.mu.Unlock()
+	if gap {
+		return s.Sync(ctx)
+	}
+	return nil
+}
+
+func (s *DeviceState) replace(raw json.RawMessage) error {
+	var tree map[string]any
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return fmt.Errorf("decoding device status: %w", err)
+	}
+	delete(tree, "ts")
+	s.mu.Lock()
+	var changes []StateChange
+	diffState(s.tree, tree, "", &changes)
+	s.tree = tree
+	s.cfgRev = treeCfgRev(tree)
+	s.mu.Unlock()
+	s.emit(changes)
+	return nil
+}
+
+// Changes returns a channel receiving each change to the state. The channel is closed once ctx
+// is done. Changes are dropped if the receiver falls behind.
+func (s *DeviceState) Changes(ctx context.Context) <-chan StateChange {
+	ch := make(chan StateChange, subscriptionBuffer)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		delete(s.subs, ch)
+		close(ch)
+		s.mu.Unlock()
+	}()
+	return ch
+}
+
+func (s *DeviceState) emit(changes []StateChange) {
+	if len(changes) == 0 {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for ch := range s.subs {
+		for _, c := range changes {
+			select {
+			case ch <- c:
+			default:
+			}
+		}
+	}
+}
+
+// CfgRev returns the most recent configuration revision seen.
+func (s *DeviceState) CfgRev() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfgRev
+}
+
+// Raw returns a JSON snapshot of the status in the Shelly.GetStatus format.
+func (s *DeviceState) Raw() (json.RawMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return json.Marshal(s.tree)
+}
+
+// Status returns a typed snapshot of the status.
+func (s *DeviceState) Status() (*ShellyGetStatusResponse, error) {
+	raw, err := s.Raw()
+	if err != nil {
+		return nil, err
+	}
+	var status ShellyGetStatusResponse
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Component decodes a snapshot of a single component's status (ex. switch:0) into v (ex.
+// *SwitchStatus). It returns false if the device has no such component.
+func (s *DeviceState) Component(key string, v any) (bool, error) {
+	s.mu.RLock()
+	c, ok := s.tree[key]
+	var raw []byte
+	var err error
+	if ok {
+		raw, err = json.Marshal(c)
+	}
+	s.mu.RUnlock()
+	if !ok || err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// mergeState deep-merges delta into tree and returns the fields which changed.
+func mergeState(tree, delta map[string]any, prefix string) []StateChange {
+	var changes []StateChange
+	for _, k := range sortedKeys(delta) {
+		path := joinPath(prefix, k)
+		nv := delta[k]
+		if nm, ok := nv.(map[string]any); ok {
+			if om, ok := tree[k].(map[string]any); ok {
+				changes = append(changes, mergeState(om, nm, path)...)
+				continue
+			}
+		}
+		old, existed := tree[k]
+		if existed && reflect.DeepEqual(old, nv) {
+			continue
+		}
+		tree[k] = nv
+		diffValue(old, nv, path, &changes)
+	}
+	return changes
+}
+
+// diffState records the changes required to turn old into new.
+func diffState(old, new map[string]any, prefix string, changes *[]StateChange) {
+	union := make(map[string]any, len(new))
+	for k := range old {
+		union[k] = nil
+	}
+	for k := range new {
+		union[k] = nil
+	}
+	for _, k := range sortedKeys(union) {
+		diffValue(old[k], new[k], joinPath(prefix, k), changes)
+	}
+}
+
+func diffValue(old, new any, path string, changes *[]StateChange) {
+	om, oldIsMap := old.(map[string]any)
+	nm, newIsMap := new.(map[string]any)
+	switch {
+	case oldIsMap && newIsMap:
+		diffState(om, nm, path, changes)
+	case newIsMap:
+		diffState(nil, nm, path, changes)
+	case oldIsMap:
+		diffState(om, nil, path, changes)
+	case !reflect.DeepEqual(old, new):
+		component, _, _ := strings.Cut(path, ".")
+		*changes = append(*changes, StateChange{Component: component, Path: path, Old: old, New: new})
+	}
+}
+
+func treeCfgRev(tree map[string]any) int {
+	sys, _ := tree["sys"].(map[string]any)
+	rev, _ := sys["cfg_rev"].(float64)
+	return int(rev)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(prefix, k string) string {
+	if prefix == "" {
+		return k
+	}
+	return prefix + "." + k
+}
@@ -0,0 +1,108 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusTransport answers every call with the current value of status.
+type statusTransport struct {
+	status string
+	calls  int
+}
+
+func (t *statusTransport) RoundTrip(ctx context.Context, req *RPCFrame) (*RPCFrame, error) {
+	t.calls++
+	return &RPCFrame{ID: req.ID, Result: json.RawMessage(t.status)}, nil
+}
+
+func (t *statusTransport) Close() error {
+	return nil
+}
+
+func TestDeviceState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tr := &statusTransport{
+		status: `{"sys":{"cfg_rev":10},"switch:0":{"id":0,"output":false,"apower":0,"aenergy":{"total":100.5}}}`,
+	}
+	s := NewDeviceState(NewClient(tr), nil)
+	changes := s.Changes(ctx)
+	require.NoError(t, s.Sync(ctx))
+	assert.Equal(t, 10, s.CfgRev())
+	for i := 0; i < 5; i++ {
+		<-changes // Seeding reports every field as added.
+	}
+
+	require.NoError(t, s.Apply(ctx, &RPCFrame{
+		Method: "NotifyStatus",
+		Params: json.RawMessage(`{"ts":1.5,"switch:0":{"id":0,"output":true,"aenergy":{"total":101}}}`),
+	}))
+	assert.Equal(t, StateChange{Component: "switch:0", Path: "switch:0.aenergy.total", Old: 100.5, New: 101.0}, <-changes)
+	assert.Equal(t, StateChange{Component: "switch:0", Path: "switch:0.output", Old: false, New: true}, <-changes)
+
+	// Fields absent from the delta are retained.
+	var sw SwitchStatus
+	ok, err := s.Component("switch:0", &sw)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, *sw.Output)
+	assert.Equal(t, 0.0, *sw.APower)
+	status, err := s.Status()
+	require.NoError(t, err)
+	require.Len(t, status.Switches, 1)
+	assert.Equal(t, 101.0, status.Switches[0].AEnergy.Total)
+
+	// The next revision is applied directly, but a gap triggers a resync.
+	require.NoError(t, s.Apply(ctx, &RPCFrame{
+		Method: "NotifyStatus",
+		Params: json.RawMessage(`{"ts":2.5,"sys":{"cfg_rev":11}}`),
+	}))
+	<-changes
+	assert.Equal(t, 1, tr.calls)
+	tr.status = `{"sys":{"cfg_rev":13},"switch:0":{"id":0,"output":true,"apower":0,"aenergy":{"total":101}}}`
+	require.NoError(t, s.Apply(ctx, &RPCFrame{
+		Method: "NotifyEvent",
+		Params: json.RawMessage(`{"ts":3.5,"events":[{"component":"sys","event":"config_changed","cfg_rev":13,"ts":3.5}]}`),
+	}))
+	assert.Equal(t, 2, tr.calls)
+	assert.Equal(t, StateChange{Component: "sys", Path: "sys.cfg_rev", Old: 11.0, New: 13.0}, <-changes)
+
+	// NotifyFullStatus replaces the state, reporting removed fields.
+	require.NoError(t, s.Apply(ctx, &RPCFrame{
+		Method: "NotifyFullStatus",
+		Params: json.RawMessage(`{"ts":4.5,"sys":{"cfg_rev":13}}`),
+	}))
+	ok, err = s.Component("switch:0", &sw)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, StateChange{Component: "switch:0", Path: "switch:0.aenergy.total", Old: 101.0}, <-changes)
+}
+
+func TestDeviceStateRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := &statusTransport{status: `{"sys":{"cfg_rev":10},"switch:0":{"id":0,"output":false}}`}
+	c := NewClient(tr)
+	s := NewDeviceState(c, nil)
+	changes := s.Changes(ctx)
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	for i := 0; i < 3; i++ {
+		<-changes
+	}
+
+	c.handleNotification(&RPCFrame{
+		Method: "NotifyStatus",
+		Params: json.RawMessage(`{"ts":1.5,"switch:0":{"id":0,"output":true}}`),
+	})
+	assert.Equal(t, StateChange{Component: "switch:0", Path: "switch:0.output", Old: false, New: true}, <-changes)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, 1, tr.calls)
+}
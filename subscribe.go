@@ -0,0 +1,153 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+const (
+	// NotifyFullStatusMethod is the notification sent with the complete device status, ex. after
+	// a component is added or the device reconnects.
+	NotifyFullStatusMethod = "NotifyFullStatus"
+
+	// subscriptionBuffer is the number of notifications queued for each subscriber before
+	// further notifications are dropped.
+	subscriptionBuffer = 32
+)
+
+// Notification is a decoded notification frame. Raw retains the original params so fields
+// which are not modeled by Value remain accessible.
+type Notification[T any] struct {
+	// Src is the device which sent the notification.
+	Src string
+
+	// Method is the notification method, ex. NotifyStatus.
+	Method string
+
+	// Value is the decoded params.
+	Value T
+
+	// Raw is the undecoded params.
+	Raw json.RawMessage
+}
+
+// Subscriber is implemented by callers which receive notifications from the device, ex. a
+// Client with a WebSocket transport.
+type Subscriber interface {
+	// Subscribe returns a channel receiving notification frames with the given method, or
+	// every notification if method is empty. The channel is closed once ctx is done.
+	Subscribe(ctx context.Context, method string) <-chan *RPCFrame
+}
+
+// NotificationHub fans notification frames out to subscribers. Callers which receive
+// notifications embed it to implement Subscriber. The zero value is ready to use.
+type NotificationHub struct {
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+type subscription struct {
+	method string
+	ch     chan *RPCFrame
+}
+
+// Subscribe implements Subscriber. Notifications are dropped if the receiver falls more than a
+// few dozen frames behind.
+func (h *NotificationHub) Subscribe(ctx context.Context, method string) <-chan *RPCFrame {
+	s := &subscription{method: method, ch: make(chan *RPCFrame, subscriptionBuffer)}
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = make(map[*subscription]struct{})
+	}
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs, s)
+		close(s.ch)
+		h.mu.Unlock()
+	}()
+	return s.ch
+}
+
+// Publish delivers f to matching subscribers. Notifications are dropped for subscribers whose
+// buffer is full rather than stalling the transport.
+func (h *NotificationHub) Publish(f *RPCFrame) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		if s.method != "" && s.method != f.Method {
+			continue
+		}
+		select {
+		case s.ch <- f:
+		default:
+		}
+	}
+}
+
+// subscribe subscribes to notifications received by c. If c isn't a Subscriber the channel
+// receives nothing and is closed once ctx is done.
+func subscribe(ctx context.Context, c Caller, method string) <-chan *RPCFrame {
+	if s, ok := c.(Subscriber); ok {
+		return s.Subscribe(ctx, method)
+	}
+	ch := make(chan *RPCFrame)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func subscribeTyped[T any](ctx context.Context, s Subscriber, method string) <-chan *Notification[T] {
+	in := s.Subscribe(ctx, method)
+	out := make(chan *Notification[T], subscriptionBuffer)
+	go func() {
+		defer close(out)
+		for f := range in {
+			n := &Notification[T]{Src: f.Src, Method: f.Method, Raw: f.Params}
+			if err := json.Unmarshal(f.Params, &n.Value); err != nil {
+				continue
+			}
+			select {
+			case out <- n:
+			default:
+			}
+		}
+	}()
+	return out
+}
+
+// Subscribe returns a channel receiving notification frames with the given method, or every
+// notification if method is empty. The channel is closed once ctx is done. Notifications are
+// dropped if the receiver falls more than a few dozen frames behind.
+func (c *Client) Subscribe(ctx context.Context, method string) <-chan *RPCFrame {
+	return c.notifications.Subscribe(ctx, method)
+}
+
+// SubscribeStatus returns a channel receiving NotifyStatus deltas. Each notification contains
+// only the components and fields which changed.
+func (c *Client) SubscribeStatus(ctx context.Context) <-chan *Notification[NotifyStatus] {
+	return subscribeTyped[NotifyStatus](ctx, &c.notifications, (&NotifyStatus{}).Method())
+}
+
+// SubscribeFullStatus returns a channel receiving NotifyFullStatus notifications, which contain
+// the complete status of every component.
+func (c *Client) SubscribeFullStatus(ctx context.Context) <-chan *Notification[NotifyStatus] {
+	return subscribeTyped[NotifyStatus](ctx, &c.notifications, NotifyFullStatusMethod)
+}
+
+// SubscribeEvent returns a channel receiving NotifyEvent notifications (ex. button pushes).
+func (c *Client) SubscribeEvent(ctx context.Context) <-chan *Notification[NotifyEvent] {
+	return subscribeTyped[NotifyEvent](ctx, &c.notifications, (&NotifyEvent{}).Method())
+}
+
+// Announce makes a lightweight call so the device learns the client's `src`. Devices only send
+// notifications over a connection once a request has been received on it.
+func (c *Client) Announce(ctx context.Context) error {
+	_, _, err := (&ShellyGetDeviceInfoRequest{}).Do(ctx, c, nil)
+	return err
+}
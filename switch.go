@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type SwitchGetConfigRequest struct {
@@ -16,18 +13,20 @@ func (r *SwitchGetConfigRequest) Method() string {
 	return "Switch.GetConfig"
 }
 
+func (r *SwitchGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *SwitchGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SwitchConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SwitchGetConfigRequest) NewTypedResponse() *SwitchConfig {
@@ -50,18 +49,20 @@ func (r *SwitchSetConfigRequest) Method() string {
 	return "Switch.SetConfig"
 }
 
+func (r *SwitchSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *SwitchSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SwitchSetConfigRequest) NewTypedResponse() *SetConfigResponse {
@@ -81,18 +82,20 @@ func (r *SwitchGetStatusRequest) Method() string {
 	return "Switch.GetStatus"
 }
 
+func (r *SwitchGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *SwitchGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SwitchStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SwitchGetStatusRequest) NewTypedResponse() *SwitchStatus {
@@ -118,18 +121,20 @@ func (r *SwitchSetRequest) Method() string {
 	return "Switch.Set"
 }
 
+func (r *SwitchSetRequest) Idempotent() bool {
+	return true
+}
+
 func (r *SwitchSetRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SwitchActionResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := &SwitchActionResponse{}
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SwitchSetRequest) NewTypedResponse() *SwitchActionResponse {
@@ -149,18 +154,20 @@ func (r *SwitchToggleRequest) Method() string {
 	return "Switch.Toggle"
 }
 
+func (r *SwitchToggleRequest) Idempotent() bool {
+	return false
+}
+
 func (r *SwitchToggleRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SwitchActionResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := &SwitchActionResponse{}
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SwitchToggleRequest) NewTypedResponse() *SwitchActionResponse {
@@ -176,7 +183,7 @@ type SwitchConfig struct {
 	ID int `json:"id"`
 
 	// Name of the switch instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// InMode is the mode of the associated input. Range of values: momentary,
 	// follow, flip, detached, cycle (if applicable), activate (if applicable)
@@ -299,3 +306,23 @@ type SwitchActionResponse struct {
 	// false otherwise.
 	WasOn bool `json:"was_on"`
 }
+
+// switchEvents are the events emitted when a switch's output is turned off for protection.
+var switchEvents = map[string]func() EventData{
+	"power_limit":   func() EventData { return &SwitchLimitEvent{} },
+	"voltage_limit": func() EventData { return &SwitchLimitEvent{} },
+	"current_limit": func() EventData { return &SwitchLimitEvent{} },
+	"overtemp":      func() EventData { return &SwitchLimitEvent{} },
+}
+
+// SwitchLimitEvent is the payload of events emitted when a protection limit turns the output
+// off (power_limit, voltage_limit, current_limit, and overtemp).
+type SwitchLimitEvent struct {
+	// Limit is the configured limit which was exceeded, if reported.
+	Limit *float64 `json:"limit,omitempty"`
+
+	// Value is the measured value which exceeded the limit, if reported.
+	Value *float64 `json:"value,omitempty"`
+}
+
+func (*SwitchLimitEvent) eventData() {}
@@ -3,9 +3,6 @@ package shelly
 import (
 	"context"
 	"encoding/json"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type SysGetConfigRequest struct{}
@@ -14,18 +11,20 @@ func (r *SysGetConfigRequest) Method() string {
 	return "Sys.GetConfig"
 }
 
+func (r *SysGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *SysGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SysConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SysGetConfigRequest) NewTypedResponse() *SysConfig {
@@ -45,18 +44,20 @@ func (r *SysSetConfigRequest) Method() string {
 	return "Sys.SetConfig"
 }
 
+func (r *SysSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *SysSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SysSetConfigRequest) NewTypedResponse() *SetConfigResponse {
@@ -73,18 +74,20 @@ func (r *SysGetStatusRequest) Method() string {
 	return "Sys.GetStatus"
 }
 
+func (r *SysGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *SysGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SysStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 func (r *SysGetStatusRequest) NewTypedResponse() *SysStatus {
@@ -124,7 +127,7 @@ type SysConfig struct {
 
 type SysDeviceConfig struct {
 	// Name of the device.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// EcoMode (experimental) decreases power consumption when set to true, at the cost of reduced
 	// execution speed and increased network latency.
@@ -144,18 +147,18 @@ type SysDeviceConfig struct {
 
 	// AddOnType enables/disables addon board (if supported). Range of values: sensor, prooutput;
 	// null to disable.
-	AddOnType *string `json:"addon_type,omitempty"`
+	AddOnType Optional[string] `json:"addon_type,omitempty"`
 }
 
 type SysLocationConfig struct {
 	// TZ is the timezone or null if unavailable.
-	TZ *string `json:"tz"`
+	TZ Optional[string] `json:"tz,omitempty"`
 
 	// Lat is the latitude in degress or null if unavailable.
-	Lat *float64 `json:"lat"`
+	Lat Optional[float64] `json:"lat,omitempty"`
 
 	// Lon is the longitude in degress or null if unavailable.
-	Lon *float64 `json:"lon"`
+	Lon Optional[float64] `json:"lon,omitempty"`
 }
 
 type SysDebugConfig struct {
@@ -187,16 +190,16 @@ type SysDebugConfigWebsocket struct {
 
 type SysDebugConfigUDP struct {
 	// Addr is the address that the device log is streamed to (null to disable logs).
-	Addr *string `json:"addr"`
+	Addr Optional[string] `json:"addr,omitempty"`
 }
 
 type SysRPC_UDP_Config struct {
 	// DstAddr is the destination address for UDP.
-	DstAddr *string `json:"dst_addr"`
+	DstAddr Optional[string] `json:"dst_addr,omitempty"`
 
 	// ListenPort is the port number for inbound UDP RPC channel, null disables. Restart is
 	// required for changes to apply
-	ListenPort *int `json:"listen_port"`
+	ListenPort Optional[int] `json:"listen_port,omitempty"`
 }
 
 type SysSNTP_Config struct {
@@ -282,3 +285,51 @@ type AvailableUpdates struct {
 	// Beta indicates the new beta version of the firmware.
 	Beta *FirmwareUpdateVersion `json:"beta,omitempty"`
 }
+
+// sysEvents are the system events.
+var sysEvents = map[string]func() EventData{
+	"ota_begin":         func() EventData { return &SysOTAEvent{} },
+	"ota_progress":      func() EventData { return &SysOTAEvent{} },
+	"ota_success":       func() EventData { return &SysOTAEvent{} },
+	"ota_error":         func() EventData { return &SysOTAEvent{} },
+	"config_changed":    func() EventData { return &SysConfigChangedEvent{} },
+	"scheduled_restart": func() EventData { return &SysScheduledRestartEvent{} },
+	"sleep":             func() EventData { return &SysSleepEvent{} },
+}
+
+// SysOTAEvent is the payload of firmware update events (ota_begin, ota_progress, ota_success,
+// and ota_error).
+type SysOTAEvent struct {
+	// Msg describes the update stage or error.
+	Msg string `json:"msg,omitempty"`
+
+	// ProgressPercent is the update progress; only present for ota_progress.
+	ProgressPercent *int `json:"progress_percent,omitempty"`
+}
+
+func (*SysOTAEvent) eventData() {}
+
+// SysConfigChangedEvent is the payload of the config_changed event.
+type SysConfigChangedEvent struct {
+	// CfgRev is the configuration revision number after the change.
+	CfgRev int `json:"cfg_rev"`
+
+	// RestartRequired is true if the change takes effect after a restart.
+	RestartRequired bool `json:"restart_required"`
+}
+
+func (*SysConfigChangedEvent) eventData() {}
+
+// SysScheduledRestartEvent is the payload of the scheduled_restart event.
+type SysScheduledRestartEvent struct {
+	// TimeMS is the time in milliseconds until the device restarts.
+	TimeMS int `json:"time_ms"`
+}
+
+func (*SysScheduledRestartEvent) eventData() {}
+
+// SysSleepEvent is the payload of the sleep event emitted by battery powered devices before
+// they go to sleep.
+type SysSleepEvent struct{}
+
+func (*SysSleepEvent) eventData() {}
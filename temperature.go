@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 // TemperatureGetConfigRequest contains parameters for the Temperature.GetConfig RPC request.
@@ -17,6 +14,10 @@ func (r *TemperatureGetConfigRequest) Method() string {
 	return "Temperature.GetConfig"
 }
 
+func (r *TemperatureGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *TemperatureGetConfigRequest) NewTypedResponse() *TemperatureConfig {
 	return &TemperatureConfig{}
 }
@@ -27,16 +28,14 @@ func (r *TemperatureGetConfigRequest) NewResponse() any {
 
 func (r *TemperatureGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*TemperatureConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // TemperatureSetConfigRequest contains parameters for the Temperature.SetConfig RPC request.
@@ -52,6 +51,10 @@ func (r *TemperatureSetConfigRequest) Method() string {
 	return "Temperature.SetConfig"
 }
 
+func (r *TemperatureSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *TemperatureSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -62,16 +65,14 @@ func (r *TemperatureSetConfigRequest) NewResponse() any {
 
 func (r *TemperatureSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // TemperatureGetStatusRequst contains parameters for the Temperature.GetStatus RPC request.
@@ -84,6 +85,10 @@ func (r *TemperatureGetStatusRequest) Method() string {
 	return "Temperature.GetStatus"
 }
 
+func (r *TemperatureGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *TemperatureGetStatusRequest) NewTypedResponse() *TemperatureStatus {
 	return &TemperatureStatus{}
 }
@@ -94,16 +99,14 @@ func (r *TemperatureGetStatusRequest) NewResponse() any {
 
 func (r *TemperatureGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*TemperatureStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 // TemperatureConfig provides configuration for temperature component instances.
@@ -112,7 +115,7 @@ type TemperatureConfig struct {
 	ID int `json:"id"`
 
 	// Name of the temperature instance.
-	Name *string `json:"name"`
+	Name Optional[string] `json:"name,omitempty"`
 
 	// ReportTHR is the temperature report threshold in Celsius. Accepted range is device-specific,
 	// default [0.5..5.0]C unless specified otherwise.
@@ -0,0 +1,82 @@
+package shelly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPTransport carries RPC frames as HTTP POST requests to a device's /rpc endpoint.
+type HTTPTransport struct {
+	// URL of the device rpc endpoint, ex. http://192.168.1.20/rpc
+	URL string
+
+	// HTTPClient is used to make requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+}
+
+// NewHTTPClient builds a Client which calls the device at url (ex. http://192.168.1.20/rpc) via
+// HTTP POST.
+func NewHTTPClient(url string, opts ...ClientOption) *Client {
+	return NewClient(&HTTPTransport{URL: url}, opts...)
+}
+
+// RoundTrip implements Transport.
+func (t *HTTPTransport) RoundTrip(ctx context.Context, req *RPCFrame) (*RPCFrame, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding rpc frame: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building http request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	hc := t.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	httpResp, err := hc.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("making http request: %w", err)
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading http response: %w", err)
+	}
+
+	if httpResp.StatusCode == http.StatusUnauthorized {
+		// The HTTP channel returns the digest challenge as a header rather than as an error
+		// frame. Translate it so the Client can handle all transports identically.
+		challenge, err := parseWWWAuthenticate(httpResp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+		msg, err := json.Marshal(challenge)
+		if err != nil {
+			return nil, err
+		}
+		return &RPCFrame{
+			ID:    req.ID,
+			Error: &RPCFrameError{Code: int(ErrRPCUnauthorized), Message: string(msg)},
+		}, nil
+	}
+
+	var resp RPCFrame
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		if httpResp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected http status %d: %s", httpResp.StatusCode, respBody)
+		}
+		return nil, fmt.Errorf("decoding rpc frame: %w", err)
+	}
+	return &resp, nil
+}
+
+// Close implements Transport.
+func (t *HTTPTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,251 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultUDPMaxDatagramSize is the largest request sent by default; a 1500 byte Ethernet MTU
+	// less the IPv4 and UDP headers.
+	DefaultUDPMaxDatagramSize = 1472
+
+	// DefaultUDPRetransmitInterval is the delay before a request without a response is resent.
+	DefaultUDPRetransmitInterval = 250 * time.Millisecond
+
+	// DefaultUDPAttempts is the number of times a request is sent before giving up.
+	DefaultUDPAttempts = 3
+
+	udpReadBufferSize = 64 * 1024
+
+	// maxFanOutID bounds random fan-out request IDs to integers exactly representable in JSON.
+	maxFanOutID = 1 << 53
+)
+
+var (
+	// ErrDatagramTooLarge is returned when an encoded request does not fit in a single datagram.
+	ErrDatagramTooLarge = errors.New("rpc frame exceeds maximum datagram size")
+
+	// ErrUDPTimeout is returned when no response is received after all attempts.
+	ErrUDPTimeout = errors.New("no response to udp rpc request")
+)
+
+// UDPTransport carries RPC frames as UDP datagrams to the port configured by the device's
+// `sys.rpc_udp.listen_port`. Responses are matched to requests by ID. Idempotent requests made
+// through a Client are retransmitted with the same ID until a response arrives. A lost response
+// can't be told apart from a lost request, so other requests (ex. Switch.Toggle) are sent once,
+// unless the context opts in with WithRetryNonIdempotent.
+type UDPTransport struct {
+	// MaxDatagramSize is the largest encoded request which will be sent.
+	MaxDatagramSize int
+
+	// RetransmitInterval is the delay before a request without a response is resent.
+	RetransmitInterval time.Duration
+
+	// Attempts is the number of times an idempotent request is sent before returning
+	// ErrUDPTimeout.
+	Attempts int
+
+	addr *net.UDPAddr
+	conn *net.UDPConn
+	src  string
+
+	mu      sync.Mutex
+	pending map[int64]func(*net.UDPAddr, *RPCFrame)
+}
+
+// NewUDPTransport listens on an ephemeral local port and sends requests to addr (ex.
+// 192.168.1.20:1010). addr may also be a broadcast or multicast address for use with FanOut.
+func NewUDPTransport(addr string) (*UDPTransport, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("resolving udp address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, fmt.Errorf("listening for udp responses: %w", err)
+	}
+	t := &UDPTransport{
+		MaxDatagramSize:    DefaultUDPMaxDatagramSize,
+		RetransmitInterval: DefaultUDPRetransmitInterval,
+		Attempts:           DefaultUDPAttempts,
+		addr:               raddr,
+		conn:               conn,
+		src:                "go-shelly-" + uuid.NewString()[:8],
+		pending:            make(map[int64]func(*net.UDPAddr, *RPCFrame)),
+	}
+	go t.readLoop()
+	return t, nil
+}
+
+// NewUDPClient builds a Client which calls the device at addr (ex. 192.168.1.20:1010) via UDP.
+func NewUDPClient(addr string, opts ...ClientOption) (*Client, error) {
+	t, err := NewUDPTransport(addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(t, opts...), nil
+}
+
+// LocalAddr returns the local address on which responses are received.
+func (t *UDPTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
+
+func (t *UDPTransport) readLoop() {
+	buf := make([]byte, udpReadBufferSize)
+	for {
+		n, from, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		var f RPCFrame
+		if err := json.Unmarshal(buf[:n], &f); err != nil || f.IsNotification() {
+			continue
+		}
+		t.mu.Lock()
+		h, ok := t.pending[f.ID]
+		t.mu.Unlock()
+		if ok {
+			h(from, &f)
+		}
+	}
+}
+
+func (t *UDPTransport) register(id int64, h func(*net.UDPAddr, *RPCFrame)) func() {
+	t.mu.Lock()
+	t.pending[id] = h
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}
+}
+
+func (t *UDPTransport) encode(req *RPCFrame) ([]byte, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding rpc frame: %w", err)
+	}
+	if len(b) > t.MaxDatagramSize {
+		return nil, fmt.Errorf("%w: %d > %d bytes", ErrDatagramTooLarge, len(b), t.MaxDatagramSize)
+	}
+	return b, nil
+}
+
+// RoundTrip implements Transport.
+func (t *UDPTransport) RoundTrip(ctx context.Context, req *RPCFrame) (*RPCFrame, error) {
+	b, err := t.encode(req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan *RPCFrame, 1)
+	defer t.register(req.ID, func(_ *net.UDPAddr, f *RPCFrame) {
+		select {
+		case ch <- f:
+		default:
+		}
+	})()
+
+	attempts := t.Attempts
+	if attempts < 1 || !mayRepeat(ctx) {
+		attempts = 1
+	}
+	for i := 0; i < attempts; i++ {
+		if _, err := t.conn.WriteToUDP(b, t.addr); err != nil {
+			return nil, fmt.Errorf("sending udp datagram: %w", err)
+		}
+		select {
+		case f := <-ch:
+			return f, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(t.RetransmitInterval):
+		}
+	}
+	return nil, fmt.Errorf("%w after %d attempts", ErrUDPTimeout, attempts)
+}
+
+// FanOutResponse is a response received from one device during a fan-out call.
+type FanOutResponse struct {
+	// Addr is the address of the responding device.
+	Addr *net.UDPAddr
+
+	// Frame is the response frame.
+	Frame *RPCFrame
+}
+
+// Decode unmarshals the result into resp, or returns the device's error if the call failed.
+func (r *FanOutResponse) Decode(resp any) error {
+	if r.Frame.Error != nil {
+		return &BadStatusWithMessageError{
+			Status: ShellyErrorCode(r.Frame.Error.Code),
+			Msg:    r.Frame.Error.Message,
+		}
+	}
+	if err := json.Unmarshal(r.Frame.Result, resp); err != nil {
+		return fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return nil
+}
+
+// FanOut sends req once to the transport's address, typically a broadcast or multicast address,
+// and collects the responses received until ctx is done; ctx should have a deadline. Only the
+// first response from each address is kept. Fan-out calls are unauthenticated.
+func (t *UDPTransport) FanOut(ctx context.Context, req RPCRequestBody) ([]*FanOutResponse, error) {
+	params, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling shelly rpc request: %w", err)
+	}
+	f := &RPCFrame{
+		JSONRPC: JSONRPCVersion,
+		ID:      rand.Int63n(maxFanOutID) + 1,
+		Src:     t.src,
+		Method:  req.Method(),
+		Params:  params,
+	}
+	b, err := t.encode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu    sync.Mutex
+		resps []*FanOutResponse
+		seen  = make(map[string]bool)
+	)
+	defer t.register(f.ID, func(from *net.UDPAddr, f *RPCFrame) {
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[from.String()] {
+			return
+		}
+		seen[from.String()] = true
+		resps = append(resps, &FanOutResponse{Addr: from, Frame: f})
+	})()
+
+	if _, err := t.conn.WriteToUDP(b, t.addr); err != nil {
+		return nil, fmt.Errorf("sending udp datagram: %w", err)
+	}
+	<-ctx.Done()
+	mu.Lock()
+	defer mu.Unlock()
+	return resps, nil
+}
+
+// Close implements Transport.
+func (t *UDPTransport) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,144 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// udpTestDevice answers Switch.Set requests, dropping the first `drop` datagrams it receives.
+func udpTestDevice(t *testing.T, drop int32) (addr string, received *atomic.Int32) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	received = &atomic.Int32{}
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if received.Add(1) <= drop {
+				continue
+			}
+			var req RPCFrame
+			require.NoError(t, json.Unmarshal(buf[:n], &req))
+			b, _ := json.Marshal(&RPCFrame{ID: req.ID, Src: testRealm, Dst: req.Src, Result: json.RawMessage(`{"was_on":true}`)})
+			conn.WriteToUDP(b, from)
+		}
+	}()
+	return conn.LocalAddr().String(), received
+}
+
+func TestUDPClientRetransmit(t *testing.T) {
+	addr, received := udpTestDevice(t, 1)
+	tr, err := NewUDPTransport(addr)
+	require.NoError(t, err)
+	tr.RetransmitInterval = 20 * time.Millisecond
+	c := NewClient(tr)
+	defer c.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, _, err := (&SwitchSetRequest{ID: 0, On: true}).Do(ctx, c, nil)
+	require.NoError(t, err)
+	assert.True(t, resp.WasOn)
+	assert.EqualValues(t, 2, received.Load())
+
+	_, _, err = (&ScriptPutCodeRequest{ID: 1, Code: strings.Repeat("x", DefaultUDPMaxDatagramSize)}).Do(ctx, c, nil)
+	assert.ErrorIs(t, err, ErrDatagramTooLarge)
+}
+
+func TestUDPClientNonIdempotent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, tc := range []struct {
+		name      string
+		call      func(*Client) error
+		wantErr   error
+		wantSends int32
+	}{
+		{
+			name: "typed",
+			call: func(c *Client) error {
+				_, _, err := (&SwitchToggleRequest{ID: 0}).Do(ctx, c, nil)
+				return err
+			},
+			wantErr:   ErrUDPTimeout,
+			wantSends: 1,
+		},
+		{
+			name: "raw",
+			call: func(c *Client) error {
+				_, err := c.Call(ctx, &RPCFrame{Method: "Switch.Toggle", Params: json.RawMessage(`{"id":0}`)}, nil)
+				return err
+			},
+			wantErr:   ErrUDPTimeout,
+			wantSends: 1,
+		},
+		{
+			name: "opt-in",
+			call: func(c *Client) error {
+				_, _, err := (&SwitchToggleRequest{ID: 0}).Do(WithRetryNonIdempotent(ctx), c, nil)
+				return err
+			},
+			wantSends: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// The device executes the first toggle but its response is lost.
+			addr, received := udpTestDevice(t, 1)
+			tr, err := NewUDPTransport(addr)
+			require.NoError(t, err)
+			tr.RetransmitInterval = 20 * time.Millisecond
+			c := NewClient(tr)
+			defer c.Disconnect(context.Background())
+
+			err = tc.call(c)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, tc.wantSends, received.Load())
+		})
+	}
+}
+
+func TestUDPClientTimeout(t *testing.T) {
+	addr, received := udpTestDevice(t, 100)
+	tr, err := NewUDPTransport(addr)
+	require.NoError(t, err)
+	tr.RetransmitInterval = 10 * time.Millisecond
+	defer tr.Close()
+
+	_, _, err = (&SwitchSetRequest{ID: 0, On: true}).Do(context.Background(), NewClient(tr), nil)
+	assert.ErrorIs(t, err, ErrUDPTimeout)
+	assert.EqualValues(t, DefaultUDPAttempts, received.Load())
+}
+
+func TestUDPFanOut(t *testing.T) {
+	addr, _ := udpTestDevice(t, 0)
+	tr, err := NewUDPTransport(addr)
+	require.NoError(t, err)
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	resps, err := tr.FanOut(ctx, &SwitchSetRequest{ID: 0, On: true})
+	require.NoError(t, err)
+	require.Len(t, resps, 1)
+	assert.Equal(t, addr, resps[0].Addr.String())
+	var resp SwitchActionResponse
+	require.NoError(t, resps[0].Decode(&resp))
+	assert.True(t, resp.WasOn)
+}
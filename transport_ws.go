@@ -0,0 +1,306 @@
+package shelly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultWebSocketMinBackoff is the initial delay before reconnecting a dropped connection.
+	DefaultWebSocketMinBackoff = 500 * time.Millisecond
+
+	// DefaultWebSocketMaxBackoff is the maximum delay between reconnection attempts.
+	DefaultWebSocketMaxBackoff = 30 * time.Second
+)
+
+var (
+	// ErrConnectionLost is returned for calls which were in flight when the connection dropped.
+	ErrConnectionLost = errors.New("websocket connection lost")
+)
+
+// WebSocketTransport maintains a long-lived WebSocket connection to a device's /rpc endpoint.
+// Concurrent calls are multiplexed over the connection and matched to responses by ID.
+// Notifications are passed to the notification handler. If the connection drops it is
+// re-established with exponential backoff; calls made while disconnected wait for the
+// reconnection or for their context to expire.
+type WebSocketTransport struct {
+	url        string
+	dialer     *websocket.Dialer
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	conn        *websocket.Conn
+	ready       chan struct{} // closed while connected
+	pending     map[int64]chan wsResult
+	onNotify    func(*RPCFrame)
+	onReconnect func()
+	closed      bool
+	done        chan struct{}
+}
+
+type wsResult struct {
+	frame *RPCFrame
+	err   error
+}
+
+// WebSocketOption configures a WebSocketTransport.
+type WebSocketOption func(*WebSocketTransport)
+
+// WithReconnectBackoff sets the minimum and maximum delay between reconnection attempts.
+func WithReconnectBackoff(min, max time.Duration) WebSocketOption {
+	return func(t *WebSocketTransport) {
+		t.minBackoff = min
+		t.maxBackoff = max
+	}
+}
+
+// WithDialer sets the dialer used to establish connections.
+func WithDialer(d *websocket.Dialer) WebSocketOption {
+	return func(t *WebSocketTransport) {
+		t.dialer = d
+	}
+}
+
+// DialWebSocket connects to the device at url (ex. ws://192.168.1.20/rpc). The initial
+// connection must succeed; later failures are retried in the background until Close.
+func DialWebSocket(ctx context.Context, url string, opts ...WebSocketOption) (*WebSocketTransport, error) {
+	t := &WebSocketTransport{
+		url:        url,
+		dialer:     websocket.DefaultDialer,
+		minBackoff: DefaultWebSocketMinBackoff,
+		maxBackoff: DefaultWebSocketMaxBackoff,
+		ready:      make(chan struct{}),
+		pending:    make(map[int64]chan wsResult),
+		done:       make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	conn, _, err := t.dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dialing websocket: %w", err)
+	}
+	t.setConn(conn)
+	go t.run(conn)
+	return t, nil
+}
+
+// WithWebSocketOptions configures the transport dialed by NewWebSocketClient. It has no effect
+// on clients built with NewClient.
+func WithWebSocketOptions(opts ...WebSocketOption) ClientOption {
+	return func(c *Client) {
+		c.wsOpts = append(c.wsOpts, opts...)
+	}
+}
+
+// NewWebSocketClient connects to the device at url (ex. ws://192.168.1.20/rpc) and builds a
+// Client which makes calls over the connection. When the connection is re-established after a
+// drop the client announces itself again so notifications resume. Use WithWebSocketOptions to
+// configure the connection, ex. WithReconnectBackoff.
+func NewWebSocketClient(ctx context.Context, url string, opts ...ClientOption) (*Client, error) {
+	var cfg Client
+	for _, o := range opts {
+		o(&cfg)
+	}
+	t, err := DialWebSocket(ctx, url, cfg.wsOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(t, opts...), nil
+}
+
+func (t *WebSocketTransport) setConn(conn *websocket.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conn = conn
+	close(t.ready)
+}
+
+// run reads frames until the connection fails, then reconnects until the transport is closed.
+func (t *WebSocketTransport) run(conn *websocket.Conn) {
+	for {
+		t.readLoop(conn)
+
+		t.mu.Lock()
+		t.conn = nil
+		t.ready = make(chan struct{})
+		for id, ch := range t.pending {
+			ch <- wsResult{err: ErrConnectionLost}
+			delete(t.pending, id)
+		}
+		closed := t.closed
+		t.mu.Unlock()
+		if closed {
+			return
+		}
+
+		var ok bool
+		if conn, ok = t.reconnect(); !ok {
+			return
+		}
+		t.setConn(conn)
+		t.mu.Lock()
+		onReconnect := t.onReconnect
+		t.mu.Unlock()
+		if onReconnect != nil {
+			go onReconnect()
+		}
+	}
+}
+
+func (t *WebSocketTransport) readLoop(conn *websocket.Conn) {
+	defer conn.Close()
+	for {
+		var f RPCFrame
+		if err := conn.ReadJSON(&f); err != nil {
+			return
+		}
+		if f.IsNotification() {
+			t.mu.Lock()
+			h := t.onNotify
+			t.mu.Unlock()
+			if h != nil {
+				h(&f)
+			}
+			continue
+		}
+		t.mu.Lock()
+		ch, ok := t.pending[f.ID]
+		delete(t.pending, f.ID)
+		t.mu.Unlock()
+		if ok {
+			ch <- wsResult{frame: &f}
+		}
+	}
+}
+
+func (t *WebSocketTransport) reconnect() (*websocket.Conn, bool) {
+	backoff := t.minBackoff
+	for {
+		select {
+		case <-t.done:
+			return nil, false
+		case <-time.After(backoff):
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t.maxBackoff)
+		conn, _, err := t.dialer.DialContext(ctx, t.url, nil)
+		cancel()
+		if err == nil {
+			t.mu.Lock()
+			closed := t.closed
+			t.mu.Unlock()
+			if closed {
+				conn.Close()
+				return nil, false
+			}
+			return conn, true
+		}
+		if backoff *= 2; backoff > t.maxBackoff {
+			backoff = t.maxBackoff
+		}
+	}
+}
+
+// RoundTrip implements Transport.
+func (t *WebSocketTransport) RoundTrip(ctx context.Context, req *RPCFrame) (*RPCFrame, error) {
+	ch := make(chan wsResult, 1)
+	for {
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			return nil, ErrClientClosed
+		}
+		conn, ready := t.conn, t.ready
+		if conn != nil {
+			t.pending[req.ID] = ch
+		}
+		t.mu.Unlock()
+		if conn != nil {
+			if err := t.write(ctx, conn, req); err != nil {
+				t.forget(req.ID)
+				return nil, err
+			}
+			break
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	select {
+	case res := <-ch:
+		return res.frame, res.err
+	case <-ctx.Done():
+		t.forget(req.ID)
+		return nil, ctx.Err()
+	}
+}
+
+func (t *WebSocketTransport) write(ctx context.Context, conn *websocket.Conn, req *RPCFrame) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+		defer conn.SetWriteDeadline(time.Time{})
+	}
+	if err := conn.WriteJSON(req); err != nil {
+		// Closing the connection unblocks the read loop, which triggers a reconnect.
+		conn.Close()
+		return fmt.Errorf("writing websocket frame: %w", err)
+	}
+	return nil
+}
+
+func (t *WebSocketTransport) forget(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, id)
+}
+
+// SetNotificationHandler implements NotifyingTransport.
+func (t *WebSocketTransport) SetNotificationHandler(h func(*RPCFrame)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onNotify = h
+}
+
+// SetReconnectHandler implements ReconnectingTransport.
+func (t *WebSocketTransport) SetReconnectHandler(h func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onReconnect = h
+}
+
+// IsConnected returns true while the connection is established.
+func (t *WebSocketTransport) IsConnected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.conn != nil
+}
+
+// Close implements Transport.
+func (t *WebSocketTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.done)
+	conn := t.conn
+	t.mu.Unlock()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
@@ -8,8 +8,13 @@ import (
 
 // NullString is similar to a *string but will encode to the JSON null value if empty, but
 // *NullString(nil) will be omitted w/ omitempty flag.
+//
+// Deprecated: use Optional[string], which also decodes null distinctly.
 type NullString string
 
+// NewNullString returns a pointer to a NullString holding s.
+//
+// Deprecated: use Some(s), which returns an Optional[string].
 func NewNullString(s string) *NullString {
 	n := NullString(s)
 	return &n
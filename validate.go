@@ -0,0 +1,180 @@
+package shelly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MinBTHomeID and MaxBTHomeID bound the IDs of BTHomeDevice and BTHomeSensor components.
+	MinBTHomeID = 200
+	MaxBTHomeID = 299
+
+	// MaxScheduleCalls is the limit of calls made by a schedule job.
+	MaxScheduleCalls = 5
+)
+
+// Validator is implemented by requests which can check their parameters before they're sent,
+// catching constraints which the device would otherwise reject with
+// ErrRPCInvalidOrMissingArguments.
+type Validator interface {
+	// Validate returns a *ValidationError describing each invalid parameter, or nil.
+	Validate() error
+}
+
+// FieldError describes an invalid request parameter.
+type FieldError struct {
+	// Field is the JSON path of the parameter, ex. `config.xpercent.expr` or `calls[2].method`.
+	Field string
+
+	// Msg describes the constraint which isn't met.
+	Msg string
+}
+
+func (err *FieldError) Error() string {
+	return err.Field + ": " + err.Msg
+}
+
+// ValidationError is returned when a request fails validation. It matches
+// ErrRPCInvalidOrMissingArguments with errors.Is, and each *FieldError with errors.As.
+type ValidationError struct {
+	// Method is the method of the invalid request.
+	Method string
+
+	// Fields lists the invalid parameters.
+	Fields []*FieldError
+}
+
+func (err *ValidationError) Error() string {
+	msgs := make([]string, 0, len(err.Fields))
+	for _, f := range err.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return fmt.Sprintf("invalid %s request: %s", err.Method, strings.Join(msgs, "; "))
+}
+
+// Is matches ErrRPCInvalidOrMissingArguments, which the device would have returned.
+func (err *ValidationError) Is(target error) bool {
+	return target == ErrRPCInvalidOrMissingArguments
+}
+
+// Unwrap returns the field errors.
+func (err *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(err.Fields))
+	for _, f := range err.Fields {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// Validate validates req if it implements Validator.
+func Validate(req RPCRequestBody) error {
+	if v, ok := req.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+type validateRequestsKey struct{}
+
+// WithValidation returns a context which makes Do and Call validate requests before sending
+// them, failing with the *ValidationError rather than making the call.
+func WithValidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, validateRequestsKey{}, true)
+}
+
+// ValidationInterceptor fails calls whose request doesn't pass Validate, without sending them.
+// Requests made with Call or Do through an InterceptedClient are validated; raw commands are
+// passed through.
+func ValidationInterceptor() Interceptor {
+	return func(ctx context.Context, req RPCRequestBody, next Invoker) (*RPCFrame, error) {
+		if err := Validate(req); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// validation collects the field errors of a request.
+type validation struct {
+	method string
+	fields []*FieldError
+}
+
+func newValidation(req RPCRequestBody) *validation {
+	return &validation{method: req.Method()}
+}
+
+func (v *validation) addf(field, format string, args ...any) {
+	v.fields = append(v.fields, &FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Method: v.method, Fields: v.fields}
+}
+
+func (v *validation) maxLen(field string, s Optional[string], n int) {
+	if s, ok := s.Get(); ok && len(s) > n {
+		v.addf(field, "must be at most %d characters, got %d", n, len(s))
+	}
+}
+
+func (v *validation) bthomeID(field string, id int) {
+	if id < MinBTHomeID || id > MaxBTHomeID {
+		v.addf(field, "must be in the range [%d..%d], got %d", MinBTHomeID, MaxBTHomeID, id)
+	}
+}
+
+// rangeMap checks a [min, max] percent range. equal allows min == max.
+func (v *validation) rangeMap(field string, m []float64, equal bool) {
+	if len(m) != 2 {
+		v.addf(field, "must have 2 elements, got %d", len(m))
+		return
+	}
+	for i, p := range m {
+		if p < 0 || p > 100 {
+			v.addf(fmt.Sprintf("%s[%d]", field, i), "must be in the range [0..100], got %g", p)
+		}
+	}
+	switch {
+	case m[0] > m[1]:
+		v.addf(field, "min %g is greater than max %g", m[0], m[1])
+	case m[0] == m[1] && !equal:
+		v.addf(field, "max must be greater than min")
+	}
+}
+
+// timeSpec checks a cron timespec, ex. `0 0 8 * * MON-FRI` or `@sunrise+30m`.
+func (v *validation) timeSpec(field string, spec string) {
+	if strings.HasPrefix(spec, "@") {
+		return
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 6 {
+		v.addf(field, "must have 6 fields (sec min hour dom month dow), got %d", len(fields))
+	}
+	tokens := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '-' || r == '/'
+	})
+	for _, t := range tokens {
+		if len(t) > 1 && t[0] == '0' && strings.Trim(t, "0123456789") == "" {
+			v.addf(field, "leading zeros are not supported, got %q", t)
+			return
+		}
+	}
+}
+
+func (v *validation) scheduleCalls(field string, calls []ScheduleCall) {
+	if len(calls) > MaxScheduleCalls {
+		v.addf(field, "must have at most %d calls, got %d", MaxScheduleCalls, len(calls))
+	}
+	for i, c := range calls {
+		if c.Method == "" {
+			v.addf(fmt.Sprintf("%s[%d].method", field, i), "is required")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package shelly
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	fields := make(map[string]string)
+	for _, f := range verr.Fields {
+		fields[f.Field] = f.Msg
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	call := ScheduleCall{Method: "Switch.Toggle"}
+	err := Validate(&ScheduleCreateRequest{
+		TimeSpec: StrPtr("0 30 08 * * MON-FRI"),
+		Calls:    []ScheduleCall{call, call, call, call, call, {}},
+	})
+	assert.ErrorIs(t, err, ErrRPCInvalidOrMissingArguments)
+	assert.Equal(t, map[string]string{
+		"timespec":        `leading zeros are not supported, got "08"`,
+		"calls":           "must have at most 5 calls, got 6",
+		"calls[5].method": "is required",
+	}, fieldErrors(t, err))
+	assert.NoError(t, Validate(&ScheduleCreateRequest{TimeSpec: StrPtr("0 0 8 * * MON-FRI"), Calls: []ScheduleCall{call}}))
+	assert.NoError(t, Validate(&ScheduleCreateRequest{TimeSpec: StrPtr("@sunset-15m"), Calls: []ScheduleCall{call}}))
+	assert.Equal(t, map[string]string{"id": "is required"}, fieldErrors(t, Validate(&ScheduleUpdateRequest{})))
+
+	assert.Equal(t, map[string]string{
+		"id": "must be in the range [200..299], got 12",
+	}, fieldErrors(t, Validate(&BTHomeDeviceSetConfigRequest{ID: 12})))
+	assert.NoError(t, Validate(&BTHomeAddSensorRequest{}))
+	assert.NoError(t, Validate(&BTHomeDeleteSensorRequest{ID: 299}))
+
+	err = Validate(&MQTTSetConfigRequest{Config: MQTTConfig{TopicPrefix: Some("$SYS/home+")}})
+	assert.Equal(t, map[string]string{"config.topic_prefix": `must not contain '+'`}, fieldErrors(t, err))
+	assert.Contains(t, err.Error(), "invalid MQTT.SetConfig request: config.topic_prefix: must not start with $")
+	assert.NoError(t, Validate(&MQTTSetConfigRequest{Config: MQTTConfig{TopicPrefix: Null[string]()}}))
+
+	long := string(make([]byte, 101))
+	err = Validate(&InputSetConfigRequest{Config: InputConfig{
+		RangeMap: []float64{120, 80},
+		XPercent: &InputXPercent{Expr: Some(long), Unit: Some("m/s")},
+	}})
+	assert.Equal(t, map[string]string{
+		"config.range_map[0]":  "must be in the range [0..100], got 120",
+		"config.range_map":     "min 120 is greater than max 80",
+		"config.xpercent.expr": "must be at most 100 characters, got 101",
+	}, fieldErrors(t, err))
+	assert.NoError(t, Validate(&InputSetConfigRequest{Config: InputConfig{RangeMap: []float64{50, 50}}}))
+	assert.Error(t, Validate(&LightSetConfigRequest{Config: LightConfig{RangeMap: &[]float64{50, 50}}}))
+	assert.Error(t, Validate(&LightSetRequest{ID: 0}))
+
+	// Requests without constraints always pass.
+	assert.NoError(t, Validate(&SwitchSetRequest{ID: 0, On: true}))
+}
+
+func TestValidationInterceptor(t *testing.T) {
+	ctx := context.Background()
+	s := &scriptedRPC{}
+	c := NewInterceptedClient(s, ValidationInterceptor())
+
+	_, _, err := Call(ctx, c, nil, &BTHomeAddDeviceRequest{ID: IntPtr(300)})
+	var ferr *FieldError
+	require.True(t, errors.As(err, &ferr))
+	assert.Equal(t, "id", ferr.Field)
+	assert.ErrorIs(t, err, ErrRPCInvalidOrMissingArguments)
+	assert.Zero(t, s.calls)
+
+	_, _, err = Call(ctx, c, nil, &BTHomeAddDeviceRequest{ID: IntPtr(200)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.calls)
+
+	// Without an ID the device picks one.
+	require.NoError(t, Validate(&BTHomeAddDeviceRequest{}))
+}
+
+func TestWithValidation(t *testing.T) {
+	ctx := context.Background()
+	s := &scriptedRPC{}
+
+	// Validation is opt-in.
+	_, _, err := Call(ctx, s, nil, &BTHomeAddDeviceRequest{ID: IntPtr(300)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.calls)
+
+	_, _, err = Call(WithValidation(ctx), s, nil, &BTHomeAddDeviceRequest{ID: IntPtr(300)})
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, "BTHome.AddDevice", verr.Method)
+	assert.Equal(t, 1, s.calls)
+}
@@ -0,0 +1,104 @@
+package shelly
+
+import (
+	"context"
+)
+
+const (
+	// MinVirtualID and MaxVirtualID bound the IDs of virtual components.
+	MinVirtualID = 200
+	MaxVirtualID = 299
+)
+
+// VirtualAddRequest creates a virtual component (ex. boolean, number, text, enum, group or
+// button).
+type VirtualAddRequest struct {
+	// Type of the component, ex. "boolean".
+	Type string `json:"type"`
+
+	// ID for the new component. Accepted range: [200..299]. If omitted, the first free ID will
+	// be used. If the desired ID is not available, an error will be returned.
+	ID *int `json:"id,omitempty"`
+
+	// Config to be used for the new component, in the format of its SetConfig method.
+	Config any `json:"config,omitempty"`
+}
+
+func (r *VirtualAddRequest) Method() string {
+	return "Virtual.Add"
+}
+
+func (r *VirtualAddRequest) Idempotent() bool {
+	return false
+}
+
+func (r *VirtualAddRequest) NewTypedResponse() *VirtualAddResponse {
+	return &VirtualAddResponse{}
+}
+
+func (r *VirtualAddRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *VirtualAddRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*VirtualAddResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *VirtualAddRequest) Validate() error {
+	v := newValidation(r)
+	if r.Type == "" {
+		v.addf("type", "is required")
+	}
+	if r.ID != nil && (*r.ID < MinVirtualID || *r.ID > MaxVirtualID) {
+		v.addf("id", "must be in the range [%d..%d], got %d", MinVirtualID, MaxVirtualID, *r.ID)
+	}
+	return v.err()
+}
+
+// VirtualAddResponse is the RPC response to the VirtualAddRequest.
+type VirtualAddResponse struct {
+	// ID of the new component.
+	ID int `json:"id"`
+}
+
+// VirtualDeleteRequest deletes a virtual component.
+type VirtualDeleteRequest struct {
+	// Key of the component, ex. "boolean:200".
+	Key string `json:"key"`
+}
+
+func (r *VirtualDeleteRequest) Method() string {
+	return "Virtual.Delete"
+}
+
+func (r *VirtualDeleteRequest) Idempotent() bool {
+	return false
+}
+
+func (r *VirtualDeleteRequest) NewTypedResponse() *RPCEmptyResponse {
+	return &RPCEmptyResponse{}
+}
+
+func (r *VirtualDeleteRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *VirtualDeleteRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*RPCEmptyResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
@@ -0,0 +1,250 @@
+package shelly
+
+import (
+	"context"
+)
+
+// Webhook sends HTTP requests when an event occurs on the device.
+type Webhook struct {
+	// ID assigned to the webhook when it is created. This should be nil for Webhook.Create, and
+	// MUST have a value for Webhook.Update.
+	ID *int `json:"id,omitempty"`
+
+	// CID is the id of the component instance which emits the event.
+	CID *int `json:"cid,omitempty"`
+
+	// Enable is true to enable the webhook, false otherwise.
+	Enable *bool `json:"enable,omitempty"`
+
+	// Event which triggers the webhook, ex. "switch.on". See Webhook.ListSupported.
+	Event *string `json:"event,omitempty"`
+
+	// Name of the webhook.
+	Name Optional[string] `json:"name,omitempty"`
+
+	// SSL_CA is the CA used to verify HTTPS URLs: "*" to skip verification, "user_ca.pem" or
+	// "ca.pem". Default (null) is ca.pem.
+	SSL_CA Optional[string] `json:"ssl_ca,omitempty"`
+
+	// URLs to request when the event occurs. Up to 5 URLs, which may contain ${token}
+	// substitutions.
+	URLs []string `json:"urls,omitempty"`
+
+	// ActiveBetween limits the webhook to a time of day, as ["HH:MM", "HH:MM"].
+	ActiveBetween []string `json:"active_between,omitempty"`
+
+	// Condition is a JS expression which must evaluate to true for the webhook to fire.
+	Condition Optional[string] `json:"condition,omitempty"`
+
+	// RepeatPeriod is the minimum number of seconds between repeated requests, 0 for no limit
+	// and -1 to fire once until the condition is false.
+	RepeatPeriod *int `json:"repeat_period,omitempty"`
+}
+
+// WebhookCreateRequest adds a new webhook to the shelly device.
+type WebhookCreateRequest Webhook
+
+func (r *WebhookCreateRequest) Method() string {
+	return "Webhook.Create"
+}
+
+func (r *WebhookCreateRequest) Idempotent() bool {
+	return false
+}
+
+func (r *WebhookCreateRequest) NewTypedResponse() *WebhookCreateResponse {
+	return &WebhookCreateResponse{}
+}
+
+func (r *WebhookCreateRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *WebhookCreateRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*WebhookCreateResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *WebhookCreateRequest) Validate() error {
+	v := newValidation(r)
+	if r.ID != nil {
+		v.addf("id", "must not be set when creating a webhook")
+	}
+	if r.CID == nil {
+		v.addf("cid", "is required")
+	}
+	if r.Event == nil {
+		v.addf("event", "is required")
+	}
+	if len(r.URLs) == 0 {
+		v.addf("urls", "must have at least 1 URL")
+	}
+	return v.err()
+}
+
+// WebhookCreateResponse is the RPC response to the WebhookCreateRequest.
+type WebhookCreateResponse struct {
+	// ID assigned to the webhook.
+	ID *int `json:"id,omitempty"`
+
+	// Rev is the current revision number of the webhooks.
+	Rev *int `json:"rev,omitempty"`
+}
+
+// WebhookUpdateResponse is the response for Webhook.Update, Webhook.Delete, and
+// Webhook.DeleteAll RPC requests.
+type WebhookUpdateResponse struct {
+	// Rev is the current revision number of the webhooks.
+	Rev *int `json:"rev,omitempty"`
+}
+
+// WebhookUpdateRequest modifies an existing webhook.
+type WebhookUpdateRequest Webhook
+
+func (r *WebhookUpdateRequest) Method() string {
+	return "Webhook.Update"
+}
+
+func (r *WebhookUpdateRequest) Idempotent() bool {
+	return true
+}
+
+func (r *WebhookUpdateRequest) NewTypedResponse() *WebhookUpdateResponse {
+	return &WebhookUpdateResponse{}
+}
+
+func (r *WebhookUpdateRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *WebhookUpdateRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*WebhookUpdateResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+func (r *WebhookUpdateRequest) Validate() error {
+	v := newValidation(r)
+	if r.ID == nil {
+		v.addf("id", "is required")
+	}
+	return v.err()
+}
+
+// WebhookListRequest lists the existing webhooks.
+type WebhookListRequest struct{}
+
+func (r *WebhookListRequest) Method() string {
+	return "Webhook.List"
+}
+
+func (r *WebhookListRequest) Idempotent() bool {
+	return true
+}
+
+func (r *WebhookListRequest) NewTypedResponse() *WebhookListResponse {
+	return &WebhookListResponse{}
+}
+
+func (r *WebhookListRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *WebhookListRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*WebhookListResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+// WebhookListResponse is the RPC response to the WebhookListRequest.
+type WebhookListResponse struct {
+	// Hooks are the existing webhooks.
+	Hooks []Webhook `json:"hooks"`
+
+	// Rev is the current revision number of the webhooks.
+	Rev *int `json:"rev,omitempty"`
+}
+
+// WebhookDeleteRequest deletes an existing webhook.
+type WebhookDeleteRequest struct {
+	// ID of the webhook to delete.
+	ID int `json:"id"`
+}
+
+func (r *WebhookDeleteRequest) Method() string {
+	return "Webhook.Delete"
+}
+
+func (r *WebhookDeleteRequest) Idempotent() bool {
+	return false
+}
+
+func (r *WebhookDeleteRequest) NewTypedResponse() *WebhookUpdateResponse {
+	return &WebhookUpdateResponse{}
+}
+
+func (r *WebhookDeleteRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *WebhookDeleteRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*WebhookUpdateResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
+
+// WebhookDeleteAllRequest deletes all existing webhooks.
+type WebhookDeleteAllRequest struct{}
+
+func (r *WebhookDeleteAllRequest) Method() string {
+	return "Webhook.DeleteAll"
+}
+
+func (r *WebhookDeleteAllRequest) Idempotent() bool {
+	return true
+}
+
+func (r *WebhookDeleteAllRequest) NewTypedResponse() *WebhookUpdateResponse {
+	return &WebhookUpdateResponse{}
+}
+
+func (r *WebhookDeleteAllRequest) NewResponse() any {
+	return r.NewTypedResponse()
+}
+
+func (r *WebhookDeleteAllRequest) Do(
+	ctx context.Context,
+	c Caller,
+	credsCallback GetCredsCallback,
+) (
+	*WebhookUpdateResponse,
+	*RPCFrame,
+	error,
+) {
+	return Call(ctx, c, credsCallback, r)
+}
@@ -2,9 +2,6 @@ package shelly
 
 import (
 	"context"
-
-	"github.com/mongoose-os/mos/common/mgrpc"
-	"github.com/mongoose-os/mos/common/mgrpc/frame"
 )
 
 type WifiStatus struct {
@@ -31,6 +28,10 @@ func (r *WifiGetStatusRequest) Method() string {
 	return "Wifi.GetStatus"
 }
 
+func (r *WifiGetStatusRequest) Idempotent() bool {
+	return true
+}
+
 func (r *WifiGetStatusRequest) NewTypedResponse() *WifiStatus {
 	return &WifiStatus{}
 }
@@ -41,16 +42,14 @@ func (r *WifiGetStatusRequest) NewResponse() any {
 
 func (r *WifiGetStatusRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*WifiStatus,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type WifiConfig struct {
@@ -91,10 +90,10 @@ type WifiAPRangeExtenderConfig struct {
 
 type WifiStationConfig struct {
 	// SSID of the network.
-	SSID *string `json:"ssid,omitempty"`
+	SSID Optional[string] `json:"ssid,omitempty"`
 
 	// Pass is the password for the ssid, writeonly. Must be provided if you provide ssid.
-	Pass *string `json:"pass,omitempty"`
+	Pass Optional[string] `json:"pass,omitempty"`
 
 	// IsOpen is true if the network is open, i.e. no password is set, false otherwise,
 	// readonly.
@@ -107,16 +106,16 @@ type WifiStationConfig struct {
 	IPv4Mode *string `json:"ipv4mode,omitempty"`
 
 	// IP to use when ipv4mode is static.
-	IP *string `json:"ip,omitempty"`
+	IP Optional[string] `json:"ip,omitempty"`
 
 	// Netmask to use when ipv4mode is static
-	Netmask *string `json:"netmask,omitempty"`
+	Netmask Optional[string] `json:"netmask,omitempty"`
 
 	// GW is the gateway to use when ipv4mode is static
-	GW *string `json:"gw,omitempty"`
+	GW Optional[string] `json:"gw,omitempty"`
 
 	// Nameserver to use when ipv4mode is static
-	Nameserver *string `json:"nameserver,omitempty"`
+	Nameserver Optional[string] `json:"nameserver,omitempty"`
 }
 
 type WifiRoamConfig struct {
@@ -135,6 +134,10 @@ func (r *WifiGetConfigRequest) Method() string {
 	return "Wifi.GetConfig"
 }
 
+func (r *WifiGetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *WifiGetConfigRequest) NewTypedResponse() *WifiConfig {
 	return &WifiConfig{}
 }
@@ -145,16 +148,14 @@ func (r *WifiGetConfigRequest) NewResponse() any {
 
 func (r *WifiGetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*WifiConfig,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }
 
 type WifiSetConfigRequest struct {
@@ -165,6 +166,10 @@ func (r *WifiSetConfigRequest) Method() string {
 	return "Wifi.SetConfig"
 }
 
+func (r *WifiSetConfigRequest) Idempotent() bool {
+	return true
+}
+
 func (r *WifiSetConfigRequest) NewTypedResponse() *SetConfigResponse {
 	return &SetConfigResponse{}
 }
@@ -175,14 +180,12 @@ func (r *WifiSetConfigRequest) NewResponse() any {
 
 func (r *WifiSetConfigRequest) Do(
 	ctx context.Context,
-	c mgrpc.MgRPC,
-	credsCallback mgrpc.GetCredsCallback,
+	c Caller,
+	credsCallback GetCredsCallback,
 ) (
 	*SetConfigResponse,
-	*frame.Response,
+	*RPCFrame,
 	error,
 ) {
-	resp := r.NewTypedResponse()
-	raw, err := Do(ctx, c, credsCallback, r, resp)
-	return resp, raw, err
+	return Call(ctx, c, credsCallback, r)
 }